@@ -196,7 +196,17 @@ func (api *APIServer) prometheusHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (api *APIServer) listRunsHandler(w http.ResponseWriter, r *http.Request) {
-	runs, err := api.db.ListRuns(r.Context())
+	// Runs are optionally selected by name. A name is shared by the runs
+	// of every host that the collection was started on.
+	var (
+		runs []database.Measurements
+		err  error
+	)
+	if name := r.URL.Query().Get("name"); name != "" {
+		runs, err = api.db.MeasurementsSelectByName(r.Context(), name)
+	} else {
+		runs, err = api.db.ListRuns(r.Context())
+	}
 	if err != nil {
 		api.logger.Error("failed to list runs", slog.String("error", err.Error()))
 		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to list runs: %v", err))
@@ -259,6 +269,29 @@ func (api *APIServer) getRunHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// cpuParam returns the optional cpu query parameter, -1 being the total. It
+// returns nil when all CPUs are requested.
+func cpuParam(r *http.Request) (*int, error) {
+	cpuStr := r.URL.Query().Get("cpu")
+	if cpuStr == "" {
+		return nil, nil
+	}
+	cpu, err := strconv.Atoi(cpuStr)
+	if err != nil || cpu < -1 {
+		return nil, fmt.Errorf("invalid cpu: %v", cpuStr)
+	}
+	return &cpu, nil
+}
+
+// selectStats returns the stat records of a run, limited to a single CPU when
+// cpu is not nil.
+func (api *APIServer) selectStats(ctx context.Context, runID uint64, cpu *int) ([]database.Stat, error) {
+	if cpu == nil {
+		return api.db.StatSelect(ctx, runID)
+	}
+	return api.db.StatSelectCPU(ctx, runID, *cpu)
+}
+
 func (api *APIServer) getStatsHandler(w http.ResponseWriter, r *http.Request) {
 	runIDStr := r.PathValue("runID")
 	runID, err := strconv.ParseUint(runIDStr, 10, 64)
@@ -267,7 +300,13 @@ func (api *APIServer) getStatsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats, err := api.db.StatSelect(r.Context(), runID)
+	cpu, err := cpuParam(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := api.selectStats(r.Context(), runID, cpu)
 	if err != nil {
 		api.logger.Error("failed to get stats", slog.Uint64("runID", runID), slog.String("error", err.Error()))
 		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get stats: %v", err))
@@ -331,6 +370,186 @@ func (api *APIServer) getDiskstatHandler(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, http.StatusOK, diskstat)
 }
 
+func (api *APIServer) getPressureHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	pressure, err := api.db.PressureSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get pressure", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get pressure: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pressure)
+}
+
+func (api *APIServer) getVmstatHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	vmstat, err := api.db.VmstatSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get vmstat", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get vmstat: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, vmstat)
+}
+
+func (api *APIServer) getLoadavgHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	loadavg, err := api.db.LoadavgSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get loadavg", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get loadavg: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, loadavg)
+}
+
+func (api *APIServer) getNetSNMPHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	netsnmp, err := api.db.NetSNMPSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get netsnmp", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get netsnmp: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, netsnmp)
+}
+
+func (api *APIServer) getNetstatHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	netstat, err := api.db.NetstatSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get netstat", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get netstat: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, netstat)
+}
+
+func (api *APIServer) getInterruptsHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	interrupts, err := api.db.InterruptsSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get interrupts", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get interrupts: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, interrupts)
+}
+
+func (api *APIServer) getStatfsHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	statfs, err := api.db.StatfsSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get statfs", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get statfs: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statfs)
+}
+
+func (api *APIServer) getCPUFreqHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	cpufreq, err := api.db.CPUFreqSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get cpufreq", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get cpufreq: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, cpufreq)
+}
+
+func (api *APIServer) getNodeHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	nodes, err := api.db.NodeSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get node", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get node: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nodes)
+}
+
+func (api *APIServer) getEventHandler(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	events, err := api.db.EventSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to get events", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get events: %v", err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, events)
+}
+
 func (api *APIServer) exportStatsCSV(w http.ResponseWriter, r *http.Request) {
 	runIDStr := r.PathValue("runID")
 	runID, err := strconv.ParseUint(runIDStr, 10, 64)
@@ -339,7 +558,13 @@ func (api *APIServer) exportStatsCSV(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats, err := api.db.StatSelect(r.Context(), runID)
+	cpu, err := cpuParam(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := api.selectStats(r.Context(), runID, cpu)
 	if err != nil {
 		api.logger.Error("failed to export stats", slog.Uint64("runID", runID), slog.String("error", err.Error()))
 		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get stats: %v", err))
@@ -350,7 +575,7 @@ func (api *APIServer) exportStatsCSV(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=stats_run_%d.csv", runID))
 
 	csvWriter := csv.NewWriter(w)
-	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "cpu", "user", "nice", "system", "iowait", "steal", "idle"})
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "cpu", "user", "nice", "system", "iowait", "steal", "irq", "soft", "guest", "gnice", "idle"})
 
 	for _, s := range stats {
 		csvWriter.Write([]string{
@@ -364,6 +589,10 @@ func (api *APIServer) exportStatsCSV(w http.ResponseWriter, r *http.Request) {
 			strconv.FormatFloat(s.System, 'f', 2, 64),
 			strconv.FormatFloat(s.IOWait, 'f', 2, 64),
 			strconv.FormatFloat(s.Steal, 'f', 2, 64),
+			strconv.FormatFloat(s.IRQ, 'f', 2, 64),
+			strconv.FormatFloat(s.Soft, 'f', 2, 64),
+			strconv.FormatFloat(s.Guest, 'f', 2, 64),
+			strconv.FormatFloat(s.GNice, 'f', 2, 64),
 			strconv.FormatFloat(s.Idle, 'f', 2, 64),
 		})
 	}
@@ -389,7 +618,7 @@ func (api *APIServer) exportMeminfoCSV(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=meminfo_run_%d.csv", runID))
 
 	csvWriter := csv.NewWriter(w)
-	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "memfree", "memavailable", "memused", "percentused", "buffers", "cached", "commit", "percentcommit", "active", "inactive", "dirty"})
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "memfree", "memavailable", "memused", "percentused", "buffers", "cached", "commit", "percentcommit", "active", "inactive", "dirty", "swapfree", "swapused", "percentswapused", "swapcached", "percentswapcached", "hugefree", "hugeused", "percenthugeused"})
 
 	for _, m := range meminfo {
 		csvWriter.Write([]string{
@@ -408,6 +637,14 @@ func (api *APIServer) exportMeminfoCSV(w http.ResponseWriter, r *http.Request) {
 			strconv.FormatUint(m.Active, 10),
 			strconv.FormatUint(m.Inactive, 10),
 			strconv.FormatUint(m.Dirty, 10),
+			strconv.FormatUint(m.SwapFree, 10),
+			strconv.FormatUint(m.SwapUsed, 10),
+			strconv.FormatFloat(m.PercentSwapUsed, 'f', 2, 64),
+			strconv.FormatUint(m.SwapCached, 10),
+			strconv.FormatFloat(m.PercentSwapCached, 'f', 2, 64),
+			strconv.FormatUint(m.HugeFree, 10),
+			strconv.FormatUint(m.HugeUsed, 10),
+			strconv.FormatFloat(m.PercentHugeUsed, 'f', 2, 64),
 		})
 	}
 	csvWriter.Flush()
@@ -432,7 +669,7 @@ func (api *APIServer) exportNetDevCSV(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=netdev_run_%d.csv", runID))
 
 	csvWriter := csv.NewWriter(w)
-	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "name", "rxpackets", "txpackets", "rxkbytes", "txkbytes", "rxcompressed", "txcompressed", "rxmulticast", "ifutil"})
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "name", "rxpackets", "txpackets", "rxkbytes", "txkbytes", "rxcompressed", "txcompressed", "rxmulticast", "ifutil", "rxerrors", "txerrors", "collisions", "rxdropped", "txdropped", "txcarrier", "rxframe", "rxfifo", "txfifo"})
 
 	for _, n := range netdev {
 		csvWriter.Write([]string{
@@ -449,6 +686,15 @@ func (api *APIServer) exportNetDevCSV(w http.ResponseWriter, r *http.Request) {
 			strconv.FormatFloat(n.TxCompressed, 'f', 2, 64),
 			strconv.FormatFloat(n.RxMulticast, 'f', 2, 64),
 			strconv.FormatFloat(n.IfUtil, 'f', 2, 64),
+			strconv.FormatFloat(n.RxErrors, 'f', 2, 64),
+			strconv.FormatFloat(n.TxErrors, 'f', 2, 64),
+			strconv.FormatFloat(n.Collisions, 'f', 2, 64),
+			strconv.FormatFloat(n.RxDropped, 'f', 2, 64),
+			strconv.FormatFloat(n.TxDropped, 'f', 2, 64),
+			strconv.FormatFloat(n.TxCarrier, 'f', 2, 64),
+			strconv.FormatFloat(n.RxFrame, 'f', 2, 64),
+			strconv.FormatFloat(n.RxFIFO, 'f', 2, 64),
+			strconv.FormatFloat(n.TxFIFO, 'f', 2, 64),
 		})
 	}
 	csvWriter.Flush()
@@ -473,7 +719,7 @@ func (api *APIServer) exportDiskstatCSV(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=diskstat_run_%d.csv", runID))
 
 	csvWriter := csv.NewWriter(w)
-	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "name", "tps", "rtps", "wtps", "dtps", "bread", "bwrtn", "bdscd"})
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "name", "tps", "rtps", "wtps", "dtps", "bread", "bwrtn", "bdscd", "rrqm", "wrqm", "rawait", "wawait", "aqusz", "areqsz", "util"})
 
 	for _, d := range diskstat {
 		csvWriter.Write([]string{
@@ -489,6 +735,420 @@ func (api *APIServer) exportDiskstatCSV(w http.ResponseWriter, r *http.Request)
 			strconv.FormatFloat(d.Bread, 'f', 2, 64),
 			strconv.FormatFloat(d.Bwrtn, 'f', 2, 64),
 			strconv.FormatFloat(d.Bdscd, 'f', 2, 64),
+			strconv.FormatFloat(d.Rrqm, 'f', 2, 64),
+			strconv.FormatFloat(d.Wrqm, 'f', 2, 64),
+			strconv.FormatFloat(d.RAwait, 'f', 2, 64),
+			strconv.FormatFloat(d.WAwait, 'f', 2, 64),
+			strconv.FormatFloat(d.AquSz, 'f', 2, 64),
+			strconv.FormatFloat(d.AreqSz, 'f', 2, 64),
+			strconv.FormatFloat(d.Util, 'f', 2, 64),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportPressureCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	pressure, err := api.db.PressureSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export pressure", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get pressure: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=pressure_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "resource", "someavg10", "someavg60", "someavg300", "somestall", "fullavg10", "fullavg60", "fullavg300", "fullstall"})
+
+	for _, p := range pressure {
+		csvWriter.Write([]string{
+			strconv.FormatUint(p.RunID, 10),
+			strconv.FormatInt(p.Timestamp, 10),
+			strconv.FormatInt(p.Start, 10),
+			strconv.FormatInt(p.Duration, 10),
+			p.Resource,
+			strconv.FormatFloat(p.SomeAvg10, 'f', 2, 64),
+			strconv.FormatFloat(p.SomeAvg60, 'f', 2, 64),
+			strconv.FormatFloat(p.SomeAvg300, 'f', 2, 64),
+			strconv.FormatFloat(p.SomeStall, 'f', 2, 64),
+			strconv.FormatFloat(p.FullAvg10, 'f', 2, 64),
+			strconv.FormatFloat(p.FullAvg60, 'f', 2, 64),
+			strconv.FormatFloat(p.FullAvg300, 'f', 2, 64),
+			strconv.FormatFloat(p.FullStall, 'f', 2, 64),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportVmstatCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	vmstat, err := api.db.VmstatSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export vmstat", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get vmstat: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=vmstat_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "pgpgin", "pgpgout", "fault", "majflt", "pgfree", "pgscank", "pgscand", "pgsteal", "vmeff", "pswpin", "pswpout"})
+
+	for _, v := range vmstat {
+		csvWriter.Write([]string{
+			strconv.FormatUint(v.RunID, 10),
+			strconv.FormatInt(v.Timestamp, 10),
+			strconv.FormatInt(v.Start, 10),
+			strconv.FormatInt(v.Duration, 10),
+			strconv.FormatFloat(v.Pgpgin, 'f', 2, 64),
+			strconv.FormatFloat(v.Pgpgout, 'f', 2, 64),
+			strconv.FormatFloat(v.Fault, 'f', 2, 64),
+			strconv.FormatFloat(v.MajFlt, 'f', 2, 64),
+			strconv.FormatFloat(v.Pgfree, 'f', 2, 64),
+			strconv.FormatFloat(v.Pgscank, 'f', 2, 64),
+			strconv.FormatFloat(v.Pgscand, 'f', 2, 64),
+			strconv.FormatFloat(v.Pgsteal, 'f', 2, 64),
+			strconv.FormatFloat(v.Vmeff, 'f', 2, 64),
+			strconv.FormatFloat(v.Pswpin, 'f', 2, 64),
+			strconv.FormatFloat(v.Pswpout, 'f', 2, 64),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportLoadavgCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	loadavg, err := api.db.LoadavgSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export loadavg", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get loadavg: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=loadavg_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "runqsz", "plistsz", "ldavg1", "ldavg5", "ldavg15", "blocked"})
+
+	for _, l := range loadavg {
+		csvWriter.Write([]string{
+			strconv.FormatUint(l.RunID, 10),
+			strconv.FormatInt(l.Timestamp, 10),
+			strconv.FormatInt(l.Start, 10),
+			strconv.FormatInt(l.Duration, 10),
+			strconv.FormatUint(l.RunqSz, 10),
+			strconv.FormatUint(l.PlistSz, 10),
+			strconv.FormatFloat(l.Ldavg1, 'f', 2, 64),
+			strconv.FormatFloat(l.Ldavg5, 'f', 2, 64),
+			strconv.FormatFloat(l.Ldavg15, 'f', 2, 64),
+			strconv.FormatInt(l.Blocked, 10),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportNetSNMPCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	netsnmp, err := api.db.NetSNMPSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export netsnmp", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get netsnmp: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=netsnmp_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "irec", "fwddgm", "idel", "orq", "asmrq", "asmok", "fragok", "fragcrt", "active", "passive", "iseg", "oseg", "atmptf", "estres", "retrans", "isegerr", "orsts", "idgm", "odgm", "noport", "idgmerr"})
+
+	for _, n := range netsnmp {
+		csvWriter.Write([]string{
+			strconv.FormatUint(n.RunID, 10),
+			strconv.FormatInt(n.Timestamp, 10),
+			strconv.FormatInt(n.Start, 10),
+			strconv.FormatInt(n.Duration, 10),
+			strconv.FormatFloat(n.Irec, 'f', 2, 64),
+			strconv.FormatFloat(n.Fwddgm, 'f', 2, 64),
+			strconv.FormatFloat(n.Idel, 'f', 2, 64),
+			strconv.FormatFloat(n.Orq, 'f', 2, 64),
+			strconv.FormatFloat(n.Asmrq, 'f', 2, 64),
+			strconv.FormatFloat(n.Asmok, 'f', 2, 64),
+			strconv.FormatFloat(n.Fragok, 'f', 2, 64),
+			strconv.FormatFloat(n.Fragcrt, 'f', 2, 64),
+			strconv.FormatFloat(n.Active, 'f', 2, 64),
+			strconv.FormatFloat(n.Passive, 'f', 2, 64),
+			strconv.FormatFloat(n.Iseg, 'f', 2, 64),
+			strconv.FormatFloat(n.Oseg, 'f', 2, 64),
+			strconv.FormatFloat(n.Atmptf, 'f', 2, 64),
+			strconv.FormatFloat(n.Estres, 'f', 2, 64),
+			strconv.FormatFloat(n.Retrans, 'f', 2, 64),
+			strconv.FormatFloat(n.Isegerr, 'f', 2, 64),
+			strconv.FormatFloat(n.Orsts, 'f', 2, 64),
+			strconv.FormatFloat(n.Idgm, 'f', 2, 64),
+			strconv.FormatFloat(n.Odgm, 'f', 2, 64),
+			strconv.FormatFloat(n.Noport, 'f', 2, 64),
+			strconv.FormatFloat(n.Idgmerr, 'f', 2, 64),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportNetstatCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	netstat, err := api.db.NetstatSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export netstat", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get netstat: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=netstat_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "lstovf", "lstdrp", "tmout", "synretr", "fretr", "lostretr", "abortto"})
+
+	for _, n := range netstat {
+		csvWriter.Write([]string{
+			strconv.FormatUint(n.RunID, 10),
+			strconv.FormatInt(n.Timestamp, 10),
+			strconv.FormatInt(n.Start, 10),
+			strconv.FormatInt(n.Duration, 10),
+			strconv.FormatFloat(n.Lstovf, 'f', 2, 64),
+			strconv.FormatFloat(n.Lstdrp, 'f', 2, 64),
+			strconv.FormatFloat(n.Tmout, 'f', 2, 64),
+			strconv.FormatFloat(n.Synretr, 'f', 2, 64),
+			strconv.FormatFloat(n.Fretr, 'f', 2, 64),
+			strconv.FormatFloat(n.Lostretr, 'f', 2, 64),
+			strconv.FormatFloat(n.Abortto, 'f', 2, 64),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportInterruptsCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	interrupts, err := api.db.InterruptsSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export interrupts", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get interrupts: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=interrupts_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "cpu", "source", "description", "intr"})
+
+	for _, i := range interrupts {
+		csvWriter.Write([]string{
+			strconv.FormatUint(i.RunID, 10),
+			strconv.FormatInt(i.Timestamp, 10),
+			strconv.FormatInt(i.Start, 10),
+			strconv.FormatInt(i.Duration, 10),
+			strconv.Itoa(i.CPU),
+			i.Source,
+			i.Description,
+			strconv.FormatFloat(i.Intr, 'f', 2, 64),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportStatfsCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	statfs, err := api.db.StatfsSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export statfs", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get statfs: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=statfs_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "name", "mountpoint", "fstype", "mbsize", "mbfree", "mbused", "fsused", "ufsused", "inodes", "ifree", "iused", "percentiused"})
+
+	for _, f := range statfs {
+		csvWriter.Write([]string{
+			strconv.FormatUint(f.RunID, 10),
+			strconv.FormatInt(f.Timestamp, 10),
+			strconv.FormatInt(f.Start, 10),
+			strconv.FormatInt(f.Duration, 10),
+			f.Name,
+			f.MountPoint,
+			f.FSType,
+			strconv.FormatFloat(f.MBSize, 'f', 2, 64),
+			strconv.FormatFloat(f.MBFree, 'f', 2, 64),
+			strconv.FormatFloat(f.MBUsed, 'f', 2, 64),
+			strconv.FormatFloat(f.FSUsed, 'f', 2, 64),
+			strconv.FormatFloat(f.UFSUsed, 'f', 2, 64),
+			strconv.FormatUint(f.Inodes, 10),
+			strconv.FormatUint(f.IFree, 10),
+			strconv.FormatUint(f.IUsed, 10),
+			strconv.FormatFloat(f.PercentIUsed, 'f', 2, 64),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportCPUFreqCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	cpufreq, err := api.db.CPUFreqSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export cpufreq", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get cpufreq: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=cpufreq_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "cpu", "mhz"})
+
+	for _, c := range cpufreq {
+		csvWriter.Write([]string{
+			strconv.FormatUint(c.RunID, 10),
+			strconv.FormatInt(c.Timestamp, 10),
+			strconv.FormatInt(c.Start, 10),
+			strconv.FormatInt(c.Duration, 10),
+			strconv.Itoa(c.CPU),
+			strconv.FormatFloat(c.MHz, 'f', 2, 64),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportNodeCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	nodes, err := api.db.NodeSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export node", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get node: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=node_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "node", "memtotal", "memfree", "memused", "percentused", "active", "inactive", "filepages", "anonpages", "slab", "dirty"})
+
+	for _, n := range nodes {
+		csvWriter.Write([]string{
+			strconv.FormatUint(n.RunID, 10),
+			strconv.FormatInt(n.Timestamp, 10),
+			strconv.FormatInt(n.Start, 10),
+			strconv.FormatInt(n.Duration, 10),
+			strconv.Itoa(n.Node),
+			strconv.FormatUint(n.MemTotal, 10),
+			strconv.FormatUint(n.MemFree, 10),
+			strconv.FormatUint(n.MemUsed, 10),
+			strconv.FormatFloat(n.PercentUsed, 'f', 2, 64),
+			strconv.FormatUint(n.Active, 10),
+			strconv.FormatUint(n.Inactive, 10),
+			strconv.FormatUint(n.FilePages, 10),
+			strconv.FormatUint(n.AnonPages, 10),
+			strconv.FormatUint(n.Slab, 10),
+			strconv.FormatUint(n.Dirty, 10),
+		})
+	}
+	csvWriter.Flush()
+}
+
+func (api *APIServer) exportEventCSV(w http.ResponseWriter, r *http.Request) {
+	runIDStr := r.PathValue("runID")
+	runID, err := strconv.ParseUint(runIDStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid run ID")
+		return
+	}
+
+	events, err := api.db.EventSelect(r.Context(), runID)
+	if err != nil {
+		api.logger.Error("failed to export events", slog.Uint64("runID", runID), slog.String("error", err.Error()))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get events: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=events_run_%d.csv", runID))
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write([]string{"runid", "timestamp", "start", "duration", "system", "type", "name", "description"})
+
+	for _, e := range events {
+		csvWriter.Write([]string{
+			strconv.FormatUint(e.RunID, 10),
+			strconv.FormatInt(e.Timestamp, 10),
+			strconv.FormatInt(e.Start, 10),
+			strconv.FormatInt(e.Duration, 10),
+			e.System,
+			e.Type,
+			e.Name,
+			e.Description,
 		})
 	}
 	csvWriter.Flush()
@@ -552,12 +1212,32 @@ func main() {
 	mux.HandleFunc("GET /api/v1/runs/{runID}/meminfo", api.getMeminfoHandler)
 	mux.HandleFunc("GET /api/v1/runs/{runID}/netdev", api.getNetDevHandler)
 	mux.HandleFunc("GET /api/v1/runs/{runID}/diskstat", api.getDiskstatHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/pressure", api.getPressureHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/vmstat", api.getVmstatHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/loadavg", api.getLoadavgHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/netsnmp", api.getNetSNMPHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/netstat", api.getNetstatHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/interrupts", api.getInterruptsHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/statfs", api.getStatfsHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/cpufreq", api.getCPUFreqHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/node", api.getNodeHandler)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/events", api.getEventHandler)
 
 	// Export endpoints (CSV)
 	mux.HandleFunc("GET /api/v1/runs/{runID}/stats/export", api.exportStatsCSV)
 	mux.HandleFunc("GET /api/v1/runs/{runID}/meminfo/export", api.exportMeminfoCSV)
 	mux.HandleFunc("GET /api/v1/runs/{runID}/netdev/export", api.exportNetDevCSV)
 	mux.HandleFunc("GET /api/v1/runs/{runID}/diskstat/export", api.exportDiskstatCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/pressure/export", api.exportPressureCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/vmstat/export", api.exportVmstatCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/loadavg/export", api.exportLoadavgCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/netsnmp/export", api.exportNetSNMPCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/netstat/export", api.exportNetstatCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/interrupts/export", api.exportInterruptsCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/statfs/export", api.exportStatfsCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/cpufreq/export", api.exportCPUFreqCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/node/export", api.exportNodeCSV)
+	mux.HandleFunc("GET /api/v1/runs/{runID}/events/export", api.exportEventCSV)
 
 	// Wrap with logging middleware
 	httpHandler := api.loggingMiddleware(mux)
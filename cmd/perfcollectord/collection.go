@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/businessperformancetuning/perfcollector/cmd/perfcollectord/spool"
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// reCollectionName matches valid collection names. Names are used as
+// directory names and therefore must be restricted.
+var reCollectionName = regexp.MustCompile("^[[:alnum:]_-]+$")
+
+// validCollectionName returns true if name can be used as a collection name.
+func validCollectionName(name string) bool {
+	return len(name) <= 64 && reCollectionName.MatchString(name)
+}
+
+// collection is a named collection of measurements. Every collection has its
+// own spool and therefore its own sequence numbers. A collection remains
+// known, while stopped, until its spool has been drained.
+type collection struct {
+	name  string
+	spool *spool.Spool
+
+	// Protected by PerfCollector mutex.
+	sc       *types.PCStartCollection // Running collection, nil if stopped
+	stopC    chan struct{}            // Closed to stop the collection
+	done     chan struct{}            // Closed when collecting exited
+	missed   uint64                   // Ticks missed since start
+	lateness time.Duration            // Lateness of the last tick
+}
+
+// openCollection opens the spool of the named collection.
+func (p *PerfCollector) openCollection(name string) (*collection, error) {
+	s, err := spool.Open(filepath.Join(p.cfg.DataDir,
+		defaultSpoolDirname, name), p.cfg.spoolMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	return &collection{
+		name:  name,
+		spool: s,
+	}, nil
+}
+
+// loadCollections opens the spools that were left behind by a previous run so
+// that their measurements are delivered.
+func (p *PerfCollector) loadCollections() error {
+	des, err := os.ReadDir(filepath.Join(p.cfg.DataDir,
+		defaultSpoolDirname))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	for _, de := range des {
+		if !de.IsDir() || !validCollectionName(de.Name()) {
+			continue
+		}
+		c, err := p.openCollection(de.Name())
+		if err != nil {
+			return err
+		}
+		if n := c.spool.Len(); n > 0 {
+			log.Infof("Spooled      : %v %v measurements",
+				c.name, n)
+		}
+		p.collections[c.name] = c
+	}
+	p.reapCollections()
+
+	return nil
+}
+
+// reapCollections forgets stopped collections that have nothing left to
+// deliver. Collections that were stopped but did not spool their stop
+// record yet are kept. The spool directory is left in place so that sequence
+// numbers continue when a collection with the same name is started. Must be
+// called with the lock held.
+func (p *PerfCollector) reapCollections() {
+	for k, c := range p.collections {
+		if c.sc != nil || c.done != nil || c.spool.Len() != 0 {
+			continue
+		}
+		if err := c.spool.Close(); err != nil {
+			log.Errorf("reapCollections %v: %v", k, err)
+		}
+		delete(p.collections, k)
+	}
+}
+
+// getCollections returns all known collections sorted by name.
+func (p *PerfCollector) getCollections() []*collection {
+	p.Lock()
+	defer p.Unlock()
+
+	cs := make([]*collection, 0, len(p.collections))
+	for _, c := range p.collections {
+		cs = append(cs, c)
+	}
+	sort.Slice(cs, func(i, j int) bool {
+		return cs[i].name < cs[j].name
+	})
+	return cs
+}
+
+// collectorRunning return true if any collection is running.
+func (p *PerfCollector) collectorRunning() bool {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, c := range p.collections {
+		if c.sc != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// closeCollections closes the spools of all collections.
+func (p *PerfCollector) closeCollections() {
+	p.Lock()
+	defer p.Unlock()
+
+	for k, c := range p.collections {
+		if err := c.spool.Close(); err != nil {
+			log.Errorf("closeCollections %v: %v", k, err)
+		}
+	}
+}
@@ -14,16 +14,22 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/businessperformancetuning/perfcollector/cmd/perfcollectord/sharedconfig"
 	"github.com/businessperformancetuning/perfcollector/util"
+	"github.com/inhies/go-bytesize"
 	flags "github.com/jessevdk/go-flags"
 )
 
 const (
-	defaultLogLevel    = "info"
-	defaultLogDirname  = "logs"
-	defaultLogFilename = "perfcollectord.log"
+	defaultLogLevel     = "info"
+	defaultLogDirname   = "logs"
+	defaultLogFilename  = "perfcollectord.log"
+	defaultSpoolDirname = "spool"
+	defaultSpoolMaxSize = "1GB"
+	defaultSinkDirname  = "sinks"
+	defaultSinkExpiry   = 7 * 24 * time.Hour
 )
 
 var (
@@ -52,6 +58,16 @@ type config struct {
 	Version     string
 	SSHKeyFile  string   `long:"sshid" description:"File containing the ssh identity"`
 	AllowedKeys []string `long:"allowedkeys" description:"Allowed SSH fingerprints)"`
+
+	SpoolMaxSize string `long:"spoolmaxsize" description:"Maximum on-disk size of the measurement spool, e.g. 512MB (0 is unlimited)"`
+
+	SinkExpiry time.Duration `long:"sinkexpiry" description:"Forget sinks that have not connected for this long, spooled measurements are retained for known sinks (0 never forgets)"`
+
+	Allow []string `long:"allow" description:"Only allow reading /proc and /sys paths that match this glob pattern, e.g. /sys/class/net (default allows all)"`
+	Deny  []string `long:"deny" description:"Deny reading /proc and /sys paths that match this glob pattern, e.g. /proc/*/status (added to the built-in deny list)"`
+
+	spoolMaxSize int64        // Parsed SpoolMaxSize
+	policy       *util.Policy // Parsed Allow and Deny
 }
 
 // serviceOptions defines the configuration options for the rpc as a service
@@ -223,6 +239,9 @@ func loadConfig() (*config, []string, error) {
 		LogDir:     defaultLogDir,
 		SSHKeyFile: defaultSSHKeyFile,
 		Version:    version(),
+
+		SpoolMaxSize: defaultSpoolMaxSize,
+		SinkExpiry:   defaultSinkExpiry,
 	}
 
 	// Service options which are only added on Windows.
@@ -407,6 +426,28 @@ func loadConfig() (*config, []string, error) {
 		}
 	}
 
+	// Verify spool size.
+	if cfg.SpoolMaxSize != "0" {
+		spoolMaxSize, err := bytesize.Parse(cfg.SpoolMaxSize)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid spoolmaxsize: %v",
+				err)
+		}
+		cfg.spoolMaxSize = int64(spoolMaxSize)
+	}
+
+	// Verify sink expiry.
+	if cfg.SinkExpiry < 0 {
+		return nil, nil, fmt.Errorf("invalid sinkexpiry: %v",
+			cfg.SinkExpiry)
+	}
+
+	// Verify path policy.
+	cfg.policy, err = util.NewPolicy(cfg.Allow, cfg.Deny)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Verify that we have at least one key set.
 	if len(cfg.AllowedKeys) == 0 {
 		return nil, nil, fmt.Errorf("must set at least one allowed key " +
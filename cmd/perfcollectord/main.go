@@ -15,7 +15,6 @@ import (
 	"syscall"
 	"time"
 
-	ch "github.com/businessperformancetuning/perfcollector/channel"
 	"github.com/businessperformancetuning/perfcollector/types"
 	"github.com/businessperformancetuning/perfcollector/util"
 	"github.com/davecgh/go-spew/spew"
@@ -51,168 +50,102 @@ func internalError(cmd types.PCCommand, err error) ([]byte, error) {
 type PerfCollector struct {
 	sync.Mutex
 
-	sinkC chan interface{}
-
-	stopCollectionC chan struct{} // XXX this probably should go into the sink
-
 	cfg *config
 
 	allowedKeys map[string]struct{}
-}
-
-// sinkRegister registers the provided encoder as the sink.
-type sinkRegister struct {
-	encoder *gob.Encoder
-	replyC  chan error
-}
-
-// sinkStatusReply is the reply to a sinkStatus request.
-type sinkStatusReply struct {
-	sink        bool // True if encoder is valid
-	measurement bool // True if measurement queue is valid
 
-	startCollection *types.PCStartCollection // Original command
-
-	measurementsFree int // Number of free measurement slots
+	collections map[string]*collection // Collections by name
+	sinks       map[string]*sink       // Known sinks by name
 }
 
-// sinkStatus requests a status update from the sink.
-type sinkStatus struct {
-	replyC chan sinkStatusReply
-}
+// drainBatch is the number of spooled measurements that are read and sunk in
+// one go.
+const drainBatch = 64
 
-// measurementRegister registers te provided channel as te measurement queue.
-type measurementRegister struct {
-	startCollection types.PCStartCollection
-	measurementC    chan *types.PCCollection
+// drain sends the spooled measurements of a collection, in order and starting
+// at sequence number cursor, into the provided encoder. Measurements remain in
+// the spool until the sink acknowledges them. It returns the sequence number
+// of the next measurement to send.
+func (p *PerfCollector) drain(c *collection, encoder *gob.Encoder, cursor uint64) (uint64, error) {
+	for {
+		ms, next, err := c.spool.Read(cursor, drainBatch)
+		if err != nil {
+			return cursor, fmt.Errorf("read %v: %v", c.name, err)
+		}
+		if len(ms) == 0 {
+			return next, nil
+		}
+		for _, m := range ms {
+			err = encoder.Encode(m)
+			if err != nil {
+				return cursor, fmt.Errorf("encode %v: %v",
+					c.name, err)
+			}
+			cursor = m.Sequence + 1
+		}
+		log.Tracef("drain: sent %v %v measurements", c.name, len(ms))
+	}
 }
 
-// measurementDrain instruct the sink to drain the measurement queue.
-type measurementDrain struct{}
-
-// getSinkStatus returns the current sink status.
-func (p *PerfCollector) getSinkStatus(ctx context.Context) (*sinkStatusReply, error) {
-	replyC := make(chan sinkStatusReply)
-	err := ch.Write(ctx, p.sinkC, sinkStatus{
-		replyC: replyC,
-	})
-	if err != nil {
-		return nil, err
+// resume prepares the spool of a collection for a sink that has durably
+// stored everything prior to sequence number resume. It returns the sequence
+// number of the first measurement to send. Measurements are trimmed once all
+// sinks have stored them.
+func (p *PerfCollector) resume(c *collection, resume uint64) (uint64, error) {
+	if resume == 0 {
+		// Sink has no prior state, send everything.
+		return c.spool.First(), nil
 	}
 
-	ssr, err := ch.Read(ctx, replyC)
+	// The sink is ahead of us, for example because the spool was
+	// removed. Continue numbering after what the sink has seen or else it
+	// will discard new measurements as duplicates.
+	advanced, err := c.spool.Advance(resume)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	//log.Errorf("%T", ssr)
-	s := ssr.(sinkStatusReply)
-	return &s, nil
-}
-
-// collectorRunning return true if the collector is running.
-func (p *PerfCollector) collectorRunning(ctx context.Context) (bool, error) {
-	ss, err := p.getSinkStatus(ctx)
-	if err != nil {
-		return false, err
+	if advanced {
+		log.Warnf("Sink resumes %v at %v which is beyond the spool, "+
+			"renumbering measurements", c.name, resume)
+		return resume, nil
 	}
-	return ss.measurement, nil
-}
 
-func (p *PerfCollector) sink(ctx context.Context) {
-	log.Tracef("sink")
-	defer log.Tracef("sink exit")
+	first := c.spool.First()
+	if resume < first {
+		log.Warnf("Measurements %v %v-%v were discarded before they "+
+			"were sent", c.name, resume, first-1)
+		return first, nil
+	}
 
-	var (
-		encoder         *gob.Encoder
-		measurementC    chan *types.PCCollection
-		startCollection types.PCStartCollection
-	)
+	return resume, nil
+}
 
-	for {
-	restart:
-		log.Tracef("sink loop")
-		select {
-		case <-ctx.Done():
-		case cmd, ok := <-p.sinkC:
-			if !ok {
-				log.Errorf("sink: sink channel died")
-				return
+// drainAll sends the spooled measurements of all collections into the
+// provided encoder. The cursors map tracks the next sequence number to send
+// per collection and is updated. Collections that have not been seen before
+// start at their resume sequence number.
+func (p *PerfCollector) drainAll(encoder *gob.Encoder, cursors, resume map[string]uint64) error {
+	for _, c := range p.getCollections() {
+		cursor, ok := cursors[c.name]
+		if !ok {
+			var err error
+			cursor, err = p.resume(c, resume[c.name])
+			if err != nil {
+				return fmt.Errorf("resume %v: %v", c.name, err)
 			}
-
-			switch c := cmd.(type) {
-			case sinkRegister:
-				log.Tracef("sink: sinkRegister %p", c.encoder)
-				if encoder != nil && c.encoder != nil {
-					log.Tracef("sink: sink already registered")
-					ch.WriteNB(ctx, c.replyC,
-						fmt.Errorf("sink already registered"))
-				} else {
-					encoder = c.encoder
-					ch.WriteNB(ctx, c.replyC, nil)
-				}
-				continue
-
-			case measurementRegister:
-				log.Tracef("sink: measurementRegister %p",
-					c.measurementC)
-				startCollection = c.startCollection
-				measurementC = c.measurementC
-				continue
-
-			case sinkStatus:
-				var sc *types.PCStartCollection
-				if measurementC != nil {
-					scCopy := startCollection
-					sc = &scCopy
-				}
-				ch.WriteNB(ctx, c.replyC, sinkStatusReply{
-					sink:            encoder != nil,
-					measurement:     measurementC != nil,
-					startCollection: sc,
-					measurementsFree: cap(measurementC) -
-						len(measurementC),
-				})
-
-			case measurementDrain:
-				if encoder == nil {
-					log.Tracef("sink: measurementDrain: "+
-						"no encoder %v",
-						len(measurementC))
-					continue
-				}
-				if measurementC == nil {
-					log.Tracef("sink: measurementDrain: " +
-						"no channel")
-					continue
-				}
-				// XXX this code needs to come out.
-				// Queue measurement and drain elsewhere.
-				for {
-					m, err := ch.ReadNB(ctx, measurementC)
-					if err == ch.ErrChannelBusy {
-						log.Tracef("sink ReadNB: %v",
-							err)
-						goto restart
-					} else if err != nil {
-						log.Errorf("sink ReadNB: %v",
-							err)
-						goto restart
-					}
-					log.Tracef("Sent measurement")
-					err = encoder.Encode(m)
-					if err != nil {
-						log.Errorf("sink encoder: %v",
-							err)
-						encoder = nil
-						continue
-					}
-				}
-
-			default:
-				log.Errorf("sink: unknown type %T", cmd)
+			if n := c.spool.Next() - cursor; n > 0 {
+				log.Infof("Replaying %v spooled %v "+
+					"measurements starting at %v", n,
+					c.name, cursor)
 			}
 		}
+		cursor, err := p.drain(c, encoder, cursor)
+		cursors[c.name] = cursor
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func (p *PerfCollector) publicKeyCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
@@ -226,136 +159,207 @@ func (p *PerfCollector) publicKeyCallback(conn ssh.ConnMetadata, key ssh.PublicK
 		return nil, fmt.Errorf("unknown key: %v", fp)
 	}
 
-	return &ssh.Permissions{}, nil
+	return &ssh.Permissions{
+		Extensions: map[string]string{"fingerprint": fp},
+	}, nil
 }
 
-func (p *PerfCollector) handleRegisterSink(ctx context.Context, cmd types.PCCommand, channel ssh.Channel) (func(), []byte, error) {
+// checkPolicy verifies that all paths may be read according to the path
+// policy. Rejected paths are written to the audit log.
+func checkPolicy(peer string, cmd types.PCCommand, paths []string) error {
+	for _, v := range paths {
+		err := util.CheckPolicy(v)
+		if err != nil {
+			log.Warnf("AUDIT: %v %v rejected: %v", peer, cmd.Cmd, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *PerfCollector) handleRegisterSink(ctx context.Context, cmd types.PCCommand, channel ssh.Channel) (*sink, []byte, error) {
 	log.Tracef("handleRegisterSink %v", cmd.Tag)
 	defer log.Tracef("handleRegisterSink %v exit", cmd.Tag)
 
-	// Instruct sink to register gob encoder.
-	replyC := make(chan error)
-	if err := ch.Write(ctx, p.sinkC, sinkRegister{
-		encoder: gob.NewEncoder(channel),
-		replyC:  replyC,
-	}); err != nil {
-		return nil, nil, err
+	// Older sinks do not send a payload.
+	var rs types.PCRegisterSink
+	if cmd.Payload != nil {
+		var ok bool
+		rs, ok = cmd.Payload.(types.PCRegisterSink)
+		if !ok {
+			reply, err := protocolError(cmd.Tag, "command type "+
+				"assertion error %v, %T", cmd.Cmd, cmd.Payload)
+			return nil, reply, err
+		}
 	}
-
-	// Non-block read of sink reply.
-	err2, readErr := ch.Read(ctx, replyC)
-	if readErr != nil {
-		return nil, nil, readErr
+	if rs.Name == "" {
+		rs.Name = types.PCDefaultSink
+	}
+	if !validSinkName(rs.Name) {
+		reply, err := protocolError(cmd.Tag, "invalid sink name")
+		return nil, reply, err
 	}
-	if err2 != nil {
+
+	// Register gob encoder and start sending measurements.
+	sk, err := p.registerSink(ctx, rs.Name, gob.NewEncoder(channel),
+		rs.Resume)
+	if err != nil {
 		reply, err := protocolError(cmd.Tag, "command %v: %v",
-			cmd.Cmd, err2)
+			cmd.Cmd, err)
 		return nil, reply, err
 	}
+	log.Infof("Sink registered: %v", rs.Name)
 
 	reply, err := types.Encode(types.PCCommand{
 		Version: types.PCVersion,
 		Tag:     cmd.Tag,
 		Cmd:     types.PCAck,
 	})
-
-	var callback func()
-	if err == nil {
-		// Set unregister callback
-		callback = func() {
-			log.Tracef("handleRegisterSink: unregister callback")
-			err := ch.Write(ctx, p.sinkC, sinkRegister{
-				encoder: nil,
-			})
-			if err != nil {
-				log.Errorf("handleRegisterSink unregister: %v",
-					err)
-			}
-		}
+	if err != nil {
+		p.unregisterSink(sk)
+		return nil, nil, err
 	}
 
-	return callback, reply, err
+	return sk, reply, nil
 }
 
-func (p *PerfCollector) startCollection(ctx context.Context, sc types.PCStartCollection) {
-	log.Tracef("startCollection %v", sc.Frequency)
-	defer log.Tracef("startCollection %v exit", sc.Frequency)
+func (p *PerfCollector) handleAckSequence(cmd types.PCCommand, sk *sink) ([]byte, error) {
+	log.Tracef("handleAckSequence %v", cmd.Cmd)
+	defer log.Tracef("handleAckSequence %v exit", cmd.Cmd)
 
-	// Message new measurements channel
-	measurements := make(chan *types.PCCollection, sc.QueueDepth)
-	err := ch.Write(ctx, p.sinkC, measurementRegister{
-		startCollection: sc,
-		measurementC:    measurements,
-	})
+	as, ok := cmd.Payload.(types.PCAckSequence)
+	if !ok {
+		return protocolError(cmd.Tag, "command type "+
+			"assertion error %v, %T", cmd.Cmd, as)
+	}
+	if as.Collection == "" {
+		as.Collection = types.PCDefaultCollection
+	}
+
+	// Only registered sinks acknowledge measurements.
+	if sk == nil {
+		return protocolError(cmd.Tag, "sink not registered")
+	}
+
+	// Measurements that were stored by all sinks no longer need to be
+	// retained.
+	err := p.ackSink(sk, as.Collection, as.Sequence)
 	if err != nil {
-		log.Errorf("startCollection Write: %v", err)
-		return
+		return internalError(cmd, err)
+	}
+
+	// Ack remote.
+	reply := types.PCCommand{
+		Version: types.PCVersion,
+		Tag:     cmd.Tag,
+		Cmd:     types.PCAck,
+	}
+	return types.Encode(reply)
+}
+
+// spoolRun spools run r as a PCRunSystem measurement of collection c so that
+// sinks see it in order with the measurements of the run.
+func (p *PerfCollector) spoolRun(c *collection, r types.PCRun) error {
+	m, err := types.EncodeRun(c.name, r)
+	if err != nil {
+		return err
 	}
+	_, err = c.spool.Append(m)
+	if err != nil {
+		return err
+	}
+	return c.spool.Sync()
+}
+
+func (p *PerfCollector) startCollection(ctx context.Context, c *collection, sc types.PCStartCollection, start time.Time, stopC, done chan struct{}) {
+	log.Tracef("startCollection %v %v", c.name, sc.Frequency)
+	defer log.Tracef("startCollection %v %v exit", c.name, sc.Frequency)
 
-	p.stopCollectionC = make(chan struct{})
+	defer func() {
+		p.Lock()
+		// A stopped collection is not restarted before done is
+		// closed.
+		if c.stopC == stopC {
+			c.sc = nil
+			c.stopC = nil
+		}
+		c.done = nil
+		close(done)
+		p.reapCollections()
+		p.Unlock()
+	}()
 
-	t := time.Tick(sc.Frequency) // XXX Replace this with an elapsed time counter
+	next := nextTick(time.Now(), sc.Frequency)
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-p.stopCollectionC:
-			err := ch.Write(ctx, p.sinkC, measurementRegister{
-				measurementC: nil,
+		case <-stopC:
+			// Close the run after its last measurement.
+			err := p.spoolRun(c, types.PCRun{
+				Name:            sc.Run,
+				Start:           start,
+				Stop:            time.Now(),
+				StartCollection: sc,
 			})
 			if err != nil {
-				log.Errorf("startCollection Write2: %v", err)
+				log.Errorf("startCollection %v: stop run: %v",
+					c.name, err)
 			}
-			p.stopCollectionC = nil
+			p.notifySinks()
 			return
+		case <-timer.C:
+		}
 
-		case <-t:
-			timestamp := time.Now()
-			for _, v := range sc.Systems {
-				m := types.PCCollection{
-					System:    v,
-					Timestamp: timestamp,    // Overall timestamp
-					Start:     time.Now(),   // This timestamp
-					Frequency: sc.Frequency, // Frequency
-				}
-
-				blob, err := util.Measure(v)
-				if err != nil {
-					log.Errorf("startCollection: %v", err)
-					// Abort measurement.
-					continue
-				}
-				m.Measurement = string(blob)
-
-				m.Duration = time.Now().Sub(m.Timestamp)
-
-				// Spill last measurement if queue depth is
-				// reached
-				err = ch.WriteNB(ctx, measurements, &m)
-				if err != nil && err == ch.ErrChannelBusy {
-					log.Tracef("startCollection spill: %v",
-						len(measurements))
-				} else if err != nil {
-					// This may be fatal but let ctx deal
-					// with that.
-					log.Errorf("startCollection WriteNB: %v",
-						err)
-				}
-			}
-			// Signal once that there are measurements to drain.
-			// XXX think about always draining. This may not be a
-			// good idea when we are polling performance every
-			// second.
-			err = ch.WriteNB(ctx, p.sinkC, measurementDrain{})
+		tick, missed := schedule(time.Now(), next, sc.Frequency)
+		if missed > 0 {
+			log.Warnf("startCollection %v: missed %v ticks", c.name,
+				missed)
+		}
+		lateness := time.Since(tick)
+
+		ms := measureAll(tick, &sc)
+		for _, m := range ms {
+			m.Missed = missed
+
+			// Spool measurement. The oldest measurements are
+			// discarded when the spool is full.
+			dropped, err := c.spool.Append(m)
 			if err != nil {
-				log.Tracef("measurementDrain signal failed: %v",
-					err)
+				log.Errorf("startCollection Append: %v", err)
+			} else if dropped > 0 {
+				log.Warnf("startCollection %v: spool full, "+
+					"discarded %v measurements", c.name,
+					dropped)
 			}
 		}
+		err := c.spool.Sync()
+		if err != nil {
+			log.Errorf("startCollection Sync: %v", err)
+		}
+
+		p.Lock()
+		if c.stopC == stopC {
+			c.missed += uint64(missed)
+			c.lateness = lateness
+		}
+		p.Unlock()
+
+		// Signal once that there are measurements to drain.
+		// XXX think about always draining. This may not be a good idea
+		// when we are polling performance every second.
+		p.notifySinks()
+
+		// Schedule the next tick relative to this tick and not to the
+		// time it took to measure to prevent drift.
+		next = tick.Add(sc.Frequency)
+		timer.Reset(time.Until(next))
 	}
 }
 
-func (p *PerfCollector) handleStartCollection(ctx context.Context, cmd types.PCCommand, channel ssh.Channel) ([]byte, error) {
+func (p *PerfCollector) handleStartCollection(ctx context.Context, peer string, cmd types.PCCommand, channel ssh.Channel) ([]byte, error) {
 	log.Tracef("handleStartCollection %v", cmd.Cmd)
 	defer log.Tracef("handleStartCollection %v exit", cmd.Cmd)
 
@@ -365,11 +369,24 @@ func (p *PerfCollector) handleStartCollection(ctx context.Context, cmd types.PCC
 			"assertion error %v, %T", cmd.Cmd, sc)
 	}
 
+	// Verify name.
+	if sc.Name == "" {
+		sc.Name = types.PCDefaultCollection
+	}
+	if !validCollectionName(sc.Name) {
+		return protocolError(cmd.Tag, "invalid collection name")
+	}
+
 	// Verify frequency.
-	if sc.Frequency < time.Second {
+	if sc.Frequency < types.PCMinFrequency {
 		return protocolError(cmd.Tag, "bad frequency")
 	}
 
+	// Verify queue depth.
+	if sc.QueueDepth < 0 {
+		return protocolError(cmd.Tag, "bad queue depth")
+	}
+
 	// Verify that all systems exist.
 	for _, v := range sc.Systems {
 		if util.ValidSystem(v) {
@@ -377,24 +394,83 @@ func (p *PerfCollector) handleStartCollection(ctx context.Context, cmd types.PCC
 		}
 		return protocolError(cmd.Tag, "invalid system %v", v)
 	}
+	if err := checkPolicy(peer, cmd, sc.Systems); err != nil {
+		return protocolError(cmd.Tag, "%v", err)
+	}
+
+	// Verify process selector.
+	if err := util.ValidProcessSelector(sc.Processes); err != nil {
+		return protocolError(cmd.Tag, "invalid process selector: %v",
+			err)
+	}
+
+	// Name the run after the collection and its start time unless the
+	// operator named it.
+	start := time.Now()
+	if sc.Run == "" {
+		sc.Run = sc.Name + "-" + start.UTC().Format("20060102T150405Z")
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	// Only allow one collection per name to run. A collection that is
+	// being stopped must spool its stop record first, otherwise it could
+	// end up after the start of the new run.
+	c, ok := p.collections[sc.Name]
+	for ok && c.sc == nil && c.done != nil {
+		done := c.done
+		p.Unlock()
+		<-done
+		p.Lock()
+		c, ok = p.collections[sc.Name]
+	}
+	if ok && c.sc != nil {
+		return protocolError(cmd.Tag, "collection %v already running",
+			sc.Name)
+	}
+	if !ok {
+		var err error
+		c, err = p.openCollection(sc.Name)
+		if err != nil {
+			return internalError(cmd, err)
+		}
+		p.collections[sc.Name] = c
+	}
 
-	// Only allow one collection to run.
-	cr, err := p.collectorRunning(ctx)
+	// Limit the number of spooled measurements.
+	dropped, err := c.spool.SetMaxEntries(sc.QueueDepth)
 	if err != nil {
 		return internalError(cmd, err)
 	}
-	if cr {
-		return protocolError(cmd.Tag, "collector already running")
+	if dropped > 0 {
+		log.Warnf("handleStartCollection %v: discarded %v spooled "+
+			"measurements", sc.Name, dropped)
 	}
 
+	// Open the run ahead of its first measurement.
+	err = p.spoolRun(c, types.PCRun{
+		Name:            sc.Run,
+		Start:           start,
+		StartCollection: sc,
+	})
+	if err != nil {
+		return internalError(cmd, err)
+	}
+
+	c.sc = &sc
+	c.stopC = make(chan struct{})
+	c.done = make(chan struct{})
+	c.missed = 0
+	c.lateness = 0
+	go p.startCollection(ctx, c, sc, start, c.stopC, c.done)
+
+	// Ack remote.
 	reply := types.PCCommand{
 		Version: types.PCVersion,
 		Tag:     cmd.Tag,
 		Cmd:     types.PCAck,
 	}
-	go p.startCollection(ctx, sc)
-
-	// Ack remote.
 	return types.Encode(reply)
 }
 
@@ -402,20 +478,38 @@ func (p *PerfCollector) handleStopCollection(ctx context.Context, cmd types.PCCo
 	log.Tracef("handleStopCollection %v", cmd.Cmd)
 	defer log.Tracef("handleStopCollection %v exit", cmd.Cmd)
 
+	// An empty name stops all collections.
+	var name string
 	if cmd.Payload != nil {
-		return protocolError(cmd.Tag, "invalid stop collector payload")
+		sc, ok := cmd.Payload.(types.PCStopCollection)
+		if !ok {
+			return protocolError(cmd.Tag, "invalid stop collector "+
+				"payload")
+		}
+		name = sc.Name
 	}
 
-	cr, err := p.collectorRunning(ctx)
-	if err != nil {
-		return internalError(cmd, err)
+	p.Lock()
+	stopped := 0
+	for _, c := range p.collections {
+		if c.sc == nil || (name != "" && c.name != name) {
+			continue
+		}
+		close(c.stopC)
+		c.stopC = nil
+		c.sc = nil
+		stopped++
 	}
-	if !cr {
+	p.Unlock()
+
+	if stopped == 0 {
+		if name != "" {
+			return protocolError(cmd.Tag, "collection %v not "+
+				"running", name)
+		}
 		return protocolError(cmd.Tag, "collector not running")
 	}
 
-	close(p.stopCollectionC)
-
 	// Ack remote.
 	reply := types.PCCommand{
 		Version: types.PCVersion,
@@ -425,7 +519,7 @@ func (p *PerfCollector) handleStopCollection(ctx context.Context, cmd types.PCCo
 	return types.Encode(reply)
 }
 
-func (p *PerfCollector) handlePrepareReplay(ctx context.Context, cmd types.PCCommand, channel ssh.Channel) ([]byte, error) {
+func (p *PerfCollector) handlePrepareReplay(ctx context.Context, peer string, cmd types.PCCommand, channel ssh.Channel) ([]byte, error) {
 	log.Tracef("handlePrepareReplay %v", cmd.Cmd)
 	defer log.Tracef("handlePrepareReplay %v exit", cmd.Cmd)
 
@@ -442,7 +536,7 @@ func (p *PerfCollector) handlePrepareReplay(ctx context.Context, cmd types.PCCom
 	//}
 
 	// Verify frequency.
-	if sr.Frequency < time.Second {
+	if sr.Frequency < types.PCMinFrequency {
 		return protocolError(cmd.Tag, "bad frequency")
 	}
 
@@ -453,6 +547,9 @@ func (p *PerfCollector) handlePrepareReplay(ctx context.Context, cmd types.PCCom
 		}
 		return protocolError(cmd.Tag, "invalid system %v", v)
 	}
+	if err := checkPolicy(peer, cmd, sr.Systems); err != nil {
+		return protocolError(cmd.Tag, "%v", err)
+	}
 
 	// Only allow one replay to run.
 	//rr, err := p.replayRunning(ctx)
@@ -464,11 +561,7 @@ func (p *PerfCollector) handlePrepareReplay(ctx context.Context, cmd types.PCCom
 	//}
 
 	// Disallow replay when collecting
-	cr, err := p.collectorRunning(ctx)
-	if err != nil {
-		return internalError(cmd, err)
-	}
-	if cr {
+	if p.collectorRunning() {
 		return protocolError(cmd.Tag, "collector running")
 	}
 
@@ -490,27 +583,45 @@ func (p *PerfCollector) handleStatusCollection(ctx context.Context, cmd types.PC
 	log.Tracef("handleStatusCollection %v", cmd.Cmd)
 	defer log.Tracef("handleStatusCollection %v exit", cmd.Cmd)
 
-	ss, err := p.getSinkStatus(ctx)
-	if err != nil {
-		return internalError(cmd, err)
+	var scr types.PCStatusCollectionReply
+	scr.Sinks = p.getSinks()
+	for _, v := range scr.Sinks {
+		scr.SinkEnabled = scr.SinkEnabled || v.Connected
+	}
+	for _, c := range p.getCollections() {
+		cs := types.PCCollectionStatus{
+			Spooled:   c.spool.Len(),
+			SpoolSize: c.spool.Size(),
+		}
+		p.Lock()
+		if c.sc != nil {
+			cs.StartCollection = *c.sc
+			cs.Running = true
+			cs.Missed = c.missed
+			cs.Lateness = c.lateness
+		} else {
+			cs.StartCollection.Name = c.name
+		}
+		p.Unlock()
+		if cs.StartCollection.QueueDepth > 0 {
+			cs.QueueFree = cs.StartCollection.QueueDepth -
+				cs.Spooled
+		}
+		scr.MeasurementEnabled = scr.MeasurementEnabled || cs.Running
+		scr.Collections = append(scr.Collections, cs)
 	}
 
 	reply := types.PCCommand{
 		Version: types.PCVersion,
 		Tag:     cmd.Tag,
 		Cmd:     types.PCStatusCollectionReplyCmd,
-		Payload: types.PCStatusCollectionReply{
-			StartCollection:    ss.startCollection,
-			QueueFree:          ss.measurementsFree,
-			SinkEnabled:        ss.sink,
-			MeasurementEnabled: ss.measurement,
-		},
+		Payload: scr,
 	}
 
 	return types.Encode(reply)
 }
 
-func (p *PerfCollector) handleOnce(cmd types.PCCommand) ([]byte, error) {
+func (p *PerfCollector) handleOnce(peer string, cmd types.PCCommand) ([]byte, error) {
 	log.Tracef("handleOnce %v", cmd.Cmd)
 	defer log.Tracef("handleOnce %v exit", cmd.Cmd)
 
@@ -521,6 +632,10 @@ func (p *PerfCollector) handleOnce(cmd types.PCCommand) ([]byte, error) {
 			co)
 	}
 
+	if err := checkPolicy(peer, cmd, co.Systems); err != nil {
+		return protocolError(cmd.Tag, "%v", err)
+	}
+
 	payload := types.PCCollectOnceReply{
 		Values: make([][]byte, len(co.Systems)),
 	}
@@ -544,7 +659,7 @@ func (p *PerfCollector) handleOnce(cmd types.PCCommand) ([]byte, error) {
 	return types.Encode(reply)
 }
 
-func (p *PerfCollector) handleDirectories(cmd types.PCCommand) ([]byte, error) {
+func (p *PerfCollector) handleDirectories(peer string, cmd types.PCCommand) ([]byte, error) {
 	log.Tracef("handleDirectories %v", cmd.Cmd)
 	defer log.Tracef("handleDirectories %v exit", cmd.Cmd)
 
@@ -563,6 +678,9 @@ func (p *PerfCollector) handleDirectories(cmd types.PCCommand) ([]byte, error) {
 		}
 		return protocolError(cmd.Tag, "invalid directory: %v", v)
 	}
+	if err := checkPolicy(peer, cmd, cd.Directories); err != nil {
+		return protocolError(cmd.Tag, "%v", err)
+	}
 
 	payload := types.PCCollectDirectoriesReply{
 		Values: make([][]string, len(cd.Directories)),
@@ -596,12 +714,13 @@ func (p *PerfCollector) handleDirectories(cmd types.PCCommand) ([]byte, error) {
 	return types.Encode(reply)
 }
 
-func (p *PerfCollector) oobHandler(ctx context.Context, channel ssh.Channel, requests <-chan *ssh.Request) {
+func (p *PerfCollector) oobHandler(ctx context.Context, peer string, channel ssh.Channel, requests <-chan *ssh.Request) {
 	log.Tracef("oobHandler")
 	defer func() {
 		log.Tracef("oobHandler exit")
 	}()
 
+	var sk *sink // Sink registered on this channel
 	for req := range requests {
 		_ = req
 		// Always reply or else the other end may hang.
@@ -627,6 +746,23 @@ func (p *PerfCollector) oobHandler(ctx context.Context, channel ssh.Channel, req
 
 		log.Tracef("oobHandler %v", spew.Sdump(cmd))
 
+		if cmd.Version != types.PCVersion {
+			log.Errorf("oobHandler invalid protocol version: "+
+				"want %v got %v", types.PCVersion, cmd.Version)
+			reply, err := protocolError(cmd.Tag, "invalid protocol "+
+				"version: want %v got %v", types.PCVersion,
+				cmd.Version)
+			if err != nil {
+				log.Errorf("oobHandler protocolError: %v", err)
+				continue
+			}
+			_, err = channel.SendRequest(types.PCCmd, false, reply)
+			if err != nil {
+				log.Errorf("oobHandler SendRequest: %v", err)
+			}
+			continue
+		}
+
 		// From here on out we must ack every command incoming command.
 		// Replies do not need to be acked.
 
@@ -651,30 +787,36 @@ func (p *PerfCollector) oobHandler(ctx context.Context, channel ssh.Channel, req
 
 			// Commands that require ack
 		case types.PCRegisterSinkCmd:
-			var callback func()
-			callback, reply, err = p.handleRegisterSink(ctx, cmd,
-				channel)
-			if callback != nil {
+			if sk != nil {
+				reply, err = protocolError(cmd.Tag, "sink %v "+
+					"already registered", sk.name)
+				break
+			}
+			sk, reply, err = p.handleRegisterSink(ctx, cmd, channel)
+			if sk != nil {
 				// Unregister on exit.
-				defer callback()
+				defer p.unregisterSink(sk)
 			}
+		case types.PCAckSequenceCmd:
+			reply, err = p.handleAckSequence(cmd, sk)
+
 		case types.PCCollectOnceCmd:
-			reply, err = p.handleOnce(cmd)
+			reply, err = p.handleOnce(peer, cmd)
 
 		case types.PCCollectDirectoriesCmd:
-			reply, err = p.handleDirectories(cmd)
+			reply, err = p.handleDirectories(peer, cmd)
 
 		case types.PCStatusCollectionCmd:
 			reply, err = p.handleStatusCollection(ctx, cmd, channel)
 
 		case types.PCStartCollectionCmd:
-			reply, err = p.handleStartCollection(ctx, cmd, channel)
+			reply, err = p.handleStartCollection(ctx, peer, cmd, channel)
 
 		case types.PCStopCollectionCmd:
 			reply, err = p.handleStopCollection(ctx, cmd, channel)
 
 		case types.PCPrepareReplayCmd:
-			reply, err = p.handlePrepareReplay(ctx, cmd, channel)
+			reply, err = p.handlePrepareReplay(ctx, peer, cmd, channel)
 
 		default:
 			reply, err = protocolError(cmd.Tag, "unknown OOB "+
@@ -725,7 +867,13 @@ func (p *PerfCollector) handleChannel(ctx context.Context, conn *ssh.ServerConn,
 	}
 	defer channel.Close()
 
-	go p.oobHandler(ctx, channel, requests)
+	// Identify the remote end in the audit log.
+	peer := conn.RemoteAddr().String()
+	if conn.Permissions != nil {
+		peer += " " + conn.Permissions.Extensions["fingerprint"]
+	}
+
+	go p.oobHandler(ctx, peer, channel, requests)
 
 	for {
 		// We do not use the read sink in the collector. Just log the
@@ -803,7 +951,8 @@ func _main() error {
 	p := &PerfCollector{
 		cfg:         loadedCfg,
 		allowedKeys: make(map[string]struct{}),
-		sinkC:       make(chan interface{}),
+		collections: make(map[string]*collection),
+		sinks:       make(map[string]*sink),
 	}
 	for _, v := range p.cfg.AllowedKeys {
 		p.allowedKeys[v] = struct{}{}
@@ -811,6 +960,15 @@ func _main() error {
 
 	log.Infof("Version      : %v", version())
 	log.Infof("Home dir     : %v", p.cfg.HomeDir)
+	if len(p.cfg.Allow) != 0 {
+		log.Infof("Allow        : %v", strings.Join(p.cfg.Allow, " "))
+	}
+	if len(p.cfg.Deny) != 0 {
+		log.Infof("Deny         : %v", strings.Join(p.cfg.Deny, " "))
+	}
+
+	// Enforce the path policy on all measurements.
+	util.SetPolicy(p.cfg.policy)
 
 	// Create the data directory in case it does not exist.
 	err = os.MkdirAll(loadedCfg.DataDir, 0700)
@@ -818,15 +976,26 @@ func _main() error {
 		return err
 	}
 
+	// Open spools that hold measurements that have not been sunk.
+	err = p.loadCollections()
+	if err != nil {
+		return fmt.Errorf("spool: %v", err)
+	}
+	defer p.closeCollections()
+
+	// Load sinks that measurements are retained for.
+	err = p.loadSinks()
+	if err != nil {
+		return fmt.Errorf("sinks: %v", err)
+	}
+
 	// SSH key.
 	signer, err := util.SSHKey(loadedCfg.SSHKeyFile)
 	if err != nil {
 		return err
 	}
 
-	// Prepare sink
 	ctx, cancel := context.WithCancel(context.Background())
-	go p.sink(ctx)
 
 	// Listen for incoming SSH connections.
 	listenC := make(chan error)
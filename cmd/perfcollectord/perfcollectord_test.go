@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+func TestSinkTrim(t *testing.T) {
+	dir, err := os.MkdirTemp("", "perfcollectord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := &PerfCollector{
+		cfg: &config{
+			DataDir:    dir,
+			SinkExpiry: time.Hour,
+		},
+		collections: make(map[string]*collection),
+		sinks:       make(map[string]*sink),
+	}
+	c, err := p.openCollection(types.PCDefaultCollection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.closeCollections()
+	c.sc = &types.PCStartCollection{} // Prevent reaping
+	p.collections[c.name] = c
+	for i := 0; i < 10; i++ {
+		_, err := c.spool.Append(&types.PCCollection{
+			System:      "/proc/stat",
+			Measurement: strconv.Itoa(i),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Two sinks, neither has acknowledged anything.
+	now := time.Now()
+	a := &sink{name: "a", state: sinkState{
+		Acked:    map[string]uint64{},
+		LastSeen: now,
+	}}
+	b := &sink{name: "b", state: sinkState{
+		Acked:    map[string]uint64{},
+		LastSeen: now,
+	}}
+	p.sinks[a.name] = a
+	p.sinks[b.name] = b
+
+	// A single sink does not trim the spool.
+	if err := p.ackSink(a, c.name, 8); err != nil {
+		t.Fatal(err)
+	}
+	if c.spool.Len() != 10 {
+		t.Fatalf("expected Len=10, got %v", c.spool.Len())
+	}
+
+	// Trim up to what all sinks have stored.
+	if err := p.ackSink(b, c.name, 4); err != nil {
+		t.Fatal(err)
+	}
+	if c.spool.First() != 5 {
+		t.Fatalf("expected First=5, got %v", c.spool.First())
+	}
+
+	// Backlog is tracked per sink.
+	for _, v := range p.getSinks() {
+		expected := map[string]int{"a": 2, "b": 6}[v.Name]
+		if v.Backlog != expected {
+			t.Fatalf("sink %v: expected backlog %v, got %v",
+				v.Name, expected, v.Backlog)
+		}
+	}
+
+	// Expired sinks no longer hold back the spool.
+	b.state.LastSeen = now.Add(-2 * time.Hour)
+	if err := p.ackSink(a, c.name, 8); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.sinks["b"]; ok {
+		t.Fatalf("expected sink b to expire")
+	}
+	if c.spool.First() != 9 {
+		t.Fatalf("expected First=9, got %v", c.spool.First())
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	base := time.Date(2020, 12, 7, 9, 0, 0, 0, time.UTC)
+	frequency := 5 * time.Second
+
+	// Ticks are aligned to wall clock boundaries.
+	next := nextTick(base.Add(1234*time.Millisecond), frequency)
+	if !next.Equal(base.Add(5 * time.Second)) {
+		t.Fatalf("unexpected tick: %v", next)
+	}
+	next = nextTick(base, frequency)
+	if !next.Equal(base.Add(5 * time.Second)) {
+		t.Fatalf("unexpected tick on boundary: %v", next)
+	}
+
+	// Late but within the period.
+	tick, missed := schedule(next.Add(time.Second), next, frequency)
+	if !tick.Equal(next) || missed != 0 {
+		t.Fatalf("unexpected schedule: %v %v", tick, missed)
+	}
+
+	// More than two periods late skips to the latest tick.
+	tick, missed = schedule(next.Add(11*time.Second), next, frequency)
+	if !tick.Equal(next.Add(10*time.Second)) || missed != 2 {
+		t.Fatalf("unexpected schedule: %v %v", tick, missed)
+	}
+}
+
+func TestRestartCollection(t *testing.T) {
+	dir, err := os.MkdirTemp("", "perfcollectord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := &PerfCollector{
+		cfg:         &config{DataDir: dir},
+		collections: make(map[string]*collection),
+		sinks:       make(map[string]*sink),
+	}
+	defer p.closeCollections()
+	ctx := context.Background()
+
+	// ack fails the test unless reply is an acknowledgement.
+	ack := func(reply []byte, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd, err := types.Decode("", reply)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := cmd.(types.PCCommand); c.Cmd != types.PCAck {
+			t.Fatalf("unexpected reply: %+v", c)
+		}
+	}
+	start := func(run string) {
+		t.Helper()
+		ack(p.handleStartCollection(ctx, "test", types.PCCommand{
+			Cmd: types.PCStartCollectionCmd,
+			Payload: types.PCStartCollection{
+				Run:       run,
+				Frequency: time.Hour,
+			},
+		}, nil))
+	}
+	stop := func() {
+		t.Helper()
+		ack(p.handleStopCollection(ctx, types.PCCommand{
+			Cmd: types.PCStopCollectionCmd,
+		}, nil))
+	}
+
+	// Restart right away, the stop record of the first run must precede
+	// the start of the second one.
+	start("a")
+	stop()
+	start("b")
+	stop()
+
+	p.Lock()
+	c := p.collections[types.PCDefaultCollection]
+	done := c.done
+	p.Unlock()
+	if done != nil {
+		<-done
+	}
+
+	ms, _, err := c.spool.Read(c.spool.First(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		name string
+		stop bool
+	}{{"a", false}, {"a", true}, {"b", false}, {"b", true}}
+	if len(ms) != len(expected) {
+		t.Fatalf("expected %v records, got %v", len(expected), len(ms))
+	}
+	for k, v := range expected {
+		r, err := types.DecodeRun(ms[k])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Name != v.name || r.Stop.IsZero() == v.stop {
+			t.Fatalf("record %v: unexpected run %+v", k, r)
+		}
+	}
+}
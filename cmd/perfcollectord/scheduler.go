@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+	"github.com/businessperformancetuning/perfcollector/util"
+)
+
+// nextTick returns the first tick after t. Ticks are aligned to wall clock
+// boundaries of frequency so that samples of different collectors line up
+// and do not drift.
+func nextTick(t time.Time, frequency time.Duration) time.Time {
+	return t.Truncate(frequency).Add(frequency)
+}
+
+// schedule returns the tick that a wakeup at now, for the scheduled tick
+// next, is attributed to. When the wakeup is more than a full period late the
+// latest passed tick is used and the skipped ticks are returned as missed.
+func schedule(now, next time.Time, frequency time.Duration) (time.Time, int) {
+	late := now.Sub(next)
+	if late < frequency {
+		return next, 0
+	}
+	missed := int(late / frequency)
+	return next.Add(time.Duration(missed) * frequency), missed
+}
+
+// measureAll reads all systems concurrently so that the measurements of a
+// tick are taken as close to simultaneously as possible. The measurements are
+// returned in the order of the systems of sc. Systems that could not be
+// measured are logged and omitted.
+func measureAll(tick time.Time, sc *types.PCStartCollection) []*types.PCCollection {
+	ms := make([]*types.PCCollection, len(sc.Systems))
+	var wg sync.WaitGroup
+	for k, v := range sc.Systems {
+		wg.Add(1)
+		go func(k int, system string) {
+			defer wg.Done()
+
+			var (
+				blob []byte
+				err  error
+			)
+			start := time.Now()
+			switch system {
+			case types.PCPidstatSystem:
+				blob, err = util.MeasurePidstat(sc.Processes)
+			default:
+				blob, err = util.Measure(system)
+			}
+			if err != nil {
+				log.Errorf("measureAll: %v", err)
+				return
+			}
+			ms[k] = &types.PCCollection{
+				Collection:  sc.Name,
+				Timestamp:   tick,
+				Start:       start,
+				Duration:    time.Since(start),
+				Lateness:    start.Sub(tick),
+				Frequency:   sc.Frequency,
+				System:      system,
+				Measurement: string(blob),
+			}
+		}(k, v)
+	}
+	wg.Wait()
+
+	// Remove failed measurements.
+	r := ms[:0]
+	for _, m := range ms {
+		if m != nil {
+			r = append(r, m)
+		}
+	}
+	return r
+}
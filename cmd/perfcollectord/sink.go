@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// reSinkName matches valid sink names. Names are used as file names and
+// therefore must be restricted.
+var reSinkName = regexp.MustCompile("^[[:alnum:]_-][[:alnum:]_.-]*$")
+
+// validSinkName returns true if name can be used as a sink name.
+func validSinkName(name string) bool {
+	return len(name) <= 255 && reSinkName.MatchString(name)
+}
+
+// sinkState is the delivery state of a sink. It is stored on disk so that
+// measurements are retained for a sink that is temporarily disconnected.
+type sinkState struct {
+	Acked    map[string]uint64 // Last acknowledged sequence number per collection
+	LastSeen time.Time         // Last time the sink was connected
+}
+
+// sink is a consumer of measurements. Every sink has its own backlog and
+// is fed by its own writer so that a slow sink does not stall the others.
+//
+// All fields but name are protected by the PerfCollector mutex.
+type sink struct {
+	name  string
+	state sinkState
+
+	connected bool
+	drainC    chan struct{} // Signals that there are measurements to drain
+	stopC     chan struct{} // Closed when the sink unregisters
+}
+
+// sinkFilename returns the filename of the delivery state of a sink.
+func (p *PerfCollector) sinkFilename(name string) string {
+	return filepath.Join(p.cfg.DataDir, defaultSinkDirname, name+".json")
+}
+
+// saveSink atomically stores the delivery state of a sink. Must be called
+// with the lock held.
+func (p *PerfCollector) saveSink(sk *sink) error {
+	filename := p.sinkFilename(sk.name)
+	err := os.MkdirAll(filepath.Dir(filename), 0700)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(sk.state)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	err = os.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// loadSinks loads the delivery state of all known sinks.
+func (p *PerfCollector) loadSinks() error {
+	des, err := os.ReadDir(filepath.Join(p.cfg.DataDir,
+		defaultSinkDirname))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	for _, de := range des {
+		name := strings.TrimSuffix(de.Name(), ".json")
+		if de.IsDir() || name == de.Name() || !validSinkName(name) {
+			continue
+		}
+		b, err := os.ReadFile(p.sinkFilename(name))
+		if err != nil {
+			return err
+		}
+		sk := &sink{name: name}
+		err = json.Unmarshal(b, &sk.state)
+		if err != nil {
+			return fmt.Errorf("sink %v: %v", name, err)
+		}
+		if sk.state.Acked == nil {
+			sk.state.Acked = make(map[string]uint64)
+		}
+		log.Infof("Sink         : %v last seen %v", name,
+			sk.state.LastSeen.Format(time.RFC3339))
+		p.sinks[name] = sk
+	}
+	p.expireSinks()
+
+	return nil
+}
+
+// expireSinks forgets disconnected sinks that have not been seen for longer
+// than the configured expiry. Must be called with the lock held.
+func (p *PerfCollector) expireSinks() {
+	if p.cfg.SinkExpiry == 0 {
+		return
+	}
+	for k, sk := range p.sinks {
+		if sk.connected ||
+			time.Since(sk.state.LastSeen) < p.cfg.SinkExpiry {
+			continue
+		}
+		log.Infof("Forgetting sink %v, last seen %v", k,
+			sk.state.LastSeen.Format(time.RFC3339))
+		err := os.Remove(p.sinkFilename(k))
+		if err != nil && !os.IsNotExist(err) {
+			log.Errorf("expireSinks %v: %v", k, err)
+		}
+		delete(p.sinks, k)
+	}
+}
+
+// trimCollections discards the spooled measurements that have been
+// acknowledged by all known sinks. Must be called with the lock held.
+func (p *PerfCollector) trimCollections() error {
+	p.expireSinks()
+	if len(p.sinks) == 0 {
+		return nil
+	}
+	for _, c := range p.collections {
+		var acked uint64
+		first := true
+		for _, sk := range p.sinks {
+			if a := sk.state.Acked[c.name]; first || a < acked {
+				acked = a
+				first = false
+			}
+		}
+		if acked == 0 {
+			continue
+		}
+		err := c.spool.Trim(acked + 1)
+		if err != nil {
+			return fmt.Errorf("trim %v: %v", c.name, err)
+		}
+	}
+	p.reapCollections()
+
+	return nil
+}
+
+// registerSink marks the named sink connected and starts feeding it
+// measurements. The resume sequence numbers record what the sink has
+// durably stored.
+func (p *PerfCollector) registerSink(ctx context.Context, name string, encoder *gob.Encoder, resume map[string]uint64) (*sink, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	sk, ok := p.sinks[name]
+	if ok && sk.connected {
+		return nil, fmt.Errorf("sink %v already registered", name)
+	}
+	if !ok {
+		sk = &sink{
+			name: name,
+			state: sinkState{
+				Acked: make(map[string]uint64),
+			},
+		}
+		p.sinks[name] = sk
+	}
+	for k, v := range resume {
+		if v > 0 && v-1 > sk.state.Acked[k] {
+			sk.state.Acked[k] = v - 1
+		}
+	}
+	sk.state.LastSeen = time.Now()
+	sk.connected = true
+	sk.drainC = make(chan struct{}, 1)
+	sk.stopC = make(chan struct{})
+	err := p.saveSink(sk)
+	if err != nil {
+		return nil, err
+	}
+	err = p.trimCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	go p.sinkWriter(ctx, sk, encoder, resume, sk.drainC, sk.stopC)
+
+	return sk, nil
+}
+
+// unregisterSink marks the sink disconnected. Its delivery state is retained
+// so that delivery resumes when the sink reconnects.
+func (p *PerfCollector) unregisterSink(sk *sink) {
+	p.Lock()
+	defer p.Unlock()
+
+	close(sk.stopC)
+	sk.connected = false
+	sk.drainC = nil
+	sk.stopC = nil
+	sk.state.LastSeen = time.Now()
+	if err := p.saveSink(sk); err != nil {
+		log.Errorf("unregisterSink %v: %v", sk.name, err)
+	}
+}
+
+// ackSink records that the sink has durably stored all measurements of a
+// collection up to and including sequence.
+func (p *PerfCollector) ackSink(sk *sink, collection string, sequence uint64) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if sequence > sk.state.Acked[collection] {
+		sk.state.Acked[collection] = sequence
+	}
+	sk.state.LastSeen = time.Now()
+	err := p.saveSink(sk)
+	if err != nil {
+		return err
+	}
+	return p.trimCollections()
+}
+
+// notifySinks signals all connected sinks that there are measurements to
+// drain. Sinks that are still busy pick up the new measurements when they
+// are done.
+func (p *PerfCollector) notifySinks() {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, sk := range p.sinks {
+		if !sk.connected {
+			continue
+		}
+		select {
+		case sk.drainC <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// getSinks returns the status of all known sinks sorted by name.
+func (p *PerfCollector) getSinks() []types.PCSinkStatus {
+	p.Lock()
+	defer p.Unlock()
+
+	ss := make([]types.PCSinkStatus, 0, len(p.sinks))
+	for _, sk := range p.sinks {
+		s := types.PCSinkStatus{
+			Name:      sk.name,
+			Connected: sk.connected,
+			LastSeen:  sk.state.LastSeen,
+		}
+		for _, c := range p.collections {
+			first := c.spool.First()
+			if a := sk.state.Acked[c.name]; a >= first {
+				first = a + 1
+			}
+			if next := c.spool.Next(); next > first {
+				s.Backlog += int(next - first)
+			}
+		}
+		ss = append(ss, s)
+	}
+	sort.Slice(ss, func(i, j int) bool {
+		return ss[i].Name < ss[j].Name
+	})
+	return ss
+}
+
+// sinkWriter sends the spooled measurements of all collections to a sink
+// until the sink unregisters or the connection fails.
+func (p *PerfCollector) sinkWriter(ctx context.Context, sk *sink, encoder *gob.Encoder, resume map[string]uint64, drainC, stopC chan struct{}) {
+	log.Tracef("sinkWriter %v", sk.name)
+	defer log.Tracef("sinkWriter %v exit", sk.name)
+
+	cursors := make(map[string]uint64) // Next sequence number to send
+	for {
+		// Replay what was not stored by the sink and send new
+		// measurements.
+		err := p.drainAll(encoder, cursors, resume)
+		if err != nil {
+			// The connection is gone, the sink is unregistered
+			// once the channel closes.
+			log.Errorf("sink %v drain: %v", sk.name, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-stopC:
+			return
+		case <-drainC:
+		}
+	}
+}
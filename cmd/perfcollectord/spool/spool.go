@@ -0,0 +1,591 @@
+// Package spool implements a persistent, size capped, append-only queue of
+// measurements. The collector appends every measurement to the spool and the
+// sink drains it in order. Measurements are assigned a sequence number when
+// they are appended and remain in the spool until the sink acknowledges that
+// they were durably stored. This allows the collector to ride out sink outages
+// without losing data.
+//
+// The spool lives in a directory and consists of segment files that are named
+// after the sequence number of their first record. Each record is a gob
+// encoded types.PCCollection prefixed with its length and a CRC32 checksum. A
+// small head file records the first sequence number that is still live so
+// that consumed records are not replayed after a restart.
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+const (
+	// segmentExtension is the file extension of segment files.
+	segmentExtension = ".spool"
+
+	// headFilename is the name of the file that records the first live
+	// sequence number.
+	headFilename = "head"
+
+	// maxSegmentSize is the size at which a new segment is started.
+	maxSegmentSize = 4 * 1024 * 1024
+
+	// minSegmentSize is the smallest segment size that is used when the
+	// spool is capped to a small size.
+	minSegmentSize = 64 * 1024
+
+	// recordHeaderSize is the size of the length and checksum that
+	// precede every record.
+	recordHeaderSize = 8
+
+	// maxRecordSize is a sanity limit on the size of a single record.
+	maxRecordSize = 64 * 1024 * 1024
+)
+
+// ErrRecordTooLarge is returned when a record exceeds the maximum size.
+var ErrRecordTooLarge = errors.New("record too large")
+
+// segment describes a single segment file.
+type segment struct {
+	first   uint64  // Sequence number of the first record
+	count   uint64  // Number of records in segment
+	size    int64   // Size of the file in bytes
+	offsets []int64 // File offset of every record
+}
+
+// next returns the sequence number that follows the last record in the
+// segment.
+func (s *segment) next() uint64 {
+	return s.first + s.count
+}
+
+// Spool is a persistent queue of measurements. It is safe for concurrent use.
+type Spool struct {
+	sync.Mutex
+
+	dir        string
+	maxSize    int64 // Maximum on-disk size, 0 is unlimited
+	maxEntries int   // Maximum live records, 0 is unlimited
+	segSize    int64 // Size at which segments are rotated
+
+	segments []*segment // Segments in sequence order
+	first    uint64     // First live sequence number
+	next     uint64     // Sequence number of the next record
+	size     int64      // Total on-disk size of all segments
+	dropped  uint64     // Records discarded to honour the limits
+
+	f *os.File // Last segment, opened for append
+}
+
+// segmentFilename returns the filename of the segment that starts at first.
+func (s *Spool) segmentFilename(first uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%016x%v", first,
+		segmentExtension))
+}
+
+// Open opens or creates the spool in dir. The spool will not grow beyond
+// maxSize bytes, a maxSize of 0 means unlimited. A trailing partial record in
+// the last segment, left by a crash, is truncated. Damage anywhere else can
+// not be explained by a crash and fails Open.
+func Open(dir string, maxSize int64) (*Spool, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		dir:     dir,
+		maxSize: maxSize,
+		segSize: maxSegmentSize,
+	}
+	if maxSize > 0 {
+		// Keep enough segments around so that the cap can be
+		// honoured by deleting whole segments.
+		s.segSize = maxSize / 8
+		if s.segSize > maxSegmentSize {
+			s.segSize = maxSegmentSize
+		}
+		if s.segSize < minSegmentSize {
+			s.segSize = minSegmentSize
+		}
+	}
+
+	// Find all segments.
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, de := range des {
+		name := de.Name()
+		if de.IsDir() || !strings.HasSuffix(name, segmentExtension) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name,
+			segmentExtension), 16, 64)
+		if err != nil {
+			// Not ours.
+			continue
+		}
+		s.segments = append(s.segments, &segment{first: first})
+	}
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].first < s.segments[j].first
+	})
+
+	// Scan all segments and truncate the damaged tail, if any.
+	for k, seg := range s.segments {
+		err = s.scan(seg, k == len(s.segments)-1)
+		if err != nil {
+			return nil, err
+		}
+		s.size += seg.size
+	}
+
+	// Recover head and next sequence number.
+	head, err := s.readHead()
+	if err != nil {
+		return nil, err
+	}
+	if head == 0 {
+		// Sequence numbers start at 1.
+		head = 1
+	}
+	s.first = head
+	s.next = head
+	if len(s.segments) > 0 {
+		if s.segments[0].first > s.first {
+			s.first = s.segments[0].first
+		}
+		if last := s.segments[len(s.segments)-1]; last.next() > s.next {
+			s.next = last.next()
+		}
+	}
+	if s.first > s.next {
+		s.first = s.next
+	}
+	err = s.reclaim()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// scan counts the valid records in seg. Only the last segment is appended to,
+// so if last is set the file is truncated after the last valid record.
+// Otherwise a damaged record is an error.
+func (s *Spool) scan(seg *segment, last bool) error {
+	filename := s.segmentFilename(seg.first)
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		_, n, err := readRecord(r)
+		if err != nil {
+			if err != io.EOF {
+				if !last {
+					return fmt.Errorf("segment %x "+
+						"damaged at offset %v: %v",
+						seg.first, offset, err)
+				}
+
+				// Damaged tail, drop it.
+				f.Close()
+				err = os.Truncate(filename, offset)
+				if err != nil {
+					return err
+				}
+			}
+			break
+		}
+		seg.offsets = append(seg.offsets, offset)
+		offset += n
+		seg.count++
+	}
+	seg.size = offset
+
+	return nil
+}
+
+// readHead returns the sequence number stored in the head file.
+func (s *Spool) readHead() (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, headFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if len(b) != 8 {
+		return 0, fmt.Errorf("invalid head file length: %v", len(b))
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// writeHead atomically and durably records the first live sequence number.
+// The new head is synced before it replaces the old one, and the directory is
+// synced so that the rename itself survives a crash. Must be called with the
+// lock held.
+func (s *Spool) writeHead() error {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], s.first)
+	filename := filepath.Join(s.dir, headFilename)
+	tmp := filename + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b[:])
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	err = s.syncDir()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, filename)
+	if err != nil {
+		return err
+	}
+	return s.syncDir()
+}
+
+// syncDir commits the directory entries of the spool to stable storage.
+func (s *Spool) syncDir() error {
+	d, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// readRecord reads a single record from r. It returns the encoded record and
+// the number of bytes consumed.
+func readRecord(r io.Reader) ([]byte, int64, error) {
+	var hdr [recordHeaderSize]byte
+	_, err := io.ReadFull(r, hdr[:])
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, 0, fmt.Errorf("short record header")
+		}
+		return nil, 0, err
+	}
+	l := binary.LittleEndian.Uint32(hdr[0:4])
+	if l > maxRecordSize {
+		return nil, 0, ErrRecordTooLarge
+	}
+	blob := make([]byte, l)
+	_, err = io.ReadFull(r, blob)
+	if err != nil {
+		return nil, 0, fmt.Errorf("short record: %v", err)
+	}
+	if crc32.ChecksumIEEE(blob) != binary.LittleEndian.Uint32(hdr[4:8]) {
+		return nil, 0, fmt.Errorf("record checksum mismatch")
+	}
+	return blob, int64(recordHeaderSize + l), nil
+}
+
+// reclaim removes segments that no longer contain live records and records
+// the new head. Must be called with the lock held.
+func (s *Spool) reclaim() error {
+	for len(s.segments) > 0 {
+		seg := s.segments[0]
+		if seg.next() > s.first {
+			break
+		}
+		if len(s.segments) == 1 && s.f != nil {
+			// Never remove the segment that is being appended to.
+			break
+		}
+		err := os.Remove(s.segmentFilename(seg.first))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.size -= seg.size
+		s.segments = s.segments[1:]
+	}
+	return s.writeHead()
+}
+
+// enforce discards the oldest records until the spool is within its limits.
+// It returns the number of records that were discarded. Must be called with
+// the lock held.
+func (s *Spool) enforce() (int, error) {
+	first := s.first
+	if s.maxEntries > 0 && s.next-s.first > uint64(s.maxEntries) {
+		s.first = s.next - uint64(s.maxEntries)
+	}
+	for s.maxSize > 0 && s.size > s.maxSize && len(s.segments) > 1 {
+		seg := s.segments[0]
+		if seg.next() > s.first {
+			s.first = seg.next()
+		}
+		err := s.reclaim()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if s.first == first {
+		return 0, nil
+	}
+	dropped := int(s.first - first)
+	s.dropped += uint64(dropped)
+	return dropped, s.reclaim()
+}
+
+// SetMaxEntries limits the number of live records in the spool. A value of 0
+// means unlimited. The oldest records are discarded to honour the limit.
+func (s *Spool) SetMaxEntries(n int) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.maxEntries = n
+	return s.enforce()
+}
+
+// Append appends a measurement to the spool and assigns its sequence number.
+// It returns the number of records that were discarded in order to honour the
+// limits.
+func (s *Spool) Append(m *types.PCCollection) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	m.Sequence = s.next
+
+	var buf bytes.Buffer
+	buf.Write(make([]byte, recordHeaderSize))
+	err := gob.NewEncoder(&buf).Encode(m)
+	if err != nil {
+		return 0, err
+	}
+	blob := buf.Bytes()
+	l := len(blob) - recordHeaderSize
+	if l > maxRecordSize {
+		return 0, ErrRecordTooLarge
+	}
+	binary.LittleEndian.PutUint32(blob[0:4], uint32(l))
+	binary.LittleEndian.PutUint32(blob[4:8],
+		crc32.ChecksumIEEE(blob[recordHeaderSize:]))
+
+	// Rotate segment if needed.
+	var seg *segment
+	if len(s.segments) > 0 {
+		seg = s.segments[len(s.segments)-1]
+	}
+	if seg == nil || seg.next() != s.next ||
+		seg.size+int64(len(blob)) > s.segSize {
+		if s.f != nil {
+			err = s.f.Close()
+			s.f = nil
+			if err != nil {
+				return 0, err
+			}
+		}
+		if seg == nil || seg.count > 0 || seg.first != s.next {
+			seg = &segment{first: s.next}
+			s.segments = append(s.segments, seg)
+		}
+	}
+	if s.f == nil {
+		s.f, err = os.OpenFile(s.segmentFilename(seg.first),
+			os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = s.f.Write(blob)
+	if err != nil {
+		return 0, err
+	}
+	seg.offsets = append(seg.offsets, seg.size)
+	seg.count++
+	seg.size += int64(len(blob))
+	s.size += int64(len(blob))
+	s.next++
+
+	return s.enforce()
+}
+
+// Sync commits the spool to stable storage.
+func (s *Spool) Sync() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+	return s.f.Sync()
+}
+
+// Read returns up to max live records starting at sequence number seq. If seq
+// precedes the first live record reading starts at the first live record. The
+// sequence number of the record that follows the returned records is returned
+// as well. Reading seeks straight to seq, the records that precede it are not
+// read again.
+func (s *Spool) Read(seq uint64, max int) ([]*types.PCCollection, uint64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if seq < s.first {
+		seq = s.first
+	}
+
+	var ms []*types.PCCollection
+	for _, seg := range s.segments {
+		if len(ms) >= max {
+			break
+		}
+		if seg.next() <= seq {
+			continue
+		}
+		i := seg.first
+		if seq > i {
+			i = seq
+		}
+		f, err := os.Open(s.segmentFilename(seg.first))
+		if err != nil {
+			return nil, 0, err
+		}
+		_, err = f.Seek(seg.offsets[i-seg.first], io.SeekStart)
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		r := bufio.NewReader(f)
+		for ; i < seg.next() && len(ms) < max; i++ {
+			blob, _, err := readRecord(r)
+			if err != nil {
+				f.Close()
+				return nil, 0, fmt.Errorf("segment %x record "+
+					"%v: %v", seg.first, i, err)
+			}
+			var m types.PCCollection
+			err = gob.NewDecoder(bytes.NewReader(blob)).Decode(&m)
+			if err != nil {
+				f.Close()
+				return nil, 0, fmt.Errorf("segment %x record "+
+					"%v: %v", seg.first, i, err)
+			}
+			ms = append(ms, &m)
+			seq = i + 1
+		}
+		f.Close()
+	}
+
+	return ms, seq, nil
+}
+
+// Trim discards all records that precede sequence number seq.
+func (s *Spool) Trim(seq uint64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if seq > s.next {
+		seq = s.next
+	}
+	if seq <= s.first {
+		return nil
+	}
+	s.first = seq
+	return s.reclaim()
+}
+
+// Advance discards all records and continues numbering at sequence number seq
+// if seq lies beyond the last assigned sequence number. This is used when a
+// sink has stored more than the spool knows about, for example after the spool
+// was removed. It returns true if the spool was advanced.
+func (s *Spool) Advance(seq uint64) (bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if seq <= s.next {
+		return false, nil
+	}
+	if s.f != nil {
+		err := s.f.Close()
+		s.f = nil
+		if err != nil {
+			return false, err
+		}
+	}
+	s.first = seq
+	s.next = seq
+	return true, s.reclaim()
+}
+
+// First returns the sequence number of the first live record.
+func (s *Spool) First() uint64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.first
+}
+
+// Next returns the sequence number that will be assigned to the next record.
+func (s *Spool) Next() uint64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.next
+}
+
+// Len returns the number of live records.
+func (s *Spool) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return int(s.next - s.first)
+}
+
+// Size returns the on-disk size of the spool in bytes.
+func (s *Spool) Size() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.size
+}
+
+// Dropped returns the number of records that were discarded to honour the
+// limits since the spool was opened.
+func (s *Spool) Dropped() uint64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.dropped
+}
+
+// Close closes the spool.
+func (s *Spool) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
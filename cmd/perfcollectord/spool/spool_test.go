@@ -0,0 +1,324 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+func measurement(i int) *types.PCCollection {
+	return &types.PCCollection{
+		Timestamp:   time.Unix(int64(i), 0),
+		Start:       time.Unix(int64(i), 0),
+		Duration:    500 * time.Microsecond,
+		Frequency:   5 * time.Second,
+		System:      "/proc/stat",
+		Measurement: strconv.Itoa(i),
+	}
+}
+
+func TestSpoolOrder(t *testing.T) {
+	dir, err := os.MkdirTemp("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if _, err := s.Append(measurement(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Len() != 100 {
+		t.Fatalf("expected Len=100, got %v", s.Len())
+	}
+
+	// Read in batches and verify order.
+	seq := s.First()
+	i := 0
+	for {
+		ms, next, err := s.Read(seq, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ms) == 0 {
+			break
+		}
+		for _, m := range ms {
+			if m.Measurement != strconv.Itoa(i) {
+				t.Fatalf("expected %v, got %v", i,
+					m.Measurement)
+			}
+			if m.Sequence != uint64(i+1) {
+				t.Fatalf("expected sequence %v, got %v", i+1,
+					m.Sequence)
+			}
+			i++
+		}
+		seq = next
+	}
+	if i != 100 {
+		t.Fatalf("expected 100 records, got %v", i)
+	}
+}
+
+func TestSpoolReopen(t *testing.T) {
+	dir, err := os.MkdirTemp("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := s.Append(measurement(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Trim(s.First() + 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a torn write at the end of the segment.
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+segmentExtension))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected 1 segment, got %v: %v", len(matches), err)
+	}
+	f, err := os.OpenFile(matches[0], os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0xff, 0x00, 0x00})
+	f.Close()
+
+	s, err = Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 6 {
+		t.Fatalf("expected Len=6, got %v", s.Len())
+	}
+	ms, _, err := s.Read(0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 6 || ms[0].Measurement != "4" {
+		t.Fatalf("unexpected records after reopen: %v", len(ms))
+	}
+
+	// Sequence numbers continue after reopen.
+	next := s.Next()
+	if _, err := s.Append(measurement(10)); err != nil {
+		t.Fatal(err)
+	}
+	ms, _, err = s.Read(next, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].Measurement != "10" {
+		t.Fatalf("unexpected record after append")
+	}
+}
+
+func TestSpoolLimits(t *testing.T) {
+	dir, err := os.MkdirTemp("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dropped, err := s.SetMaxEntries(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != 0 {
+		t.Fatalf("expected dropped=0, got %v", dropped)
+	}
+	for i := 0; i < 25; i++ {
+		if _, err := s.Append(measurement(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Len() != 10 {
+		t.Fatalf("expected Len=10, got %v", s.Len())
+	}
+	if s.Dropped() != 15 {
+		t.Fatalf("expected Dropped=15, got %v", s.Dropped())
+	}
+	ms, _, err := s.Read(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms[0].Measurement != "15" {
+		t.Fatalf("expected oldest record 15, got %v", ms[0].Measurement)
+	}
+
+	// Size cap discards whole segments.
+	dir2, err := os.MkdirTemp("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir2)
+	maxSize := int64(4 * minSegmentSize)
+	s2, err := Open(dir2, maxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	for i := 0; i < 5000; i++ {
+		m := measurement(i)
+		m.Measurement = string(make([]byte, 512))
+		if _, err := s2.Append(m); err != nil {
+			t.Fatal(err)
+		}
+		if s2.Size() > maxSize {
+			t.Fatalf("spool size %v exceeds %v", s2.Size(),
+				maxSize)
+		}
+	}
+	if s2.Dropped() == 0 {
+		t.Fatalf("expected dropped records")
+	}
+}
+
+func TestSpoolAdvance(t *testing.T) {
+	dir, err := os.MkdirTemp("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := s.Append(measurement(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Advancing backwards is a no-op.
+	advanced, err := s.Advance(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if advanced || s.Len() != 5 {
+		t.Fatalf("unexpected advance: %v %v", advanced, s.Len())
+	}
+
+	advanced, err = s.Advance(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !advanced || s.Len() != 0 {
+		t.Fatalf("expected advance: %v %v", advanced, s.Len())
+	}
+	m := measurement(5)
+	if _, err := s.Append(m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Sequence != 100 {
+		t.Fatalf("expected sequence 100, got %v", m.Sequence)
+	}
+	s.Close()
+
+	// Numbering survives a restart.
+	s, err = Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.First() != 100 || s.Next() != 101 {
+		t.Fatalf("unexpected range after reopen: %v-%v", s.First(),
+			s.Next())
+	}
+}
+
+func TestSpoolDamagedSegment(t *testing.T) {
+	dir, err := os.MkdirTemp("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := Open(dir, 8*minSegmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 500; i++ {
+		m := measurement(i)
+		m.Measurement = string(make([]byte, 512))
+		if _, err := s.Append(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+segmentExtension))
+	if err != nil || len(matches) < 2 {
+		t.Fatalf("expected segments, got %v: %v", len(matches), err)
+	}
+
+	// Damage in the last segment is a torn write and is repaired.
+	last := matches[len(matches)-1]
+	f, err := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{0xff, 0x00, 0x00})
+	f.Close()
+	s, err = Open(dir, 8*minSegmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 500 {
+		t.Fatalf("expected Len=500, got %v", s.Len())
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Damage in an earlier segment is not.
+	fi, err := os.Stat(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(matches[0], fi.Size()-1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir, 8*minSegmentSize); err == nil {
+		t.Fatal("expected error")
+	}
+	fi2, err := os.Stat(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi2.Size() != fi.Size()-1 {
+		t.Fatalf("damaged segment was modified")
+	}
+}
@@ -15,7 +15,10 @@ import (
 	"time"
 
 	"github.com/businessperformancetuning/perfcollector/cmd/perfprocessord/journal"
+	"github.com/businessperformancetuning/perfcollector/database"
 	"github.com/businessperformancetuning/perfcollector/parser"
+	"github.com/businessperformancetuning/perfcollector/types"
+	"github.com/businessperformancetuning/perfcollector/util"
 	"github.com/jrick/flagfile"
 )
 
@@ -39,6 +42,7 @@ type config struct {
 	License     string
 	InputFile   string
 	Output      string
+	Run         string
 }
 
 func usage() {
@@ -66,6 +70,8 @@ Flags:
   --output string
 	Output file or directory depending on mode, - outputs to stdout in JSON mode
 	e.g. ~/datadump.csv or ~/journal.json
+  --run string
+	Only process the runs with this name, e.g. default-20240102T150405Z
 `)
 	os.Exit(2)
 }
@@ -84,6 +90,7 @@ func (c *config) FlagSet() *flag.FlagSet {
 	fs.StringVar(&c.License, "license", "", "")
 	fs.StringVar(&c.InputFile, "input", "", "")
 	fs.StringVar(&c.Output, "output", "", "")
+	fs.StringVar(&c.Run, "run", "", "")
 	fs.Usage = usage
 	return fs
 }
@@ -199,33 +206,87 @@ var (
 	fileCache = make(map[string]*os.File)
 )
 
+// resetPrevious forgets the previous measurements of every system of the
+// collection of cur and makes cur, a /proc/stat measurement, the new baseline.
+// Only /proc/stat carries the boot time but after a reboot the counters of
+// all systems start over.
+func resetPrevious(name string, cur *journal.WrapPCCollection) {
+	prefix := strings.TrimSuffix(name, cur.Measurement.System)
+	for k := range previousCache {
+		// Systems start with a slash, this skips collections that
+		// share a prefix.
+		if strings.HasPrefix(k, prefix+"/") {
+			delete(previousCache, k)
+		}
+	}
+	previousCache[name] = cur
+}
+
 func constructHeader(wc *journal.WrapPCCollection) (string, error) {
 	var mHdr string
 	switch wc.Measurement.System {
 	case "/proc/stat":
-		mHdr = "CPU,%usr,%nice,%system,%iowait,%steal,%idle"
+		mHdr = "CPU,%usr,%nice,%system,%iowait,%steal,%irq,%soft," +
+			"%guest,%gnice,%idle"
 	case "/proc/meminfo":
 		mHdr = "kbmemfree,kbavail,kbmemused,%memused,kbbuffers," +
 			"kbcached,kbcommit,%commit,kbactive,kbinact," +
-			"kbdirty"
+			"kbdirty,kbswpfree,kbswpused,%swpused,kbswpcad," +
+			"%swpcad,kbhugfree,kbhugused,%hugused"
+	case "/proc/vmstat":
+		mHdr = "pgpgin/s,pgpgout/s,fault/s,majflt/s,pgfree/s," +
+			"pgscank/s,pgscand/s,pgsteal/s,%vmeff,pswpin/s,pswpout/s"
 	case "/proc/net/dev":
 		mHdr = "IFACE,rxpck/s,txpck/s,rxkB/s,txkB/s,rxcmp/s," +
-			"txcmp/s,rxmcst/s,%ifutil"
+			"txcmp/s,rxmcst/s,%ifutil,rxerr/s,txerr/s,coll/s," +
+			"rxdrop/s,txdrop/s,txcarr/s,rxfram/s,rxfifo/s,txfifo/s"
+	case "/proc/net/snmp":
+		mHdr = "irec/s,fwddgm/s,idel/s,orq/s,asmrq/s,asmok/s," +
+			"fragok/s,fragcrt/s,active/s,passive/s,iseg/s,oseg/s," +
+			"atmptf/s,estres/s,retrans/s,isegerr/s,orsts/s," +
+			"idgm/s,odgm/s,noport/s,idgmerr/s"
+	case "/proc/net/netstat":
+		mHdr = "lstovf/s,lstdrp/s,tmout/s,synretr/s,fretr/s," +
+			"lostretr/s,abortto/s"
 	case "/proc/diskstats":
-		mHdr = "DEV,tps,rtps,wtps,dtps,bread/s,bwrtn/s,bdscd/s"
+		mHdr = "DEV,tps,rtps,wtps,dtps,bread/s,bwrtn/s,bdscd/s," +
+			"rrqm/s,wrqm/s,r_await,w_await,aqu-sz,areq-sz,%util"
+	case types.PCPidstatSystem:
+		mHdr = "UID,PID,%usr,%system,%CPU,minflt/s,majflt/s,VSZ," +
+			"RSS,kB_rd/s,kB_wr/s,kB_ccwr/s,cswch/s,nvcswch/s," +
+			"threads,Command"
+	case "/proc/pressure/cpu", "/proc/pressure/memory",
+		"/proc/pressure/io":
+		mHdr = "RESOURCE,%some10,%some60,%some300,%some,%full10," +
+			"%full60,%full300,%full"
 	case "/proc/loadavg":
-		// XXX do notthing
+		mHdr = "runq-sz,plist-sz,ldavg-1,ldavg-5,ldavg-15,blocked"
+	case "/proc/interrupts", "/proc/softirqs":
+		mHdr = "CPU,SOURCE,DESCRIPTION,intr/s"
+	case types.PCCPUFreqSystem:
+		mHdr = "CPU,MHz"
+	case types.PCNodeSystem:
+		mHdr = "NODE,kbmemtotal,kbmemfree,kbmemused,%memused,kbactive," +
+			"kbinact,kbfile,kbanon,kbslab,kbdirty"
+	case types.PCStatfsSystem:
+		mHdr = "FILESYSTEM,MOUNTPOINT,TYPE,MBfssize,MBfsfree,MBfsused," +
+			"%fsused,%ufsused,Inodes,Ifree,Iused,%Iused"
 	default:
-		return "", fmt.Errorf("unsupported system: %v",
-			wc.Measurement.System)
+		if !util.CgroupSystem(wc.Measurement.System) {
+			return "", fmt.Errorf("unsupported system: %v",
+				wc.Measurement.System)
+		}
+		mHdr = "CGROUP,%usr,%system,%CPU,%throttled,throttled/s," +
+			"kbmem,kbanon,kbfile,fault/s,majflt/s,rkB/s,wkB/s," +
+			"r/s,w/s,%cpusome,%cpufull"
 	}
 	return "#site,host,timestamp," + mHdr, nil
 }
 
 var (
-	reDuplex = regexp.MustCompile("/sys/class/net/[[:alnum:]]*/duplex")
-	reSpeed  = regexp.MustCompile("/sys/class/net/[[:alnum:]]*/speed")
-	reNIC    = regexp.MustCompile("(/[[:alnum:]]+){4}")
+	reDuplex = regexp.MustCompile("/sys/class/net/[^/]+/duplex")
+	reSpeed  = regexp.MustCompile("/sys/class/net/[^/]+/speed")
+	reNIC    = regexp.MustCompile("(/[^/]+){4}")
 )
 
 func createNetCache(cache map[string]string) (map[string]parser.NIC, error) {
@@ -269,7 +330,7 @@ func createNetCache(cache map[string]string) (map[string]parser.NIC, error) {
 			continue
 		}
 
-		key := fmt.Sprintf("%v %v %v %v", site, host, run, nic)
+		key := parser.NICKey(site, host, run, nic)
 		cc, ok := r[key]
 		if !ok {
 			cc = parser.NIC{}
@@ -298,18 +359,151 @@ func createNetCache(cache map[string]string) (map[string]parser.NIC, error) {
 	return r, nil
 }
 
+// unixTimestamp returns t as UNIX seconds. Fractional seconds are only
+// printed for sub-second collections.
+func unixTimestamp(t time.Time) string {
+	s := strconv.FormatInt(t.Unix(), 10)
+	if ns := t.Nanosecond(); ns != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%09d", ns), "0")
+	}
+	return s
+}
+
+// writeEvent appends an event of cur, such as a counter reset, to the events
+// file in output. Events mark boundaries in the CSV files.
+func writeEvent(output string, cur *journal.WrapPCCollection, e database.Event) error {
+	filename := filepath.Join(output, "events")
+	f, ok := fileCache[filename]
+	if !ok {
+		err := os.MkdirAll(output, 0754)
+		if err != nil {
+			return err
+		}
+		f, err = os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|
+			os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		fileCache[filename] = f
+
+		_, err = fmt.Fprintf(f, "#site,host,timestamp,SYSTEM,TYPE,NAME,"+
+			"DESCRIPTION\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v\n", cur.Site, cur.Host,
+		unixTimestamp(cur.Measurement.Timestamp), csvString(e.System),
+		e.Type, csvString(e.Name), csvString(e.Description))
+	return err
+}
+
+// writeRun appends run r of cur to the runs file in output. A run is written
+// when it starts and again when it stops; the last line of a run is the most
+// recent.
+func writeRun(output string, cur *journal.WrapPCCollection, r *types.PCRun) error {
+	filename := filepath.Join(output, "runs")
+	f, ok := fileCache[filename]
+	if !ok {
+		err := os.MkdirAll(output, 0754)
+		if err != nil {
+			return err
+		}
+		f, err = os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|
+			os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		fileCache[filename] = f
+
+		_, err = fmt.Fprintf(f, "#site,host,run,NAME,COLLECTION,start,"+
+			"stop,LABEL,DESCRIPTION\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	var stop string
+	if !r.Stop.IsZero() {
+		stop = unixTimestamp(r.Stop)
+	}
+	_, err := fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v\n", cur.Site,
+		cur.Host, cur.Run, csvString(r.Name),
+		csvString(cur.Measurement.Collection), unixTimestamp(r.Start),
+		stop, csvString(r.StartCollection.Label),
+		csvString(r.StartCollection.Description))
+	return err
+}
+
+// runKey returns the key of the run of wc in the map of run names.
+func runKey(wc *journal.WrapPCCollection) string {
+	return fmt.Sprintf("%v %v %v", wc.Site, wc.Host, wc.Run)
+}
+
+// selected returns true if wc must be processed. Runs are journaled ahead of
+// their measurements and their names are recorded in runs so that the
+// measurements can be selected by run name.
+func selected(cfg *config, runs map[string]string, wc *journal.WrapPCCollection) (bool, error) {
+	if wc.Measurement.System == types.PCRunSystem {
+		r, err := types.DecodeRun(wc.Measurement)
+		if err != nil {
+			return false, err
+		}
+		runs[runKey(wc)] = r.Name
+	}
+	return cfg.Run == "" || runs[runKey(wc)] == cfg.Run, nil
+}
+
+// csvString quotes s if it contains characters that have a meaning in CSV.
+func csvString(s string) string {
+	if !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
 func csv(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.NIC) error {
 	if cur.Site != cfg.SiteID {
 		// File should not have decrypted
 		return fmt.Errorf("unexpected site: %v", cur.Site)
 	}
 
+	if cur.Measurement.System == types.PCRunSystem {
+		r, err := types.DecodeRun(cur.Measurement)
+		if err != nil {
+			return err
+		}
+		return writeRun(cfg.Output, cur, r)
+	}
+
 	// XXX work around trailing /
 	// XXX FIXME
 	if cur.Measurement.System == "/proc/net/dev/" {
 		cur.Measurement.System = "/proc/net/dev"
 	}
-	if cur.Measurement.System == "/proc/loadavg" {
+
+	// perfprocessord journals the speed and duplex of an interface when
+	// it is first seen or its link changed.
+	if reDuplex.MatchString(cur.Measurement.System) ||
+		reSpeed.MatchString(cur.Measurement.System) {
+		key := fmt.Sprintf("%v %v %v %v", cur.Site, cur.Host, cur.Run,
+			cur.Measurement.System)
+		nc, err := createNetCache(map[string]string{
+			key: cur.Measurement.Measurement,
+		})
+		if err != nil {
+			return err
+		}
+		for k, v := range nc {
+			nic := cache[k]
+			if reDuplex.MatchString(cur.Measurement.System) {
+				nic.Duplex = v.Duplex
+			} else {
+				nic.Speed = v.Speed
+			}
+			cache[k] = nic
+		}
 		return nil
 	}
 
@@ -317,6 +511,7 @@ func csv(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.NIC
 	name := strconv.FormatUint(cur.Site, 10) + "_" +
 		strconv.FormatUint(cur.Host, 10) + "_" +
 		strconv.FormatUint(cur.Run, 10) + "_" +
+		cur.Measurement.Collection + "_" +
 		cur.Measurement.System
 	var (
 		prev *journal.WrapPCCollection
@@ -328,23 +523,43 @@ func csv(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.NIC
 		return nil
 	}
 
+	// Skip measurements that were journaled more than once. A collector
+	// that lost its spool restarts numbering, so check time as well.
+	if cur.Measurement.Sequence != 0 &&
+		cur.Measurement.Sequence <= prev.Measurement.Sequence &&
+		!cur.Measurement.Timestamp.After(prev.Measurement.Timestamp) {
+		return nil
+	}
+
+	// Named collections are written into their own directory.
+	output := cfg.Output
+	if c := cur.Measurement.Collection; c != "" &&
+		c != types.PCDefaultCollection {
+		output = filepath.Join(cfg.Output, c)
+	}
+
+	// All cgroups are written to a single file.
+	system := cur.Measurement.System
+	if util.CgroupSystem(system) {
+		system = types.PCCgroupRoot
+	}
+
 	var (
 		f *os.File
 	)
-	filename := filepath.Join(cfg.Output, cur.Measurement.System)
+	filename := filepath.Join(output, system)
 	if f, ok = fileCache[filename]; !ok {
 		// File not seen, create file, write header and cache
 
 		// Create dirs.
-		dir := filepath.Join(cfg.Output,
-			filepath.Dir(cur.Measurement.System))
+		dir := filepath.Join(output, filepath.Dir(system))
 		err := os.MkdirAll(dir, 0754)
 		if err != nil {
 			return err
 		}
 
 		// Overwrite old files.
-		file := filepath.Join(cfg.Output, cur.Measurement.System)
+		file := filepath.Join(output, system)
 		if cfg.Verbose {
 			fmt.Printf("open %v\n", file)
 		}
@@ -365,6 +580,45 @@ func csv(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.NIC
 		return err
 	}
 
+	if util.CgroupSystem(cur.Measurement.System) {
+		p, err := parser.ProcessCgroup([]byte(prev.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessCgroup prev: %v", err)
+		}
+		c, err := parser.ProcessCgroup([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessCgroup cur: %v", err)
+		}
+		// Ignore database bits
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
+		r, err := parser.CubeCgroup(0, 0, 0, 0, p, c, tvi)
+		if parser.IsReset(err) {
+			// Drop the interval and start over.
+			previousCache[name] = cur
+			return writeEvent(output, cur, database.Event{
+				System:      cur.Measurement.System,
+				Type:        database.EventReset,
+				Description: err.Error(),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("CubeCgroup: %v", err)
+		}
+
+		// Write out record
+		fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+			csvString(r.Name), r.UserT, r.System, r.CPU, r.Throttled,
+			r.NrThrottled, r.MemCurrent, r.MemAnon, r.MemFile,
+			r.PgFault, r.PgMajFault, r.RKBytes, r.WKBytes, r.Rios,
+			r.Wios, r.CPUSome, r.CPUFull)
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+		return nil
+	}
+
 	switch cur.Measurement.System {
 	case "/proc/stat":
 		p, err := parser.ProcessStat([]byte(prev.Measurement.Measurement))
@@ -377,16 +631,26 @@ func csv(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.NIC
 		}
 		// Ignore database bits
 		r, err := parser.CubeStat(0, 0, 0, 0, &p, &c)
+		if parser.IsReset(err) {
+			// Drop the interval and start over.
+			resetPrevious(name, cur)
+			return writeEvent(output, cur, database.Event{
+				System:      cur.Measurement.System,
+				Type:        database.EventReset,
+				Description: err.Error(),
+			})
+		}
 		if err != nil {
 			return fmt.Errorf("CubeStat: %v", err)
 		}
 
 		// Write out records
 		for k := range r {
-			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
-				cur.Site, cur.Host, cur.Measurement.Timestamp.Unix(),
+			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+				cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
 				r[k].CPU, r[k].UserT, r[k].Nice, r[k].System,
-				r[k].IOWait, r[k].Steal, r[k].Idle)
+				r[k].IOWait, r[k].Steal, r[k].IRQ, r[k].Soft,
+				r[k].Guest, r[k].GNice, r[k].Idle)
 		}
 
 		// Store cur into previousCache
@@ -405,11 +669,16 @@ func csv(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.NIC
 		}
 
 		// Write out records
-		fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
-			cur.Site, cur.Host, cur.Measurement.Timestamp.Unix(),
+		fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
 			r.MemFree, r.MemAvailable, r.MemUsed, r.PercentUsed,
 			r.Buffers, r.Cached, r.Commit, r.PercentCommit,
-			r.Active, r.Inactive, r.Dirty)
+			r.Active, r.Inactive, r.Dirty, r.SwapFree, r.SwapUsed,
+			r.PercentSwapUsed, r.SwapCached, r.PercentSwapCached,
+			r.HugeFree, r.HugeUsed, r.PercentHugeUsed)
+
+		// Store cur into previousCache
+		previousCache[name] = cur
 
 	case "/proc/net/dev":
 		p, err := parser.ProcessNetDev([]byte(prev.Measurement.Measurement))
@@ -421,11 +690,11 @@ func csv(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.NIC
 			return fmt.Errorf("ProcessNetDev cur: %v", err)
 		}
 		// Ignore database bits
-		tvi := uint64(cur.Measurement.Frequency.Seconds()) *
-			parser.UserHZ
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
 
 		// XXX there is no nic cache here, fix
-		r, err := parser.CubeNetDev(cur.Site, cur.Host, cur.Run,
+		r, events, err := parser.CubeNetDev(cur.Site, cur.Host, cur.Run,
 			0, /* timestamp */
 			0, /* start */
 			0, /* duration */
@@ -433,16 +702,97 @@ func csv(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.NIC
 		if err != nil {
 			return fmt.Errorf("CubeNetDev: %v", err)
 		}
+		for k := range events {
+			err := writeEvent(output, cur, events[k])
+			if err != nil {
+				return err
+			}
+		}
 
 		// Write out records
 		for k := range r {
-			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
-				cur.Site, cur.Host, cur.Measurement.Timestamp.Unix(),
+			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+				cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
 				r[k].Name, r[k].RxPackets, r[k].TxPackets,
 				r[k].RxKBytes, r[k].TxKBytes, r[k].RxCompressed,
-				r[k].TxCompressed, r[k].RxMulticast, r[k].IfUtil)
+				r[k].TxCompressed, r[k].RxMulticast, r[k].IfUtil,
+				r[k].RxErrors, r[k].TxErrors, r[k].Collisions,
+				r[k].RxDropped, r[k].TxDropped, r[k].TxCarrier,
+				r[k].RxFrame, r[k].RxFIFO, r[k].TxFIFO)
+		}
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case "/proc/net/snmp":
+		p, err := parser.ProcessNetProto([]byte(prev.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessNetProto prev: %v", err)
+		}
+		c, err := parser.ProcessNetProto([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessNetProto cur: %v", err)
+		}
+		// Ignore database bits
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
+		r, err := parser.CubeNetSNMP(0, 0, 0, 0, p, c, tvi)
+		if parser.IsReset(err) {
+			// Drop the interval and start over.
+			previousCache[name] = cur
+			return writeEvent(output, cur, database.Event{
+				System:      cur.Measurement.System,
+				Type:        database.EventReset,
+				Description: err.Error(),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("CubeNetSNMP: %v", err)
+		}
+
+		// Write out record
+		fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+			r.Irec, r.Fwddgm, r.Idel, r.Orq, r.Asmrq, r.Asmok,
+			r.Fragok, r.Fragcrt, r.Active, r.Passive, r.Iseg, r.Oseg,
+			r.Atmptf, r.Estres, r.Retrans, r.Isegerr, r.Orsts,
+			r.Idgm, r.Odgm, r.Noport, r.Idgmerr)
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case "/proc/net/netstat":
+		p, err := parser.ProcessNetProto([]byte(prev.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessNetProto prev: %v", err)
+		}
+		c, err := parser.ProcessNetProto([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessNetProto cur: %v", err)
+		}
+		// Ignore database bits
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
+		r, err := parser.CubeNetstat(0, 0, 0, 0, p, c, tvi)
+		if parser.IsReset(err) {
+			// Drop the interval and start over.
+			previousCache[name] = cur
+			return writeEvent(output, cur, database.Event{
+				System:      cur.Measurement.System,
+				Type:        database.EventReset,
+				Description: err.Error(),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("CubeNetstat: %v", err)
 		}
 
+		// Write out record
+		fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+			r.Lstovf, r.Lstdrp, r.Tmout, r.Synretr, r.Fretr,
+			r.Lostretr, r.Abortto)
+
 		// Store cur into previousCache
 		previousCache[name] = cur
 
@@ -456,20 +806,256 @@ func csv(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.NIC
 			return fmt.Errorf("ProcessDiskstats cur: %v", err)
 		}
 		// Ignore database bits
-		tvi := uint64(cur.Measurement.Frequency.Seconds()) *
-			parser.UserHZ
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
 		// XXX there is no nic cache here, fix
-		r, err := parser.CubeDiskstats(0, 0, 0, 0, p, c, tvi)
+		r, events, err := parser.CubeDiskstats(0, 0, 0, 0, p, c, tvi)
 		if err != nil {
 			return fmt.Errorf("CubeDiskstats: %v", err)
 		}
+		for k := range events {
+			err := writeEvent(output, cur, events[k])
+			if err != nil {
+				return err
+			}
+		}
 
 		// Write out records
 		for k := range r {
-			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
-				cur.Site, cur.Host, cur.Measurement.Timestamp.Unix(),
+			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+				cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
 				r[k].Name, r[k].Tps, r[k].Rtps, r[k].Wtps,
-				r[k].Dtps, r[k].Bread, r[k].Bwrtn, r[k].Bdscd)
+				r[k].Dtps, r[k].Bread, r[k].Bwrtn, r[k].Bdscd,
+				r[k].Rrqm, r[k].Wrqm, r[k].RAwait, r[k].WAwait,
+				r[k].AquSz, r[k].AreqSz, r[k].Util)
+		}
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case types.PCCPUFreqSystem:
+		// CPUFreq isn't differential so just toss first measurement.
+		c, err := parser.ProcessCPUFreq([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessCPUFreq cur: %v", err)
+		}
+		// Ignore database bits
+		r, err := parser.CubeCPUFreq(0, 0, 0, 0, c)
+		if err != nil {
+			return fmt.Errorf("CubeCPUFreq: %v", err)
+		}
+
+		// Write out records
+		for k := range r {
+			fmt.Fprintf(f, "%v,%v,%v,%v,%v\n",
+				cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+				r[k].CPU, r[k].MHz)
+		}
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case types.PCNodeSystem:
+		// Node meminfo isn't differential so just toss first measurement.
+		c, err := parser.ProcessNodeMeminfo([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessNodeMeminfo cur: %v", err)
+		}
+		// Ignore database bits
+		r, err := parser.CubeNodeMeminfo(0, 0, 0, 0, c)
+		if err != nil {
+			return fmt.Errorf("CubeNodeMeminfo: %v", err)
+		}
+
+		// Write out records
+		for k := range r {
+			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+				cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+				r[k].Node, r[k].MemTotal, r[k].MemFree, r[k].MemUsed,
+				r[k].PercentUsed, r[k].Active, r[k].Inactive,
+				r[k].FilePages, r[k].AnonPages, r[k].Slab, r[k].Dirty)
+		}
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case types.PCStatfsSystem:
+		// Statfs isn't differential so just toss first measurement.
+		c, err := parser.ProcessStatfs([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessStatfs cur: %v", err)
+		}
+		// Ignore database bits
+		r, err := parser.CubeStatfs(0, 0, 0, 0, c)
+		if err != nil {
+			return fmt.Errorf("CubeStatfs: %v", err)
+		}
+
+		// Write out records
+		for k := range r {
+			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+				cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+				csvString(r[k].Name), csvString(r[k].MountPoint),
+				r[k].FSType, r[k].MBSize, r[k].MBFree, r[k].MBUsed,
+				r[k].FSUsed, r[k].UFSUsed, r[k].Inodes, r[k].IFree,
+				r[k].IUsed, r[k].PercentIUsed)
+		}
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case types.PCPidstatSystem:
+		p, err := parser.ProcessPidstat([]byte(prev.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessPidstat prev: %v", err)
+		}
+		c, err := parser.ProcessPidstat([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessPidstat cur: %v", err)
+		}
+		// Ignore database bits
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
+		r, err := parser.CubePidstat(0, 0, 0, 0, p, c, tvi)
+		if err != nil {
+			return fmt.Errorf("CubePidstat: %v", err)
+		}
+
+		// Write out records
+		for k := range r {
+			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+				cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+				r[k].UID, r[k].PID, r[k].UserT, r[k].System,
+				r[k].CPU, r[k].MinFlt, r[k].MajFlt, r[k].VSZ,
+				r[k].RSS, r[k].KBRead, r[k].KBWrite, r[k].KBCcwr,
+				r[k].Cswch, r[k].Nvcswch, r[k].Threads,
+				csvString(r[k].Name))
+		}
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case "/proc/loadavg":
+		c, err := parser.ProcessLoadavg([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessLoadavg cur: %v", err)
+		}
+		// The blocked processes are reported by the most recent
+		// /proc/stat measurement of the same collection.
+		var st *parser.Stat
+		statName := strings.TrimSuffix(name, cur.Measurement.System) +
+			"/proc/stat"
+		if ps, ok := previousCache[statName]; ok {
+			s, err := parser.ProcessStat([]byte(ps.Measurement.Measurement))
+			if err != nil {
+				return fmt.Errorf("ProcessStat: %v", err)
+			}
+			st = &s
+		}
+		// Ignore database bits
+		r, err := parser.CubeLoadavg(0, 0, 0, 0, &c, st)
+		if err != nil {
+			return fmt.Errorf("CubeLoadavg: %v", err)
+		}
+
+		// Write out record
+		fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+			r.RunqSz, r.PlistSz, r.Ldavg1, r.Ldavg5, r.Ldavg15,
+			r.Blocked)
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case "/proc/vmstat":
+		p, err := parser.ProcessVmstat([]byte(prev.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessVmstat prev: %v", err)
+		}
+		c, err := parser.ProcessVmstat([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessVmstat cur: %v", err)
+		}
+		// Ignore database bits
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
+		r, err := parser.CubeVmstat(0, 0, 0, 0, &p, &c, tvi)
+		if parser.IsReset(err) {
+			// Drop the interval and start over.
+			previousCache[name] = cur
+			return writeEvent(output, cur, database.Event{
+				System:      cur.Measurement.System,
+				Type:        database.EventReset,
+				Description: err.Error(),
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("CubeVmstat: %v", err)
+		}
+
+		// Write out record
+		fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+			r.Pgpgin, r.Pgpgout, r.Fault, r.MajFlt, r.Pgfree,
+			r.Pgscank, r.Pgscand, r.Pgsteal, r.Vmeff, r.Pswpin,
+			r.Pswpout)
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case "/proc/pressure/cpu", "/proc/pressure/memory",
+		"/proc/pressure/io":
+		p, err := parser.ProcessPressure([]byte(prev.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessPressure prev: %v", err)
+		}
+		c, err := parser.ProcessPressure([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessPressure cur: %v", err)
+		}
+		// Ignore database bits
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
+		r, err := parser.CubePressure(0, 0, 0, 0,
+			parser.PressureResource(cur.Measurement.System), &p, &c,
+			tvi)
+		if err != nil {
+			return fmt.Errorf("CubePressure: %v", err)
+		}
+
+		// Write out record
+		fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n",
+			cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+			r.Resource, r.SomeAvg10, r.SomeAvg60, r.SomeAvg300,
+			r.SomeStall, r.FullAvg10, r.FullAvg60, r.FullAvg300,
+			r.FullStall)
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case "/proc/interrupts", "/proc/softirqs":
+		p, err := parser.ProcessInterrupts([]byte(prev.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessInterrupts prev: %v", err)
+		}
+		c, err := parser.ProcessInterrupts([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return fmt.Errorf("ProcessInterrupts cur: %v", err)
+		}
+		// Ignore database bits
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
+		r, err := parser.CubeInterrupts(0, 0, 0, 0, p, c, tvi)
+		if err != nil {
+			return fmt.Errorf("CubeInterrupts: %v", err)
+		}
+
+		// Write out records
+		for k := range r {
+			fmt.Fprintf(f, "%v,%v,%v,%v,%v,%v,%v\n",
+				cur.Site, cur.Host, unixTimestamp(cur.Measurement.Timestamp),
+				r[k].CPU, csvString(r[k].Source),
+				csvString(r[k].Description), r[k].Intr)
 		}
 
 		// Store cur into previousCache
@@ -581,6 +1167,9 @@ func _main() error {
 			return err
 		}
 	}
+	if netCache == nil {
+		netCache = make(map[string]parser.NIC)
+	}
 
 	// Open input file
 	f, err := os.Open(cfg.InputFile)
@@ -607,6 +1196,7 @@ func _main() error {
 	}
 
 	// Process
+	runs := make(map[string]string) // Run names, keyed by runKey
 	entries := 0
 	start := time.Now()
 	s := time.Now().Add(5 * time.Second)
@@ -626,6 +1216,14 @@ func _main() error {
 			return err
 		}
 
+		ok, err := selected(cfg, runs, wc)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
 		switch mode {
 		case modeCSV:
 			if err != nil {
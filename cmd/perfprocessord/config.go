@@ -67,6 +67,7 @@ type config struct {
 	Version     string
 	SSHKeyFile  string   `long:"sshid" description:"File containing the ssh identity"`
 	Hosts       []string `long:"hosts" description:"Add perfcollector host <siteid:hostid/ip:port>"`
+	SinkName    string   `long:"sinkname" description:"Name that identifies this processor to the collectors (default hostname)"`
 
 	// Socket
 	SocketFilename string `long:"socket" description:"Socket filename"`
@@ -425,6 +426,15 @@ func loadConfig() (*config, []string, error) {
 	// worry about changing names per network and such.
 	cfg.DataDir = cleanAndExpandPath(cfg.DataDir)
 
+	// Collectors track delivery per sink name.
+	if cfg.SinkName == "" {
+		cfg.SinkName, err = os.Hostname()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: sink name: %v",
+				funcName, err)
+		}
+	}
+
 	// Journal filename and cipher
 	cfg.journalFilename = filepath.Join(cfg.DataDir,
 		sharedconfig.DefaultJournalFilename)
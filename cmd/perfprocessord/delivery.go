@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/businessperformancetuning/perfcollector/cmd/perfprocessord/journal"
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+const (
+	// ackInterval is the interval at which durably stored measurements
+	// are acknowledged to the collector.
+	ackInterval = 5 * time.Second
+
+	// deliveryDirname is the directory in the data directory that holds
+	// the per host delivery state.
+	deliveryDirname = "delivery"
+)
+
+// deliveryState records, per collection, the last measurement of a collector
+// that was durably stored. It is used to resume delivery after a reconnect.
+// Runs that are open at that point are recorded as well so that their
+// measurements continue in the same run.
+type deliveryState struct {
+	Sequences map[string]uint64      // Last durably stored sequence numbers
+	Runs      map[string]deliveryRun // Open runs, keyed by collection
+	NextRun   uint64                 // Next run identifier when journaling
+}
+
+// deliveryRun is a run that was open when the delivery state was recorded.
+type deliveryRun struct {
+	ID  uint64      // Run identifier
+	Run types.PCRun // Run as started by the collector
+}
+
+// deliveryFilename returns the filename of the delivery state of a host.
+func (p *PerfCtl) deliveryFilename(site, host uint64) string {
+	return filepath.Join(p.cfg.DataDir, deliveryDirname,
+		fmt.Sprintf("%v_%v.json", site, host))
+}
+
+// loadDelivery returns the delivery state of a host. A zero state is returned
+// if the host has never delivered measurements.
+func (p *PerfCtl) loadDelivery(site, host uint64) (*deliveryState, error) {
+	ds := deliveryState{
+		Sequences: make(map[string]uint64),
+		Runs:      make(map[string]deliveryRun),
+	}
+	b, err := os.ReadFile(p.deliveryFilename(site, host))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &ds, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(b, &ds)
+	if err != nil {
+		return nil, fmt.Errorf("delivery state %v:%v: %v", site, host,
+			err)
+	}
+	if ds.Sequences == nil {
+		ds.Sequences = make(map[string]uint64)
+	}
+	if ds.Runs == nil {
+		ds.Runs = make(map[string]deliveryRun)
+	}
+	return &ds, nil
+}
+
+// saveDelivery atomically stores the delivery state of a host.
+func (p *PerfCtl) saveDelivery(site, host uint64, ds *deliveryState) error {
+	filename := p.deliveryFilename(site, host)
+	err := os.MkdirAll(filepath.Dir(filename), 0750)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(ds)
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	err = os.WriteFile(tmp, b, 0640)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// acker tracks which measurements have been stored and periodically
+// acknowledges them to the collector.
+type acker struct {
+	sync.Mutex
+
+	site   uint64
+	host   uint64
+	s      *session
+	stored map[string]uint64 // Last stored sequence number per collection
+	acked  map[string]uint64 // Last acknowledged sequence number per collection
+	doneC  chan struct{}     // Closed when ackLoop exits
+
+	runs    map[string]deliveryRun // Open runs, keyed by collection
+	nextRun uint64                 // Next run identifier when journaling
+}
+
+// newAcker returns an acker that starts at the provided delivery state.
+func newAcker(site, host uint64, s *session, ds *deliveryState) *acker {
+	a := &acker{
+		site:   site,
+		host:   host,
+		s:      s,
+		stored: make(map[string]uint64, len(ds.Sequences)),
+		acked:  make(map[string]uint64, len(ds.Sequences)),
+		doneC:  make(chan struct{}),
+
+		runs:    make(map[string]deliveryRun, len(ds.Runs)),
+		nextRun: ds.NextRun,
+	}
+	for k, v := range ds.Sequences {
+		a.stored[k] = v
+		a.acked[k] = v
+	}
+	for k, v := range ds.Runs {
+		a.runs[k] = v
+	}
+	return a
+}
+
+// openRun returns the open run of a collection.
+func (a *acker) openRun(collection string) (deliveryRun, bool) {
+	a.Lock()
+	defer a.Unlock()
+	r, ok := a.runs[collection]
+	return r, ok
+}
+
+// setRun records r as the open run of a collection. A nil r records that the
+// run of the collection was closed.
+func (a *acker) setRun(collection string, r *deliveryRun) {
+	a.Lock()
+	if r == nil {
+		delete(a.runs, collection)
+	} else {
+		a.runs[collection] = *r
+	}
+	a.Unlock()
+}
+
+// allocRun returns a new run identifier. It is used when journaling, with a
+// database the run identifiers are handed out by the database. Identifier 0
+// is reserved for measurements that predate runs.
+func (a *acker) allocRun() uint64 {
+	a.Lock()
+	defer a.Unlock()
+	if a.nextRun == 0 {
+		a.nextRun = 1
+	}
+	id := a.nextRun
+	a.nextRun++
+	return id
+}
+
+// store records that all measurements of a collection up to and including
+// sequence have been stored.
+func (a *acker) store(collection string, sequence uint64) {
+	a.Lock()
+	if sequence > a.stored[collection] {
+		a.stored[collection] = sequence
+	}
+	a.Unlock()
+}
+
+// flush makes all stored measurements durable, records the delivery state and
+// acknowledges the measurements to the collector.
+func (p *PerfCtl) flush(a *acker) error {
+	a.Lock()
+	stored := make(map[string]uint64, len(a.stored))
+	changed := make([]string, 0, len(a.stored))
+	for k, v := range a.stored {
+		stored[k] = v
+		if a.acked[k] != v {
+			changed = append(changed, k)
+		}
+	}
+	runs := make(map[string]deliveryRun, len(a.runs))
+	for k, v := range a.runs {
+		runs[k] = v
+	}
+	nextRun := a.nextRun
+	a.Unlock()
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// Journal entries must hit the disk before they can be acknowledged.
+	if p.cfg.Journal {
+		err := journal.Sync(p.cfg.journalFilename)
+		if err != nil {
+			return fmt.Errorf("journal sync: %v", err)
+		}
+	}
+
+	err := p.saveDelivery(a.site, a.host, &deliveryState{
+		Sequences: stored,
+		Runs:      runs,
+		NextRun:   nextRun,
+	})
+	if err != nil {
+		return fmt.Errorf("save delivery: %v", err)
+	}
+
+	a.Lock()
+	for _, k := range changed {
+		a.acked[k] = stored[k]
+	}
+	a.Unlock()
+
+	for _, k := range changed {
+		err := p.send(a.s, types.PCCommand{
+			Cmd: types.PCAckSequenceCmd,
+			Payload: types.PCAckSequence{
+				Collection: k,
+				Sequence:   stored[k],
+			},
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ackLoop periodically acknowledges stored measurements until the context is
+// canceled. The remaining measurements are acknowledged on exit.
+func (p *PerfCtl) ackLoop(ctx context.Context, a *acker) {
+	log.Tracef("ackLoop %v:%v", a.site, a.host)
+	defer log.Tracef("ackLoop exit %v:%v", a.site, a.host)
+
+	defer close(a.doneC)
+
+	t := time.NewTicker(ackInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := p.flush(a); err != nil {
+				log.Errorf("ackLoop %v:%v: %v", a.site, a.host,
+					err)
+			}
+			return
+		case <-t.C:
+			if err := p.flush(a); err != nil {
+				log.Errorf("ackLoop %v:%v: %v", a.site, a.host,
+					err)
+			}
+		}
+	}
+}
@@ -77,6 +77,22 @@ func Journal(filename string, aead cipher.AEAD, payload interface{}) error {
 	return nil
 }
 
+// Sync commits the journal to stable storage.
+func Sync(filename string) error {
+	mtx.Lock()
+	defer mtx.Unlock()
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0640)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Nothing journaled yet.
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
 // XXX this really doesn't belong here.
 type WrapPCCollection struct {
 	Site        uint64
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/gob"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -25,6 +26,7 @@ import (
 	"github.com/businessperformancetuning/perfcollector/types"
 	"github.com/businessperformancetuning/perfcollector/util"
 	"github.com/davecgh/go-spew/spew"
+	"github.com/inhies/go-bytesize"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/sync/errgroup"
 )
@@ -442,25 +444,68 @@ func (p *PerfCtl) singleCommand(ctx context.Context, s *session, h HostIdentifie
 		fmt.Printf("Status             : %v\n", s.address)
 		fmt.Printf("Sink enabled       : %v\n", r.SinkEnabled)
 		fmt.Printf("Measurement enabled: %v\n", r.MeasurementEnabled)
-		if r.MeasurementEnabled && r.StartCollection != nil {
-			fmt.Printf("Frequency          : %v\n",
-				r.StartCollection.Frequency)
-			fmt.Printf("Queue depth        : %v\n",
-				r.StartCollection.QueueDepth)
-			fmt.Printf("Queue free         : %v\n",
-				r.QueueFree)
-			fmt.Printf("Systems            : %v\n",
-				r.StartCollection.Systems)
+		for _, c := range r.Collections {
+			fmt.Printf("Collection         : %v\n",
+				c.StartCollection.Name)
+			fmt.Printf("  Running          : %v\n", c.Running)
+			if c.Running {
+				fmt.Printf("  Run              : %v\n",
+					c.StartCollection.Run)
+				if c.StartCollection.Label != "" {
+					fmt.Printf("  Label            : %v\n",
+						c.StartCollection.Label)
+				}
+				if c.StartCollection.Description != "" {
+					fmt.Printf("  Description      : %v\n",
+						c.StartCollection.Description)
+				}
+				fmt.Printf("  Frequency        : %v\n",
+					c.StartCollection.Frequency)
+				fmt.Printf("  Queue depth      : %v\n",
+					c.StartCollection.QueueDepth)
+				fmt.Printf("  Queue free       : %v\n",
+					c.QueueFree)
+				fmt.Printf("  Systems          : %v\n",
+					c.StartCollection.Systems)
+				ps := c.StartCollection.Processes
+				if len(ps.Names) != 0 {
+					fmt.Printf("  Process names    : %v\n",
+						ps.Names)
+				}
+				if len(ps.Users) != 0 {
+					fmt.Printf("  Process users    : %v\n",
+						ps.Users)
+				}
+				if len(ps.Cgroups) != 0 {
+					fmt.Printf("  Process cgroups  : %v\n",
+						ps.Cgroups)
+				}
+				fmt.Printf("  Missed ticks     : %v\n", c.Missed)
+				fmt.Printf("  Lateness         : %v\n", c.Lateness)
+			}
+			fmt.Printf("  Spooled          : %v (%v)\n", c.Spooled,
+				bytesize.New(float64(c.SpoolSize)))
+		}
+		for _, v := range r.Sinks {
+			fmt.Printf("Sink               : %v\n", v.Name)
+			fmt.Printf("  Connected        : %v\n", v.Connected)
+			fmt.Printf("  Last seen        : %v\n",
+				v.LastSeen.Format(time.RFC3339))
+			fmt.Printf("  Backlog          : %v\n", v.Backlog)
 		}
 
 	case "start":
-		frequency, err := util.ArgAsInt("frequency", a)
-		if err != nil {
-			frequency = 5
+		// Frequency is in seconds or a duration such as 500ms.
+		frequency := 5 * time.Second
+		if f, err := util.ArgAsInt("frequency", a); err == nil {
+			frequency = time.Duration(f) * time.Second
+		} else if f, err := util.ArgAsDuration("frequency", a); err == nil {
+			frequency = f
 		}
 		queueDepth, err := util.ArgAsInt("depth", a)
 		if err != nil {
-			queueDepth = 1000
+			// Limited by the collector spool size.
+			queueDepth = 0
 		}
 		systems, err := util.ArgAsStringSlice("systems", a)
 		if err != nil {
@@ -469,14 +514,42 @@ func (p *PerfCtl) singleCommand(ctx context.Context, s *session, h HostIdentifie
 				"/proc/meminfo",
 				"/proc/net/dev",
 				"/proc/diskstats",
+				"/proc/vmstat",
+			}
+		}
+		name, err := util.ArgAsString("name", a)
+		if err != nil {
+			name = types.PCDefaultCollection
+		}
+		// Cgroups are provided as glob patterns and expanded on the
+		// collector.
+		if cgroups, err := util.ArgAsStringSlice("cgroups", a); err == nil {
+			cs, err := p.globDirectories(ctx, s, cgroups)
+			if err != nil {
+				return fmt.Errorf("cgroups: %v", err)
 			}
+			systems = append(systems, cs...)
 		}
+		// Processes measured by the pidstat system.
+		var processes types.PCProcessSelector
+		processes.Names, _ = util.ArgAsStringSlice("pidnames", a)
+		processes.Users, _ = util.ArgAsStringSlice("pidusers", a)
+		processes.Cgroups, _ = util.ArgAsStringSlice("pidcgroups", a)
+		// The collector names the run when it is not named.
+		run, _ := util.ArgAsString("run", a)
+		label, _ := util.ArgAsString("label", a)
+		description, _ := util.ArgAsString("description", a)
 		_, err = p.sendAndWait(ctx, s, types.PCCommand{
 			Cmd: types.PCStartCollectionCmd,
 			Payload: types.PCStartCollection{
-				Frequency:  time.Duration(frequency) * time.Second,
-				QueueDepth: queueDepth,
-				Systems:    systems,
+				Name:        name,
+				Frequency:   frequency,
+				QueueDepth:  queueDepth,
+				Systems:     systems,
+				Processes:   processes,
+				Run:         run,
+				Label:       label,
+				Description: description,
 			},
 		})
 		if err != nil {
@@ -484,8 +557,13 @@ func (p *PerfCtl) singleCommand(ctx context.Context, s *session, h HostIdentifie
 		}
 
 	case "stop":
+		// Stop all collections unless a name is provided.
+		name, _ := util.ArgAsString("name", a)
 		_, err := p.sendAndWait(ctx, s, types.PCCommand{
 			Cmd: types.PCStopCollectionCmd,
+			Payload: types.PCStopCollection{
+				Name: name,
+			},
 		})
 		if err != nil {
 			return err
@@ -715,6 +793,69 @@ func (p *PerfCtl) getNetDevices(ctx context.Context, s *session, devices []strin
 	return nics, nil
 }
 
+// globDirectories expands the directory glob patterns on the collector. The
+// directories are listed level by level with the directories command, so
+// only components that contain a pattern cost a round trip.
+func (p *PerfCtl) globDirectories(ctx context.Context, s *session, patterns []string) ([]string, error) {
+	var r []string
+	for _, pattern := range patterns {
+		pattern = filepath.Clean(pattern)
+		if !filepath.IsAbs(pattern) {
+			return nil, fmt.Errorf("not an absolute path: %v",
+				pattern)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %v: %v",
+				pattern, err)
+		}
+
+		matches := []string{"/"}
+		for _, c := range strings.Split(pattern, "/")[1:] {
+			if !strings.ContainsAny(c, `*?[\`) {
+				for k := range matches {
+					matches[k] = filepath.Join(matches[k], c)
+				}
+				continue
+			}
+
+			reply, err := p.sendAndWait(ctx, s, types.PCCommand{
+				Cmd: types.PCCollectDirectoriesCmd,
+				Payload: types.PCCollectDirectories{
+					Directories: matches,
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			dr, ok := reply.(types.PCCollectDirectoriesReply)
+			if !ok || len(dr.Values) != len(matches) {
+				return nil, fmt.Errorf("invalid directories "+
+					"reply: %T", reply)
+			}
+			var next []string
+			for k := range dr.Values {
+				for _, v := range dr.Values[k] {
+					// Only directories have a trailing slash.
+					if !strings.HasSuffix(v, "/") {
+						continue
+					}
+					v = strings.TrimSuffix(v, "/")
+					if ok, _ := filepath.Match(c, v); ok {
+						next = append(next,
+							filepath.Join(matches[k], v))
+					}
+				}
+			}
+			if len(next) == 0 {
+				return nil, fmt.Errorf("no match: %v", pattern)
+			}
+			matches = next
+		}
+		r = append(r, matches...)
+	}
+	return r, nil
+}
+
 func (p *PerfCtl) journal(site, host, run uint64, measurement types.PCCollection) error {
 	if !util.ValidSystem(measurement.System) {
 		return fmt.Errorf("journal unsupported system: %v",
@@ -754,6 +895,574 @@ func (p *PerfCtl) journal(site, host, run uint64, measurement types.PCCollection
 	return json.NewEncoder(f).Encode(measurement)
 }
 
+// insertEvents stores events, e.g. devices that appeared or disappeared.
+func (p *PerfCtl) insertEvents(ctx context.Context, h *hostState, events []database.Event) error {
+	for k := range events {
+		log.Debugf("sinkLoop event %v:%v: %v", h.site, h.host,
+			events[k].Description)
+		err := p.db.EventInsert(ctx, &events[k])
+		if err != nil {
+			return fmt.Errorf("EventInsert: %w", err)
+		}
+	}
+	return nil
+}
+
+// reset records a counter reset, e.g. after a reboot of the host, and returns
+// true if err is one. The interval is dropped and the caller must use the
+// current measurement as the new baseline.
+func (p *PerfCtl) reset(ctx context.Context, h *hostState, m *types.PCCollection, err error) (bool, error) {
+	if !parser.IsReset(err) {
+		return false, nil
+	}
+	log.Warnf("sinkLoop %v %v:%v: %v", m.System, h.site, h.host, err)
+	return true, p.insertEvents(ctx, h, []database.Event{{
+		RunID:     h.run,
+		Timestamp: m.Timestamp.UnixNano(),
+		Start:     m.Start.UnixNano(),
+		Duration:  int64(m.Duration),
+
+		System:      m.System,
+		Type:        database.EventReset,
+		Description: err.Error(),
+	}})
+}
+
+// sinkMeasurement stores measurement m of a host in the journal or the
+// database and hands its sequence number to the acker once it is stored. An
+// error is returned when m was not stored, e.g. because the database is
+// unreachable; it is then not acknowledged so that the collector retransmits
+// it. Records that the database rejects would be rejected again, they are
+// logged and acknowledged. Records that were stored before are skipped by the
+// database.
+func (p *PerfCtl) sinkMeasurement(ctx context.Context, s *session, a *acker, runs map[string]*hostState, m *types.PCCollection) error {
+	err := p.processMeasurement(ctx, s, a, runs, m)
+	if errors.Is(err, database.ErrData) {
+		log.Errorf("sinkMeasurement %v:%v %v: dropped: %v", a.site,
+			a.host, m.System, err)
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Everything up to and including m has been stored.
+	if m.Sequence != 0 {
+		a.store(m.Collection, m.Sequence)
+	}
+	return nil
+}
+
+// processMeasurement records measurement m in the journal or turns it into
+// statistics that are inserted into the database. Measurements that can not
+// be parsed or cubed are logged and dropped, retransmitting them would not
+// help. Journal and database errors are returned.
+func (p *PerfCtl) processMeasurement(ctx context.Context, s *session, a *acker, runs map[string]*hostState, m *types.PCCollection) error {
+	site, host := a.site, a.host
+	collection := m.Collection
+
+	if m.System == types.PCRunSystem {
+		err := p.handleRun(ctx, a, runs, m)
+		if err != nil {
+			return fmt.Errorf("run: %v", err)
+		}
+		return nil
+	}
+	state, err := p.run(ctx, a, runs, m)
+	if err != nil {
+		return fmt.Errorf("run: %v", err)
+	}
+	runID := state.run
+
+	prev := state.collection(collection)
+
+	// The inventory is kept up to date for both the database and
+	// the journal. The journal receives the speed and duplex of
+	// interfaces ahead of the measurement they apply to.
+	inventory := p.inventory(ctx, s, state, m)
+
+	// XXX consider reading more than one measurement at a time and
+	// batch the writes.
+
+	if p.cfg.Journal {
+		log.Tracef("sinkLoop journal %v:%v: %v",
+			site, host, m.System)
+		err := p.journalInventory(state, inventory)
+		if err != nil {
+			return fmt.Errorf("journal inventory: %v", err)
+		}
+		err = p.journal(site, host, runID, *m)
+		if err != nil {
+			return fmt.Errorf("journal: %v", err)
+		}
+		return nil
+	}
+
+	// Post process
+	switch m.System {
+	case "/proc/stat":
+		s, err := parser.ProcessStat([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process stat "+
+				"%v:%v: %v", site, host, err)
+			return nil
+		}
+		if prev.stat == nil {
+			prev.stat = &s
+			return nil
+		}
+		cs, err := parser.CubeStat(runID, m.Timestamp.UnixNano(),
+			m.Start.UnixNano(), int64(m.Duration), prev.stat,
+			&s)
+		if reset, err := p.reset(ctx, state, m, err); reset {
+			// Only /proc/stat carries the boot time, the
+			// other systems of the collection were reset as
+			// well and start over from their next sample.
+			*prev = previousSample{stat: &s}
+			return err
+		}
+		if err != nil {
+			log.Errorf("sinkLoop CubeStat %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+		prev.stat = &s
+
+		err = p.db.StatInsert(ctx, cs)
+		if err != nil {
+			return fmt.Errorf("StatInsert: %w", err)
+		}
+		return nil
+
+	case "/proc/meminfo":
+		s, err := parser.ProcessMeminfo([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"meminfo %v:%v: %v", site, host, err)
+			return nil
+		}
+		mi, err := parser.CubeMeminfo(runID, m.Timestamp.UnixNano(),
+			m.Start.UnixNano(), int64(m.Duration), &s)
+		if err != nil {
+			log.Errorf("sinkLoop CubeMeminfo %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+
+		err = p.db.MeminfoInsert(ctx, mi)
+		if err != nil {
+			return fmt.Errorf("MeminfoInsert: %w", err)
+		}
+		return nil
+
+	case "/proc/loadavg":
+		la, err := parser.ProcessLoadavg([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"loadavg %v:%v: %v", site, host, err)
+			return nil
+		}
+		// The blocked processes are reported by the most
+		// recent /proc/stat of the collection.
+		l, err := parser.CubeLoadavg(runID, m.Timestamp.UnixNano(),
+			m.Start.UnixNano(), int64(m.Duration), &la, prev.stat)
+		if err != nil {
+			log.Errorf("sinkLoop CubeLoadavg %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+
+		err = p.db.LoadavgInsert(ctx, l)
+		if err != nil {
+			return fmt.Errorf("LoadavgInsert: %w", err)
+		}
+		return nil
+
+	case "/proc/vmstat":
+		vs, err := parser.ProcessVmstat([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"vmstat %v:%v: %v", site, host, err)
+			return nil
+		}
+		if prev.vmstat == nil {
+			prev.vmstat = &vs
+			prev.started(m)
+			return nil
+		}
+		tvi := prev.interval(m)
+		v, err := parser.CubeVmstat(runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), prev.vmstat, &vs, tvi)
+		if reset, err := p.reset(ctx, state, m, err); reset {
+			prev.vmstat = &vs
+			prev.started(m)
+			return err
+		}
+		if err != nil {
+			log.Errorf("sinkLoop CubeVmstat %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+		prev.vmstat = &vs
+		prev.started(m)
+
+		err = p.db.VmstatInsert(ctx, v)
+		if err != nil {
+			return fmt.Errorf("VmstatInsert: %w", err)
+		}
+		return nil
+
+	case "/proc/net/dev":
+		n, err := parser.ProcessNetDev([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process netdev "+
+				"%v:%v: %v", site, host, err)
+			return nil
+		}
+
+		if prev.net == nil {
+			prev.net = n
+			prev.started(m)
+			return nil
+		}
+		tvi := prev.interval(m)
+		nd, events, err := parser.CubeNetDev(site, host, runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), prev.net, n, tvi, state.nics)
+		if err != nil {
+			log.Errorf("sigkLoop CubeNetDev %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+		prev.net = n
+		prev.started(m)
+		err = p.insertEvents(ctx, state, events)
+		if err != nil {
+			return err
+		}
+
+		err = p.db.NetDevInsert(ctx, nd)
+		if err != nil {
+			return fmt.Errorf("NetDevInsert: %w", err)
+		}
+		return nil
+
+	case "/proc/net/snmp":
+		n, err := parser.ProcessNetProto([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"netsnmp %v:%v: %v", site, host, err)
+			return nil
+		}
+		if prev.netsnmp == nil {
+			prev.netsnmp = n
+			prev.started(m)
+			return nil
+		}
+		tvi := prev.interval(m)
+		ns, err := parser.CubeNetSNMP(runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), prev.netsnmp, n, tvi)
+		if reset, err := p.reset(ctx, state, m, err); reset {
+			prev.netsnmp = n
+			prev.started(m)
+			return err
+		}
+		if err != nil {
+			log.Errorf("sinkLoop CubeNetSNMP %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+		prev.netsnmp = n
+		prev.started(m)
+
+		err = p.db.NetSNMPInsert(ctx, ns)
+		if err != nil {
+			return fmt.Errorf("NetSNMPInsert: %w", err)
+		}
+		return nil
+
+	case "/proc/net/netstat":
+		n, err := parser.ProcessNetProto([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"netstat %v:%v: %v", site, host, err)
+			return nil
+		}
+		if prev.netstat == nil {
+			prev.netstat = n
+			prev.started(m)
+			return nil
+		}
+		tvi := prev.interval(m)
+		ns, err := parser.CubeNetstat(runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), prev.netstat, n, tvi)
+		if reset, err := p.reset(ctx, state, m, err); reset {
+			prev.netstat = n
+			prev.started(m)
+			return err
+		}
+		if err != nil {
+			log.Errorf("sinkLoop CubeNetstat %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+		prev.netstat = n
+		prev.started(m)
+
+		err = p.db.NetstatInsert(ctx, ns)
+		if err != nil {
+			return fmt.Errorf("NetstatInsert: %w", err)
+		}
+		return nil
+
+	case "/proc/diskstats":
+		d, err := parser.ProcessDiskstats([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"diskstats %v:%v: %v", site, host, err)
+			return nil
+		}
+		if prev.disk == nil {
+			prev.disk = d
+			prev.started(m)
+			return nil
+		}
+		tvi := prev.interval(m)
+		ds, events, err := parser.CubeDiskstats(runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), prev.disk, d, tvi)
+		if err != nil {
+			log.Errorf("sigkLoop CubeDiskstats %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+		prev.disk = d
+		prev.started(m)
+		err = p.insertEvents(ctx, state, events)
+		if err != nil {
+			return err
+		}
+
+		err = p.db.DiskstatInsert(ctx, ds)
+		if err != nil {
+			return fmt.Errorf("DiskstatInsert: %w", err)
+		}
+		return nil
+
+	case types.PCStatfsSystem:
+		st, err := parser.ProcessStatfs([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"statfs %v:%v: %v", site, host, err)
+			return nil
+		}
+		fs, err := parser.CubeStatfs(runID, m.Timestamp.UnixNano(),
+			m.Start.UnixNano(), int64(m.Duration), st)
+		if err != nil {
+			log.Errorf("sinkLoop CubeStatfs %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+
+		err = p.db.StatfsInsert(ctx, fs)
+		if err != nil {
+			return fmt.Errorf("StatfsInsert: %w", err)
+		}
+		return nil
+
+	case types.PCCPUFreqSystem:
+		cf, err := parser.ProcessCPUFreq([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"cpufreq %v:%v: %v", site, host, err)
+			return nil
+		}
+		fs, err := parser.CubeCPUFreq(runID, m.Timestamp.UnixNano(),
+			m.Start.UnixNano(), int64(m.Duration), cf)
+		if err != nil {
+			log.Errorf("sinkLoop CubeCPUFreq %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+
+		err = p.db.CPUFreqInsert(ctx, fs)
+		if err != nil {
+			return fmt.Errorf("CPUFreqInsert: %w", err)
+		}
+		return nil
+
+	case types.PCNodeSystem:
+		nm, err := parser.ProcessNodeMeminfo([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"node %v:%v: %v", site, host, err)
+			return nil
+		}
+		ns, err := parser.CubeNodeMeminfo(runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), nm)
+		if err != nil {
+			log.Errorf("sinkLoop CubeNodeMeminfo %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+
+		err = p.db.NodeInsert(ctx, ns)
+		if err != nil {
+			return fmt.Errorf("NodeInsert: %w", err)
+		}
+		return nil
+
+	case types.PCPidstatSystem:
+		ps, err := parser.ProcessPidstat([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"pidstat %v:%v: %v", site, host, err)
+			return nil
+		}
+		if prev.pidstat == nil {
+			prev.pidstat = ps
+			prev.started(m)
+			return nil
+		}
+		tvi := prev.interval(m)
+		pr, err := parser.CubePidstat(runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), prev.pidstat, ps, tvi)
+		if err != nil {
+			log.Errorf("sinkLoop CubePidstat %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+		prev.pidstat = ps
+		prev.started(m)
+
+		err = p.db.PidstatInsert(ctx, pr)
+		if err != nil {
+			return fmt.Errorf("PidstatInsert: %w", err)
+		}
+		return nil
+
+	case "/proc/pressure/cpu", "/proc/pressure/memory",
+		"/proc/pressure/io":
+		ps, err := parser.ProcessPressure([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"pressure %v:%v: %v", site, host, err)
+			return nil
+		}
+		if prev.pressure == nil {
+			prev.pressure = make(map[string]*parser.Pressure)
+		}
+		if _, ok := prev.pressure[m.System]; !ok {
+			prev.pressure[m.System] = &ps
+			prev.started(m)
+			return nil
+		}
+		tvi := prev.interval(m)
+		pr, err := parser.CubePressure(runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), parser.PressureResource(m.System),
+			prev.pressure[m.System], &ps, tvi)
+		if err != nil {
+			log.Errorf("sinkLoop CubePressure %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+		prev.pressure[m.System] = &ps
+		prev.started(m)
+
+		err = p.db.PressureInsert(ctx, pr)
+		if err != nil {
+			return fmt.Errorf("PressureInsert: %w", err)
+		}
+		return nil
+
+	case "/proc/interrupts", "/proc/softirqs":
+		in, err := parser.ProcessInterrupts([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process "+
+				"interrupts %v:%v: %v", site, host, err)
+			return nil
+		}
+		if prev.interrupts == nil {
+			prev.interrupts = make(map[string]*parser.Interrupts)
+		}
+		if _, ok := prev.interrupts[m.System]; !ok {
+			prev.interrupts[m.System] = in
+			prev.started(m)
+			return nil
+		}
+		tvi := prev.interval(m)
+		is, err := parser.CubeInterrupts(runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), prev.interrupts[m.System], in,
+			tvi)
+		if err != nil {
+			log.Errorf("sinkLoop CubeInterrupts %v:%v: %v",
+				site, host, err)
+			// CPUs went on or offline, start over.
+			prev.interrupts[m.System] = in
+			prev.started(m)
+			return nil
+		}
+		prev.interrupts[m.System] = in
+		prev.started(m)
+
+		err = p.db.InterruptsInsert(ctx, is)
+		if err != nil {
+			return fmt.Errorf("InterruptsInsert: %w", err)
+		}
+		return nil
+
+	default:
+		if !util.CgroupSystem(m.System) {
+			log.Errorf("unknown system %v:%v: %v",
+				site, host, m.System)
+			return nil
+		}
+		cg, err := parser.ProcessCgroup([]byte(m.Measurement))
+		if err != nil {
+			log.Errorf("sinkLoop could not process cgroup "+
+				"%v:%v: %v", site, host, err)
+			return nil
+		}
+		if prev.cgroup == nil {
+			prev.cgroup = make(map[string]*parser.Cgroup)
+		}
+		if _, ok := prev.cgroup[m.System]; !ok {
+			prev.cgroup[m.System] = cg
+			prev.started(m)
+			return nil
+		}
+		tvi := prev.interval(m)
+		c, err := parser.CubeCgroup(runID,
+			m.Timestamp.UnixNano(), m.Start.UnixNano(),
+			int64(m.Duration), prev.cgroup[m.System], cg, tvi)
+		if reset, err := p.reset(ctx, state, m, err); reset {
+			prev.cgroup[m.System] = cg
+			prev.started(m)
+			return err
+		}
+		if err != nil {
+			log.Errorf("sinkLoop CubeCgroup %v:%v: %v",
+				site, host, err)
+			return nil
+		}
+		prev.cgroup[m.System] = cg
+		prev.started(m)
+
+		err = p.db.CgroupInsert(ctx, c)
+		if err != nil {
+			return fmt.Errorf("CgroupInsert: %w", err)
+		}
+		return nil
+	}
+}
+
 func (p *PerfCtl) sinkLoop(ctx context.Context, site, host uint64, address string) error {
 	log.Tracef("sinkLoop %v:%v", site, host)
 	defer log.Tracef("sinkLoop exit %v:%v", site, host)
@@ -776,9 +1485,23 @@ func (p *PerfCtl) sinkLoop(ctx context.Context, site, host uint64, address strin
 	// Setup out of band handler.
 	go p.oobHandler(s)
 
+	// Resume after the last durably stored measurement.
+	ds, err := p.loadDelivery(site, host)
+	if err != nil {
+		return terminalError{err: err}
+	}
+	resume := make(map[string]uint64, len(ds.Sequences))
+	for k, v := range ds.Sequences {
+		resume[k] = v + 1
+	}
+
 	// Register sinkLoop.
 	_, err = p.sendAndWait(ctx, s, types.PCCommand{
 		Cmd: types.PCRegisterSinkCmd,
+		Payload: types.PCRegisterSink{
+			Name:   p.cfg.SinkName,
+			Resume: resume,
+		},
 	})
 	if err != nil {
 		log.Errorf("sinkLoop sendAndWait connect %v:%v: %v",
@@ -786,49 +1509,25 @@ func (p *PerfCtl) sinkLoop(ctx context.Context, site, host uint64, address strin
 		return terminalError{err: err}
 	}
 
-	// Create nicCache that holds duplex and speed.
-	// XXX this is incorrect; this overwrites nics from different hosts
-	nicCache := make(map[string]parser.NIC)
-	cacheFilled := false
-	fillCache := func(n parser.NetDev) error {
-		if cacheFilled {
-			return nil
-		}
-		nics := make([]string, 0, len(n))
-		for k := range n {
-			if _, ok := nicCache[k]; ok {
-				continue
-			}
-			if k == "lo" {
-				// lo is invalid so insert zero value
-				nicCache[k] = parser.NIC{}
-				continue
-			}
-			nics = append(nics, k)
-		}
-
-		// Get specifics
-		reply, err := p.getNetDevices(ctx, s, nics)
-		if err != nil {
-			return err
-		}
-
-		// Cache values
-		for k := range reply {
-			nicCache[nics[k]] = reply[k]
-		}
-		cacheFilled = true
+	// Periodically acknowledge stored measurements. The final ack must go
+	// out before the session is unregistered.
+	a := newAcker(site, host, s, ds)
+	ackCtx, ackCancel := context.WithCancel(ctx)
+	go p.ackLoop(ackCtx, a)
+	defer func() {
+		ackCancel()
+		<-a.doneC
+	}()
 
-		return nil
-	}
+	// Every collection has its own run, it is started and stopped by the
+	// collector.
+	runs := make(map[string]*hostState) // Keyed by collection
 
-	runID := uint64(0)
-	var (
-		previousStat *parser.Stat
-		previousNet  parser.NetDev
-		previousDisk []parser.Diskstats
-	)
 	// We are in sinkLoop mode. Register sinkLoop and process measurements.
+	sequences := make(map[string]uint64, len(ds.Sequences))
+	for k, v := range ds.Sequences {
+		sequences[k] = v
+	}
 	dec := gob.NewDecoder(s.channel)
 	for {
 		var m types.PCCollection
@@ -838,142 +1537,33 @@ func (p *PerfCtl) sinkLoop(ctx context.Context, site, host uint64, address strin
 				site, host, err)
 		}
 
-		// XXX consider reading more than one measurement at a time and
-		// batch the writes.
-
-		if p.cfg.Journal {
-			log.Tracef("sinkLoop journal %v:%v: %v",
-				site, host, m.System)
-			err := p.journal(site, host, runID, m)
-			if err != nil {
-				return fmt.Errorf("sinkLoop journal %v:%v: %v",
-					site, host, err)
-			}
-			continue
+		// Older collectors only have the default collection.
+		if m.Collection == "" {
+			m.Collection = types.PCDefaultCollection
 		}
 
-		// Post process
-		switch m.System {
-		case "/proc/stat":
-			s, err := parser.ProcessStat([]byte(m.Measurement))
-			if err != nil {
-				log.Errorf("sinkLoop could not process stat "+
-					"%v:%v: %v", site, host, err)
-				continue
-			}
-			if previousStat == nil {
-				previousStat = &s
-				continue
-			}
-			cs, err := parser.CubeStat(runID, m.Timestamp.Unix(),
-				m.Start.Unix(), int64(m.Duration), previousStat,
-				&s)
-			if err != nil {
-				log.Errorf("sinkLoop CubeStat %v:%v: %v",
-					site, host, err)
-				continue
-			}
-			previousStat = &s
-
-			err = p.db.StatInsert(ctx, cs)
-			if err != nil {
-				log.Errorf("sinkLoop CubeStat insert %v:%v: %v",
-					site, host, err)
-			}
-			continue
-
-		case "/proc/meminfo":
-			s, err := parser.ProcessMeminfo([]byte(m.Measurement))
-			if err != nil {
-				log.Errorf("sinkLoop could not process "+
-					"meminfo %v:%v: %v", site, host, err)
-				continue
-			}
-			mi, err := parser.CubeMeminfo(runID, m.Timestamp.Unix(),
-				m.Start.Unix(), int64(m.Duration), &s)
-			if err != nil {
-				log.Errorf("sinkLoop CubeMeminfo %v:%v: %v",
-					site, host, err)
-				continue
-			}
-
-			err = p.db.MeminfoInsert(ctx, mi)
-			if err != nil {
-				log.Errorf("sinkLoop MeminfoInsert insert "+
-					"%v:%v: %v", site, host, err)
-			}
-			continue
-
-		case "/proc/net/dev":
-			n, err := parser.ProcessNetDev([]byte(m.Measurement))
-			if err != nil {
-				log.Errorf("sinkLoop could not process netdev "+
-					"%v:%v: %v", site, host, err)
-				continue
-			}
-
-			// See if we need to cache NIC details
-			err = fillCache(n)
-			if err != nil {
-				log.Errorf("sinkLoop could not process "+
-					"fillCache %v:%v: %v", site, host, err)
-				continue
-			}
-
-			if previousNet == nil {
-				previousNet = n
+		// Older collectors do not send sequence numbers.
+		collection := m.Collection
+		if m.Sequence != 0 {
+			last := sequences[collection]
+			if m.Sequence <= last {
+				log.Debugf("sinkLoop duplicate %v:%v: %v %v",
+					site, host, collection, m.Sequence)
 				continue
 			}
-			tvi := uint64(m.Frequency.Seconds()) * parser.UserHZ
-			nd, err := parser.CubeNetDev(site, host, runID, m.Timestamp.Unix(),
-				m.Start.Unix(), int64(m.Duration),
-				previousNet, n, tvi, nicCache)
-			if err != nil {
-				log.Errorf("sigkLoop CubeNetDev %v:%v: %v",
-					site, host, err)
-				continue
-			}
-			previousNet = n
-
-			err = p.db.NetDevInsert(ctx, nd)
-			if err != nil {
-				log.Errorf("sinkLoop NetDevInsert insert "+
-					"%v:%v: %v", site, host, err)
-			}
-			continue
-
-		case "/proc/diskstats":
-			d, err := parser.ProcessDiskstats([]byte(m.Measurement))
-			if err != nil {
-				log.Errorf("sinkLoop could not process "+
-					"diskstats %v:%v: %v", site, host, err)
-				continue
-			}
-			if previousDisk == nil {
-				previousDisk = d
-				continue
+			if last != 0 && m.Sequence != last+1 {
+				log.Warnf("sinkLoop gap %v:%v: %v measurements "+
+					"%v-%v are missing", site, host,
+					collection, last+1, m.Sequence-1)
 			}
-			tvi := uint64(m.Frequency.Seconds()) * parser.UserHZ
-			ds, err := parser.CubeDiskstats(runID,
-				m.Timestamp.Unix(), m.Start.Unix(),
-				int64(m.Duration), previousDisk, d, tvi)
-			if err != nil {
-				log.Errorf("sigkLoop CubeDiskstats %v:%v: %v",
-					site, host, err)
-				continue
-			}
-			previousDisk = d
-
-			err = p.db.DiskstatInsert(ctx, ds)
-			if err != nil {
-				log.Errorf("sinkLoop DiskstatInsert insert "+
-					"%v:%v: %v", site, host, err)
-			}
-			continue
+			sequences[collection] = m.Sequence
+		}
 
-		default:
-			log.Errorf("unknown system %v:%v: %v",
-				site, host, m.System)
+		// Measurements that were not stored are retransmitted after
+		// the reconnect, they must not be acknowledged.
+		err = p.sinkMeasurement(ctx, s, a, runs, &m)
+		if err != nil {
+			return fmt.Errorf("sinkLoop %v:%v: %v", site, host, err)
 		}
 	}
 }
@@ -2,12 +2,20 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/businessperformancetuning/perfcollector/cmd/perfprocessord/journal"
+	"github.com/businessperformancetuning/perfcollector/database"
+	"github.com/businessperformancetuning/perfcollector/parser"
+	"github.com/businessperformancetuning/perfcollector/types"
 )
 
 var j = []byte(`
@@ -45,3 +53,398 @@ func TestJSONEncode(t *testing.T) {
 		t.Errorf("expected 8 entries, got %d", entry)
 	}
 }
+
+func TestDeliveryState(t *testing.T) {
+	dir, err := os.MkdirTemp("", "delivery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := &PerfCtl{cfg: &config{DataDir: dir}}
+
+	// Unknown hosts start at 0.
+	ds, err := p.loadDelivery(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ds.Sequences) != 0 {
+		t.Fatalf("expected no sequences, got %v", ds.Sequences)
+	}
+
+	err = p.saveDelivery(1, 2, &deliveryState{
+		Sequences: map[string]uint64{"default": 42, "fast": 7},
+		Runs: map[string]deliveryRun{
+			"fast": {ID: 3, Run: types.PCRun{Name: "fast-run"}},
+		},
+		NextRun: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err = p.loadDelivery(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.Sequences["default"] != 42 || ds.Sequences["fast"] != 7 {
+		t.Fatalf("unexpected sequences: %v", ds.Sequences)
+	}
+	if r := ds.Runs["fast"]; r.ID != 3 || r.Run.Name != "fast-run" ||
+		ds.NextRun != 4 {
+		t.Fatalf("unexpected runs: %v %v", ds.Runs, ds.NextRun)
+	}
+
+	// Hosts do not share state.
+	ds, err = p.loadDelivery(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ds.Sequences) != 0 {
+		t.Fatalf("expected no sequences, got %v", ds.Sequences)
+	}
+}
+
+func TestHostState(t *testing.T) {
+	h1 := newHostState(1, 2, 0)
+	h2 := newHostState(1, 3, 0)
+	if h1.collection("default") != h1.collection("default") ||
+		h1.collection("default") == h1.collection("fast") {
+		t.Fatal("unexpected collections")
+	}
+
+	n := parser.NetDev{
+		"lo":   {Name: "lo"},
+		"eth0": {Name: "eth0"},
+	}
+	stale := h1.staleNICs(n)
+	if len(stale) != 1 || stale[0] != "eth0" {
+		t.Fatalf("unexpected stale nics: %v", stale)
+	}
+	h1.setNIC("eth0", parser.NIC{Duplex: "full", Speed: 1000}, 0)
+	h2.setNIC("eth0", parser.NIC{Duplex: "full", Speed: 10}, 0)
+	if nic := h1.nics[parser.NICKey(1, 2, 0, "eth0")]; nic.Speed != 1000 {
+		t.Fatalf("unexpected nic: %+v", nic)
+	}
+	if len(h1.nics) != 2 || len(h2.nics) != 1 {
+		t.Fatalf("hosts share nics: %v %v", h1.nics, h2.nics)
+	}
+	if stale := h1.staleNICs(n); len(stale) != 0 {
+		t.Fatalf("unexpected stale nics: %v", stale)
+	}
+
+	// Link changes and new interfaces are inventoried again, removed
+	// interfaces are forgotten.
+	n = parser.NetDev{
+		"eth0":  {Name: "eth0", TxCarrier: 1},
+		"veth1": {Name: "veth1"},
+	}
+	stale = h1.staleNICs(n)
+	if len(stale) != 2 {
+		t.Fatalf("unexpected stale nics: %v", stale)
+	}
+	if _, ok := h1.nics[parser.NICKey(1, 2, 0, "lo")]; ok {
+		t.Fatal("lo not forgotten")
+	}
+
+	// The inventory is journaled with the timing of its measurement.
+	m := &types.PCCollection{
+		Collection: "default",
+		Timestamp:  time.Unix(1700000001, 0),
+		Start:      time.Unix(1700000000, 0),
+		Frequency:  5 * time.Second,
+		System:     "/proc/net/dev",
+	}
+	for _, v := range nicMeasurements(m, "eth0", parser.NIC{}) {
+		if !v.Start.Equal(m.Start) || v.Frequency != m.Frequency ||
+			!v.Timestamp.Equal(m.Timestamp) {
+			t.Fatalf("unexpected measurement: %+v", v)
+		}
+	}
+}
+
+func TestRuns(t *testing.T) {
+	dir, err := os.MkdirTemp("", "runs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	aead, err := journal.CreateAEAD(1, "license", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &PerfCtl{cfg: &config{
+		DataDir:         dir,
+		Journal:         true,
+		journalFilename: filepath.Join(dir, "journal"),
+		aead:            aead,
+	}}
+	ctx := context.Background()
+	ds, err := p.loadDelivery(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newAcker(1, 2, nil, ds)
+	runs := make(map[string]*hostState)
+
+	// Measurements of collectors that do not announce runs start a run
+	// that is named after the collection.
+	start := time.Unix(1700000000, 0)
+	m := types.PCCollection{
+		Collection: "default",
+		Timestamp:  start,
+		Frequency:  time.Second,
+		System:     "/proc/stat",
+	}
+	h, err := p.run(ctx, a, runs, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.run != 1 || h.info.Name != "default" {
+		t.Fatalf("unexpected run: %v %+v", h.run, h.info)
+	}
+
+	// An announced run stops the open run.
+	r := types.PCRun{
+		Name:  "baseline",
+		Start: start.Add(time.Minute),
+		StartCollection: types.PCStartCollection{
+			Name:  "default",
+			Label: "ticket-1",
+		},
+	}
+	rm, err := types.EncodeRun("default", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.handleRun(ctx, a, runs, rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err = p.run(ctx, a, runs, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.run != 2 || h.info.Name != "baseline" {
+		t.Fatalf("unexpected run: %v %+v", h.run, h.info)
+	}
+
+	// A run that is delivered again continues the open run, also after
+	// the sink restarted.
+	err = p.handleRun(ctx, a, make(map[string]*hostState), rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dr, ok := a.openRun("default"); !ok || dr.ID != 2 {
+		t.Fatalf("unexpected open run: %v %+v", ok, dr)
+	}
+
+	r.Stop = start.Add(time.Hour)
+	rm, err = types.EncodeRun("default", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = p.handleRun(ctx, a, runs, rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.openRun("default"); ok || len(runs) != 0 {
+		t.Fatal("run not stopped")
+	}
+
+	// Every start and stop is journaled.
+	f, err := os.Open(p.cfg.journalFilename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want := []struct {
+		run  uint64
+		name string
+		stop bool
+	}{
+		{1, "default", false},
+		{1, "default", true},
+		{2, "baseline", false},
+		{2, "baseline", true},
+	}
+	for _, v := range want {
+		wc, err := journal.ReadEncryptedJournalEntry(f, aead)
+		if err != nil {
+			t.Fatal(err)
+		}
+		jr, err := types.DecodeRun(wc.Measurement)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wc.Run != v.run || jr.Name != v.name ||
+			jr.Stop.IsZero() == v.stop {
+			t.Fatalf("unexpected run: %v %+v", wc.Run, jr)
+		}
+	}
+}
+
+// statDB is a database that only stores stat and vmstat records, events and
+// runs. Inserts fail while err is set.
+type statDB struct {
+	database.Database
+
+	err     error
+	stats   int
+	vmstats int
+	events  []database.Event
+}
+
+func (d *statDB) MeasurementsInsert(ctx context.Context, m *database.Measurements) (uint64, error) {
+	return 1, nil
+}
+
+func (d *statDB) StatInsert(ctx context.Context, s []database.Stat) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.stats++
+	return nil
+}
+
+func (d *statDB) VmstatInsert(ctx context.Context, v *database.Vmstat) error {
+	d.vmstats++
+	return nil
+}
+
+func (d *statDB) EventInsert(ctx context.Context, e *database.Event) error {
+	d.events = append(d.events, *e)
+	return nil
+}
+
+const sampleStat = `cpu  %v 0 100 1000 0 0 0 0 0 0
+cpu0 %v 0 100 1000 0 0 0 0 0 0
+ctxt 1000
+btime %v
+processes 100
+procs_running 1
+procs_blocked 0
+`
+
+func TestSinkMeasurementInsertError(t *testing.T) {
+	dir, err := os.MkdirTemp("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := &statDB{}
+	p := &PerfCtl{cfg: &config{DataDir: dir}, db: db}
+	ctx := context.Background()
+	a := newAcker(1, 2, nil, &deliveryState{})
+	runs := make(map[string]*hostState)
+
+	start := time.Unix(1700000000, 0)
+	measurement := func(sequence uint64, user int) *types.PCCollection {
+		return &types.PCCollection{
+			Collection:  "default",
+			Sequence:    sequence,
+			Timestamp:   start.Add(time.Duration(sequence) * time.Second),
+			Start:       start.Add(time.Duration(sequence) * time.Second),
+			Frequency:   time.Second,
+			System:      "/proc/stat",
+			Measurement: fmt.Sprintf(sampleStat, user, user, 1609459200),
+		}
+	}
+
+	// The first measurement is the baseline.
+	err = p.sinkMeasurement(ctx, nil, a, runs, measurement(1, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.stored["default"] != 1 {
+		t.Fatalf("unexpected stored: %v", a.stored)
+	}
+
+	// A failed insert is not acknowledged.
+	db.err = errors.New("connection reset")
+	err = p.sinkMeasurement(ctx, nil, a, runs, measurement(2, 200))
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if a.stored["default"] != 1 || db.stats != 0 {
+		t.Fatalf("unexpected stored: %v %v", a.stored, db.stats)
+	}
+
+	db.err = nil
+	err = p.sinkMeasurement(ctx, nil, a, runs, measurement(3, 300))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.stored["default"] != 3 || db.stats != 1 {
+		t.Fatalf("unexpected stored: %v %v", a.stored, db.stats)
+	}
+
+	// A record that the database rejects is dropped and acknowledged.
+	db.err = fmt.Errorf("%w: value out of range", database.ErrData)
+	err = p.sinkMeasurement(ctx, nil, a, runs, measurement(4, 400))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.stored["default"] != 4 || db.stats != 1 {
+		t.Fatalf("unexpected stored: %v %v", a.stored, db.stats)
+	}
+}
+
+func TestSinkMeasurementReboot(t *testing.T) {
+	dir, err := os.MkdirTemp("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := &statDB{}
+	p := &PerfCtl{cfg: &config{DataDir: dir}, db: db}
+	ctx := context.Background()
+	a := newAcker(1, 2, nil, &deliveryState{})
+	runs := make(map[string]*hostState)
+
+	start := time.Unix(1700000000, 0)
+	var sequence uint64
+	sink := func(system, measurement string) {
+		t.Helper()
+		sequence++
+		err := p.sinkMeasurement(ctx, nil, a, runs, &types.PCCollection{
+			Collection:  "default",
+			Sequence:    sequence,
+			Timestamp:   start.Add(time.Duration(sequence) * time.Second),
+			Start:       start.Add(time.Duration(sequence) * time.Second),
+			Frequency:   time.Second,
+			System:      system,
+			Measurement: measurement,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tick := func(user, pgfault int, btime int64) {
+		t.Helper()
+		sink("/proc/stat", fmt.Sprintf(sampleStat, user, user, btime))
+		sink("/proc/vmstat", fmt.Sprintf("pgfault %v\n", pgfault))
+	}
+
+	tick(100, 1000, 1609459200)
+	tick(200, 2000, 1609459200)
+	if db.stats != 1 || db.vmstats != 1 {
+		t.Fatalf("unexpected inserts: %v %v", db.stats, db.vmstats)
+	}
+
+	// The host rebooted, vmstat must not be cubed across the reboot.
+	tick(10, 100, 1609462800)
+	if db.stats != 1 || db.vmstats != 1 {
+		t.Fatalf("unexpected inserts: %v %v", db.stats, db.vmstats)
+	}
+	if len(db.events) != 1 || db.events[0].Type != database.EventReset {
+		t.Fatalf("unexpected events: %+v", db.events)
+	}
+
+	tick(20, 200, 1609462800)
+	if db.stats != 2 || db.vmstats != 2 {
+		t.Fatalf("unexpected inserts: %v %v", db.stats, db.vmstats)
+	}
+}
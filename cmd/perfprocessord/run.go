@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/businessperformancetuning/perfcollector/cmd/perfprocessord/journal"
+	"github.com/businessperformancetuning/perfcollector/database"
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// openRun returns the state of the open run of collection c or nil if the
+// collection has no open run. A run that was open when a previous sinkLoop of
+// the host exited is resumed from the delivery state.
+func (p *PerfCtl) openRun(a *acker, runs map[string]*hostState, c string) *hostState {
+	if h, ok := runs[c]; ok {
+		return h
+	}
+	dr, ok := a.openRun(c)
+	if !ok {
+		return nil
+	}
+	h := newHostState(a.site, a.host, dr.ID)
+	h.info = dr.Run
+	runs[c] = h
+	log.Infof("Run resumed %v:%v %v: %v (%v)", a.site, a.host, c,
+		h.info.Name, h.run)
+	return h
+}
+
+// startRun starts run r of collection c. The run is journaled or inserted
+// into the database depending on how measurements are recorded. An open run
+// of the collection is stopped first; its stop was lost, e.g. because the
+// collector exited. A run that is delivered again, because the sink exited
+// before it was acknowledged, continues the open run.
+func (p *PerfCtl) startRun(ctx context.Context, a *acker, runs map[string]*hostState, c string, r types.PCRun) (*hostState, error) {
+	if h := p.openRun(a, runs, c); h != nil {
+		if h.info.Name == r.Name && h.info.Start.Equal(r.Start) {
+			return h, nil
+		}
+		stop := h.last
+		if stop.IsZero() {
+			stop = r.Start
+		}
+		log.Warnf("Run %v:%v %v: %v was not stopped", a.site, a.host,
+			c, h.info.Name)
+		err := p.stopRun(ctx, a, runs, c, stop)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var id uint64
+	if p.cfg.Journal {
+		id = a.allocRun()
+	} else {
+		params, err := json.Marshal(r.StartCollection)
+		if err != nil {
+			return nil, err
+		}
+		id, err = p.db.MeasurementsInsert(ctx, &database.Measurements{
+			SiteID:      a.site,
+			HostID:      a.host,
+			Name:        r.Name,
+			Collection:  c,
+			Label:       r.StartCollection.Label,
+			Description: r.StartCollection.Description,
+			Parameters:  string(params),
+			Start:       r.Start.UnixNano(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("MeasurementsInsert: %v", err)
+		}
+	}
+
+	h := newHostState(a.site, a.host, id)
+	h.info = r
+	if p.cfg.Journal {
+		err := p.journalRun(h, c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	runs[c] = h
+	a.setRun(c, &deliveryRun{ID: id, Run: r})
+
+	log.Infof("Run started %v:%v %v: %v (%v)", a.site, a.host, c, r.Name,
+		id)
+	return h, nil
+}
+
+// stopRun stops the open run of collection c at stop.
+func (p *PerfCtl) stopRun(ctx context.Context, a *acker, runs map[string]*hostState, c string, stop time.Time) error {
+	h := p.openRun(a, runs, c)
+	if h == nil {
+		log.Debugf("stopRun %v:%v %v: no open run", a.site, a.host, c)
+		return nil
+	}
+
+	h.info.Stop = stop
+	if p.cfg.Journal {
+		err := p.journalRun(h, c)
+		if err != nil {
+			return err
+		}
+	} else {
+		err := p.db.MeasurementsStop(ctx, h.run, stop.UnixNano())
+		if err != nil {
+			return err
+		}
+	}
+	delete(runs, c)
+	a.setRun(c, nil)
+
+	log.Infof("Run stopped %v:%v %v: %v (%v)", a.site, a.host, c,
+		h.info.Name, h.run)
+	return nil
+}
+
+// handleRun starts or stops a run, see types.PCRun, as instructed by a
+// PCRunSystem measurement of the collector.
+func (p *PerfCtl) handleRun(ctx context.Context, a *acker, runs map[string]*hostState, m *types.PCCollection) error {
+	r, err := types.DecodeRun(m)
+	if err != nil {
+		return err
+	}
+	if r.Stop.IsZero() {
+		_, err = p.startRun(ctx, a, runs, m.Collection, *r)
+		return err
+	}
+	return p.stopRun(ctx, a, runs, m.Collection, r.Stop)
+}
+
+// run returns the state of the run that measurement m belongs to. Collectors
+// that predate runs do not announce them, in that case a run that is named
+// after the collection is started.
+func (p *PerfCtl) run(ctx context.Context, a *acker, runs map[string]*hostState, m *types.PCCollection) (*hostState, error) {
+	h := p.openRun(a, runs, m.Collection)
+	if h == nil {
+		var err error
+		h, err = p.startRun(ctx, a, runs, m.Collection, types.PCRun{
+			Name:  m.Collection,
+			Start: m.Timestamp,
+			StartCollection: types.PCStartCollection{
+				Name:      m.Collection,
+				Frequency: m.Frequency,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	h.last = m.Timestamp
+	return h, nil
+}
+
+// journalRun journals the run of h so that it can be selected by name when
+// the journal is processed. It is journaled when it starts and again when it
+// stops.
+func (p *PerfCtl) journalRun(h *hostState, c string) error {
+	m, err := types.EncodeRun(c, h.info)
+	if err != nil {
+		return err
+	}
+	return journal.Journal(p.cfg.journalFilename, p.cfg.aead,
+		journal.WrapPCCollection{
+			Site:        h.site,
+			Host:        h.host,
+			Run:         h.run,
+			Measurement: m,
+		})
+}
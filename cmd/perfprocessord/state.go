@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/businessperformancetuning/perfcollector/cmd/perfprocessord/journal"
+	"github.com/businessperformancetuning/perfcollector/parser"
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// previousSample holds the previous measurements of a collection. Counters
+// are converted to rates by comparing them to the previous measurement.
+type previousSample struct {
+	stat       *parser.Stat
+	vmstat     *parser.Vmstat
+	net        parser.NetDev
+	netsnmp    parser.NetProto
+	netstat    parser.NetProto
+	disk       []parser.Diskstats
+	pidstat    parser.Pidstat
+	cgroup     map[string]*parser.Cgroup     // Keyed by system
+	pressure   map[string]*parser.Pressure   // Keyed by system
+	interrupts map[string]*parser.Interrupts // Keyed by system
+	start      map[string]time.Time          // Start of previous, keyed by system
+}
+
+// interval returns the time interval, in jiffies, between the previous
+// measurement of the system of m and m.
+func (p *previousSample) interval(m *types.PCCollection) uint64 {
+	return parser.Interval(p.start[m.System], m.Start, m.Frequency)
+}
+
+// started records m as the previous measurement of its system.
+func (p *previousSample) started(m *types.PCCollection) {
+	if p.start == nil {
+		p.start = make(map[string]time.Time)
+	}
+	p.start[m.System] = m.Start
+}
+
+// hostState is the state of a single site, host and run that is needed to
+// turn measurements into statistics. It holds the previous measurements of
+// every collection and the inventory of the network interfaces of the host. It is owned by the sinkLoop of the host and used by both the
+// database and the journal path.
+type hostState struct {
+	site uint64
+	host uint64
+	run  uint64
+
+	info types.PCRun // Run as started by the collector
+	last time.Time   // Timestamp of the last measurement of the run
+
+	previous map[string]*previousSample // Keyed by collection
+
+	nics    map[string]parser.NIC // Speed and duplex, keyed by parser.NICKey
+	carrier map[string]uint64     // Carrier losses at inventory, keyed by name
+}
+
+// newHostState returns the state of a site, host and run.
+func newHostState(site, host, run uint64) *hostState {
+	return &hostState{
+		site:     site,
+		host:     host,
+		run:      run,
+		previous: make(map[string]*previousSample),
+		nics:     make(map[string]parser.NIC),
+		carrier:  make(map[string]uint64),
+	}
+}
+
+// collection returns the previous measurements of collection c. Every
+// collection is cubed against its own previous measurements.
+func (h *hostState) collection(c string) *previousSample {
+	prev, ok := h.previous[c]
+	if !ok {
+		prev = &previousSample{}
+		h.previous[c] = prev
+	}
+	return prev
+}
+
+// staleNICs returns, in no particular order, the interfaces of n whose speed
+// and duplex must be inventoried. These are the interfaces that were not seen
+// before and the ones that lost their carrier, i.e. whose link went down and
+// possibly came back at a different speed, since they were inventoried.
+// Interfaces that no longer exist are forgotten.
+func (h *hostState) staleNICs(n parser.NetDev) []string {
+	for k := range h.carrier {
+		if _, ok := n[k]; !ok {
+			delete(h.carrier, k)
+			delete(h.nics, parser.NICKey(h.site, h.host, h.run, k))
+		}
+	}
+
+	var stale []string
+	for k, v := range n {
+		carrier, ok := h.carrier[k]
+		if ok && carrier == v.TxCarrier {
+			continue
+		}
+		if k == "lo" {
+			// lo has no speed so insert zero value.
+			h.setNIC(k, parser.NIC{}, v.TxCarrier)
+			continue
+		}
+		stale = append(stale, k)
+	}
+	return stale
+}
+
+// setNIC records the speed and duplex of interface name and the number of
+// carrier losses at the time they were obtained.
+func (h *hostState) setNIC(name string, nic parser.NIC, carrier uint64) {
+	h.nics[parser.NICKey(h.site, h.host, h.run, name)] = nic
+	h.carrier[name] = carrier
+}
+
+// nicMeasurements returns the speed and duplex of interface name in the
+// format of the /sys/class/net files, so that they can be journaled next to
+// the measurements they apply to.
+func nicMeasurements(m *types.PCCollection, name string, nic parser.NIC) []types.PCCollection {
+	duplex := nic.Duplex
+	if duplex == "" {
+		duplex = "unknown"
+	}
+	return []types.PCCollection{
+		{
+			Collection:  m.Collection,
+			Timestamp:   m.Timestamp,
+			Start:       m.Start,
+			Frequency:   m.Frequency,
+			System:      "/sys/class/net/" + name + "/duplex",
+			Measurement: duplex + "\n",
+		},
+		{
+			Collection:  m.Collection,
+			Timestamp:   m.Timestamp,
+			Start:       m.Start,
+			Frequency:   m.Frequency,
+			System:      "/sys/class/net/" + name + "/speed",
+			Measurement: strconv.FormatUint(nic.Speed, 10) + "\n",
+		},
+	}
+}
+
+// refreshNICs inventories the speed and duplex of the stale interfaces of n,
+// see staleNICs, and returns them as measurements. All stale interfaces are
+// queried in a single round trip. Virtual interfaces and interfaces without a
+// link do not report a speed, which fails the whole query. In that case the
+// interfaces are queried one by one and the ones that fail are recorded with
+// a zero speed.
+func (p *PerfCtl) refreshNICs(ctx context.Context, s *session, h *hostState, m *types.PCCollection, n parser.NetDev) []types.PCCollection {
+	stale := h.staleNICs(n)
+	if len(stale) == 0 {
+		return nil
+	}
+	nics, err := p.getNetDevices(ctx, s, stale)
+	if err != nil {
+		log.Debugf("refreshNICs %v:%v: %v", h.site, h.host, err)
+		nics = make([]parser.NIC, len(stale))
+		for k, name := range stale {
+			reply, err := p.getNetDevices(ctx, s, []string{name})
+			if err != nil {
+				log.Debugf("refreshNICs %v:%v %v: %v", h.site,
+					h.host, name, err)
+				continue
+			}
+			nics[k] = reply[0]
+		}
+	}
+
+	var r []types.PCCollection
+	for k, name := range stale {
+		nic := nics[k]
+		log.Debugf("refreshNICs %v:%v %v: %v %v", h.site, h.host, name,
+			nic.Duplex, nic.Speed)
+		h.setNIC(name, nic, n[name].TxCarrier)
+		r = append(r, nicMeasurements(m, name, nic)...)
+	}
+	return r
+}
+
+// inventory updates the interface inventory of h from m. It returns the speed
+// and duplex of the interfaces that were inventoried as measurements, see
+// refreshNICs.
+func (p *PerfCtl) inventory(ctx context.Context, s *session, h *hostState, m *types.PCCollection) []types.PCCollection {
+	if m.System != "/proc/net/dev" {
+		return nil
+	}
+	n, err := parser.ProcessNetDev([]byte(m.Measurement))
+	if err != nil {
+		// Reported when the measurement is processed.
+		return nil
+	}
+	return p.refreshNICs(ctx, s, h, m, n)
+}
+
+// journalInventory journals the inventory measurements of h, see inventory.
+func (p *PerfCtl) journalInventory(h *hostState, ms []types.PCCollection) error {
+	for k := range ms {
+		err := journal.Journal(p.cfg.journalFilename, p.cfg.aead,
+			journal.WrapPCCollection{
+				Site:        h.site,
+				Host:        h.host,
+				Run:         h.run,
+				Measurement: &ms[k],
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
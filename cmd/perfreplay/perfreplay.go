@@ -23,6 +23,7 @@ import (
 	"github.com/businessperformancetuning/perfcollector/database"
 	"github.com/businessperformancetuning/perfcollector/load"
 	"github.com/businessperformancetuning/perfcollector/parser"
+	"github.com/businessperformancetuning/perfcollector/types"
 	"github.com/businessperformancetuning/perfcollector/util"
 	"github.com/businessperformancetuning/perfcollector/validation"
 	"github.com/dustin/go-humanize"
@@ -94,6 +95,7 @@ type config struct {
 	Site       uint64
 	Host       uint64
 	Run        uint64
+	RunName    string
 
 	// Playback speed control
 	Speed float64 // Playback speed multiplier (1.0 = realtime, 2.0 = 2x faster, 0.5 = half speed)
@@ -135,6 +137,8 @@ Flags:
 	Host ID that is being replayed.
   --run unsigned integer
 	Run ID that is being replayed.
+  --runname string
+	Name of the run that is being replayed, overrides --run.
   --input string
 	Input file, e.g. ~/journal
   --output string
@@ -179,6 +183,7 @@ func (c *config) FlagSet() *flag.FlagSet {
 	fs.Uint64Var(&c.Site, "siteid", 0, "")
 	fs.Uint64Var(&c.Host, "host", 0, "")
 	fs.Uint64Var(&c.Run, "run", 0, "")
+	fs.StringVar(&c.RunName, "runname", "", "")
 	// Playback speed, scale, and mode flags
 	fs.Float64Var(&c.Speed, "speed", 1.0, "")
 	fs.Float64Var(&c.Scale, "scale", 1.0, "")
@@ -350,6 +355,28 @@ var (
 	previousCache = make(map[string]*journal.WrapPCCollection)
 )
 
+// resetPrevious is called when the /proc/stat measurement cur shows that the
+// host rebooted. No system of the collection may be replayed across the
+// reboot so all previous measurements are dropped and cur is kept.
+func resetPrevious(name string, cur *journal.WrapPCCollection) {
+	prefix := strings.TrimSuffix(name, cur.Measurement.System)
+	for k := range previousCache {
+		if strings.HasPrefix(k, prefix+"/") {
+			delete(previousCache, k)
+		}
+	}
+	previousCache[name] = cur
+}
+
+// logEvents logs the device events of an interval. The devices they refer to,
+// e.g. a veth interface that was removed, are not replayed in the interval.
+func logEvents(events []database.Event) {
+	for _, e := range events {
+		log.Warningf("%v: %v, not replayed in this interval",
+			e.System, e.Description)
+	}
+}
+
 // metricsCollector collects real-time system metrics during replay
 type metricsCollector struct {
 	mu              sync.Mutex
@@ -536,15 +563,11 @@ func parse(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.N
 	// Work around trailing /
 	cur.Measurement.System = strings.TrimRight(cur.Measurement.System, "/")
 
-	// Ignore loadavg for now
-	if cur.Measurement.System == "/proc/loadavg" {
-		return nil, nil
-	}
-
 	// Construct previousCache map key
 	name := strconv.FormatUint(cur.Site, 10) + "_" +
 		strconv.FormatUint(cur.Host, 10) + "_" +
 		strconv.FormatUint(cur.Run, 10) + "_" +
+		cur.Measurement.Collection + "_" +
 		cur.Measurement.System
 	var (
 		prev *journal.WrapPCCollection
@@ -556,6 +579,14 @@ func parse(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.N
 		return nil, nil
 	}
 
+	// Skip measurements that were journaled more than once. A collector
+	// that lost its spool restarts numbering, so check time as well.
+	if cur.Measurement.Sequence != 0 &&
+		cur.Measurement.Sequence <= prev.Measurement.Sequence &&
+		!cur.Measurement.Timestamp.After(prev.Measurement.Timestamp) {
+		return nil, nil
+	}
+
 	var record interface{}
 	switch cur.Measurement.System {
 	case "/proc/stat":
@@ -569,6 +600,13 @@ func parse(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.N
 		}
 		// Ignore database bits
 		record, err = parser.CubeStat(0, 0, 0, 0, &p, &c)
+		if parser.IsReset(err) {
+			// Never replay across a reboot, start over.
+			log.Warningf("%v: dropping interval: %v",
+				cur.Measurement.System, err)
+			resetPrevious(name, cur)
+			return nil, nil
+		}
 		if err != nil {
 			return nil, fmt.Errorf("CubeStat: %v", err)
 		}
@@ -588,6 +626,9 @@ func parse(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.N
 			return nil, fmt.Errorf("CubeMeminfo: %v", err)
 		}
 
+		// Store cur into previousCache
+		previousCache[name] = cur
+
 	case "/proc/net/dev":
 		p, err := parser.ProcessNetDev([]byte(prev.Measurement.Measurement))
 		if err != nil {
@@ -598,11 +639,13 @@ func parse(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.N
 			return nil, fmt.Errorf("ProcessNetDev cur: %v", err)
 		}
 		// Ignore database bits
-		tvi := uint64(cur.Measurement.Frequency.Seconds()) *
-			parser.UserHZ
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
 
 		// XXX there is no nic cache here, fix
-		record, err = parser.CubeNetDev(cur.Site, cur.Host, cur.Run,
+		var events []database.Event
+		record, events, err = parser.CubeNetDev(cur.Site, cur.Host,
+			cur.Run,
 			0, /* timestamp */
 			0, /* start */
 			0, /* duration */
@@ -610,6 +653,33 @@ func parse(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.N
 		if err != nil {
 			return nil, fmt.Errorf("CubeNetDev: %v", err)
 		}
+		logEvents(events)
+
+		// Store cur into previousCache
+		previousCache[name] = cur
+
+	case "/proc/loadavg":
+		c, err := parser.ProcessLoadavg([]byte(cur.Measurement.Measurement))
+		if err != nil {
+			return nil, fmt.Errorf("ProcessLoadavg cur: %v", err)
+		}
+		// The blocked processes are reported by the most recent
+		// /proc/stat measurement of the same collection.
+		var st *parser.Stat
+		statName := strings.TrimSuffix(name, cur.Measurement.System) +
+			"/proc/stat"
+		if ps, ok := previousCache[statName]; ok {
+			s, err := parser.ProcessStat([]byte(ps.Measurement.Measurement))
+			if err != nil {
+				return nil, fmt.Errorf("ProcessStat: %v", err)
+			}
+			st = &s
+		}
+		// Ignore database bits
+		record, err = parser.CubeLoadavg(0, 0, 0, 0, &c, st)
+		if err != nil {
+			return nil, fmt.Errorf("CubeLoadavg: %v", err)
+		}
 
 		// Store cur into previousCache
 		previousCache[name] = cur
@@ -624,13 +694,16 @@ func parse(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.N
 			return nil, fmt.Errorf("ProcessDiskstats cur: %v", err)
 		}
 		// Ignore database bits
-		tvi := uint64(cur.Measurement.Frequency.Seconds()) *
-			parser.UserHZ
+		tvi := parser.Interval(prev.Measurement.Start,
+			cur.Measurement.Start, cur.Measurement.Frequency)
 		// XXX there is no nic cache here, fix
-		record, err = parser.CubeDiskstats(0, 0, 0, 0, p, c, tvi)
+		var events []database.Event
+		record, events, err = parser.CubeDiskstats(0, 0, 0, 0, p, c,
+			tvi)
 		if err != nil {
 			return nil, fmt.Errorf("CubeDiskstats: %v", err)
 		}
+		logEvents(events)
 
 		// Store cur into previousCache
 		previousCache[name] = cur
@@ -642,9 +715,9 @@ func parse(cfg *config, cur *journal.WrapPCCollection, cache map[string]parser.N
 }
 
 var (
-	reDuplex = regexp.MustCompile("/sys/class/net/[[:alnum:]]*/duplex")
-	reSpeed  = regexp.MustCompile("/sys/class/net/[[:alnum:]]*/speed")
-	reNIC    = regexp.MustCompile("(/[[:alnum:]]+){4}")
+	reDuplex = regexp.MustCompile("/sys/class/net/[^/]+/duplex")
+	reSpeed  = regexp.MustCompile("/sys/class/net/[^/]+/speed")
+	reNIC    = regexp.MustCompile("(/[^/]+){4}")
 )
 
 func createNetCache(cache map[string]string) (map[string]parser.NIC, error) {
@@ -688,7 +761,7 @@ func createNetCache(cache map[string]string) (map[string]parser.NIC, error) {
 			continue
 		}
 
-		key := fmt.Sprintf("%v %v %v %v", site, host, run, nic)
+		key := parser.NICKey(site, host, run, nic)
 		cc, ok := r[key]
 		if !ok {
 			cc = parser.NIC{}
@@ -771,6 +844,30 @@ func giveOrTake(x, y, percent uint64) bool {
 	return false
 }
 
+// canSwap returns true if this machine has swap space.
+func canSwap() bool {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return false
+	}
+	mi, err := parser.ProcessMeminfo(data)
+	if err != nil {
+		return false
+	}
+	return mi.SwapTotal != 0
+}
+
+// memoryTarget returns the memory, in KiB, that is allocated to replay m.
+// Memory that was swapped out was in use as well. It is added when swap is
+// true so that the replay reproduces the swap pressure of the measured
+// machine.
+func memoryTarget(m *database.Meminfo, swap bool) uint64 {
+	if !swap {
+		return m.MemUsed
+	}
+	return m.MemUsed + m.SwapUsed
+}
+
 func workerMem(ctx context.Context, wg *sync.WaitGroup, ready chan<- struct{}, c chan *database.Meminfo) {
 	defer wg.Done()
 
@@ -783,6 +880,11 @@ func workerMem(ctx context.Context, wg *sync.WaitGroup, ready chan<- struct{}, c
 		err            error
 	)
 
+	// Allocating swapped out memory on a machine without swap ends with
+	// the OOM killer so only replay swap usage when we can swap.
+	swap := canSwap()
+	warned := false
+
 	// Signal that worker is ready to receive work
 	close(ready)
 
@@ -795,14 +897,21 @@ func workerMem(ctx context.Context, wg *sync.WaitGroup, ready chan<- struct{}, c
 				log.Errorf("workerMem channel exited")
 				return
 			}
+			if m.SwapUsed != 0 && !swap && !warned {
+				log.Warningf("workerMem: swap usage not replayed, " +
+					"no swap available")
+				warned = true
+			}
+			target := memoryTarget(m, swap)
+
 			// Only reallocate memory if the size differs by >10%.
 			// Painting memory is very expensive so try to avoid it
 			// in order to not contaminate CPU usage.
-			if giveOrTake(m.MemUsed, memorySize, 10) {
+			if giveOrTake(target, memorySize, 10) {
 				continue
 			}
 
-			log.Tracef("reallocate MemUsed: %v\n", m.MemUsed*1024)
+			log.Tracef("reallocate MemUsed: %v\n", target*1024)
 			// Free prior memory
 			if memoryLocation != nil {
 				err = munmap(memoryLocation)
@@ -814,13 +923,13 @@ func workerMem(ctx context.Context, wg *sync.WaitGroup, ready chan<- struct{}, c
 			}
 
 			// Allocate new size
-			memoryLocation, err = mmap(m.MemUsed * 1024)
+			memoryLocation, err = mmap(target * 1024)
 			if err != nil {
 				// XXX should we abort?
 				log.Errorf("mmap: %v", err)
 				continue
 			}
-			memorySize = m.MemUsed
+			memorySize = target
 		}
 	}
 }
@@ -828,12 +937,18 @@ func workerMem(ctx context.Context, wg *sync.WaitGroup, ready chan<- struct{}, c
 var (
 	td          = make([]uint, 101) // Training data
 	numCores    = -1
-	frequency   = -1
+	frequency   time.Duration
 	scaleFactor = 1.0 // Workload intensity scale factor
 
 	dm = make(map[string]string) // Disk mapper data
 )
 
+// frequencyUnits returns the work units of one frequency interval given the
+// work units per second.
+func frequencyUnits(units int) int {
+	return int(float64(units) * frequency.Seconds())
+}
+
 func workerStat(ctx context.Context, wg *sync.WaitGroup, ready chan<- struct{}, c chan []database.Stat) {
 	defer wg.Done()
 
@@ -891,9 +1006,9 @@ func workerStat(ctx context.Context, wg *sync.WaitGroup, ready chan<- struct{},
 			if u == 0 {
 				// Only a bit of work to be done
 				log.Tracef("workerStat busy short: %v units: %v",
-					busy, units*frequency)
+					busy, frequencyUnits(units))
 				go func() {
-					d := load.UserWork(units * frequency)
+					d := load.UserWork(frequencyUnits(units))
 					log.Tracef("workerStat duration short %v",
 						d)
 				}()
@@ -902,11 +1017,11 @@ func workerStat(ctx context.Context, wg *sync.WaitGroup, ready chan<- struct{},
 			units = u
 
 			log.Tracef("workerStat busy: %v units: %v", busy,
-				units*frequency)
+				frequencyUnits(units))
 			for i := 0; i < numCores; i++ {
 				x := i
 				go func(int) {
-					d := load.UserWork(units * frequency)
+					d := load.UserWork(frequencyUnits(units))
 					log.Tracef("workerStat duration %v", d)
 				}(x)
 			}
@@ -1015,7 +1130,7 @@ func loadDisk(ctx context.Context, mode modeRW, ds database.Diskstat) {
 		ds.Name, ms, ios, size, b, msg)
 
 	// Hit it
-	timeout := time.Duration(frequency) * time.Second
+	timeout := frequency
 	var (
 		err    error
 		d      time.Duration
@@ -1259,7 +1374,11 @@ func _main() error {
 	}
 	log.Infof("Site ID: %v", cfg.Site)
 	log.Infof("Host ID: %v", cfg.Host)
-	log.Infof("Run ID : %v", cfg.Run)
+	if cfg.RunName != "" {
+		log.Infof("Run    : %v", cfg.RunName)
+	} else {
+		log.Infof("Run ID : %v", cfg.Run)
+	}
 	log.Infof("Replay Mode: %v", cfg.ReplayMode)
 	if cfg.Speed != 1.0 {
 		log.Infof("Playback Speed: %.2fx", cfg.Speed)
@@ -1315,6 +1434,7 @@ func _main() error {
 		jd = json.NewDecoder(f)
 	}
 	seen := make(map[string]struct{}, 16)
+	resolved := cfg.RunName == ""
 	for {
 		var (
 			wc  *journal.WrapPCCollection
@@ -1340,7 +1460,25 @@ func _main() error {
 			wc = &wrap
 		}
 
-		if wc.Site != cfg.Site || wc.Host != cfg.Host ||
+		// Runs are journaled ahead of their measurements, use them to
+		// find the run that is selected by name.
+		if wc.Measurement.System == types.PCRunSystem {
+			if resolved || wc.Site != cfg.Site || wc.Host != cfg.Host {
+				continue
+			}
+			r, err := types.DecodeRun(wc.Measurement)
+			if err != nil {
+				return err
+			}
+			if r.Name == cfg.RunName {
+				cfg.Run = wc.Run
+				resolved = true
+				log.Infof("Run ID : %v", cfg.Run)
+			}
+			continue
+		}
+
+		if !resolved || wc.Site != cfg.Site || wc.Host != cfg.Host ||
 			wc.Run != cfg.Run {
 			continue
 		}
@@ -1352,8 +1490,11 @@ func _main() error {
 		seen[wc.Measurement.System] = struct{}{}
 
 	}
-	frequency = int(freq / time.Second) // Store frequency
-	scaleFactor = cfg.Scale             // Store scale factor for workers
+	if !resolved {
+		return fmt.Errorf("run not found: %v", cfg.RunName)
+	}
+	frequency = freq        // Store frequency
+	scaleFactor = cfg.Scale // Store scale factor for workers
 
 	// Apply speed multiplier to frequency
 	// Speed > 1.0 means faster playback (shorter intervals)
@@ -1493,7 +1634,8 @@ func _main() error {
 		}
 
 		if wc.Site != cfg.Site || wc.Host != cfg.Host ||
-			wc.Run != cfg.Run {
+			wc.Run != cfg.Run ||
+			wc.Measurement.System == types.PCRunSystem {
 			continue
 		}
 
@@ -1562,6 +1704,9 @@ func _main() error {
 		case []database.NetDev:
 			// TODO: Integrate network replay (GAP-005)
 
+		case *database.Loadavg:
+			// The run queue is a result of the replayed load.
+
 		case []database.Diskstat:
 			diskRecords++
 			// Record target disk I/O for validation
@@ -0,0 +1,91 @@
+package database
+
+// CGROUP %usr %system %CPU %throttled throttled/s kbmem kbanon kbfile fault/s majflt/s rkB/s wkB/s r/s w/s %cpusome %cpufull
+type Cgroup struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	Name        string  // CGROUP
+	UserT       float64 // %usr
+	System      float64 // %system
+	CPU         float64 // %CPU
+	Throttled   float64 // %throttled
+	NrThrottled float64 // throttled/s
+	MemCurrent  uint64  // kbmem
+	MemAnon     uint64  // kbanon
+	MemFile     uint64  // kbfile
+	PgFault     float64 // fault/s
+	PgMajFault  float64 // majflt/s
+	RKBytes     float64 // rkB/s
+	WKBytes     float64 // wkB/s
+	Rios        float64 // r/s
+	Wios        float64 // w/s
+	CPUSome     float64 // %cpusome
+	CPUFull     float64 // %cpufull
+}
+
+// SQL queries for cgroup table.
+var (
+	InsertCgroup = `
+INSERT INTO cgroup (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	name,
+	usert,
+	system,
+	cpu,
+	throttled,
+	nrthrottled,
+	memcurrent,
+	memanon,
+	memfile,
+	pgfault,
+	pgmajfault,
+	rkbytes,
+	wkbytes,
+	rios,
+	wios,
+	cpusome,
+	cpufull
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:name,
+	:usert,
+	:system,
+	:cpu,
+	:throttled,
+	:nrthrottled,
+	:memcurrent,
+	:memanon,
+	:memfile,
+	:pgfault,
+	:pgmajfault,
+	:rkbytes,
+	:wkbytes,
+	:rios,
+	:wios,
+	:cpusome,
+	:cpufull
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectCgroupByRunID = `
+SELECT runid, timestamp, start, duration, name, usert, system, cpu, throttled,
+       nrthrottled, memcurrent, memanon, memfile, pgfault, pgmajfault,
+       rkbytes, wkbytes, rios, wios, cpusome, cpufull
+FROM cgroup
+WHERE runid = $1
+ORDER BY timestamp, name;
+`
+)
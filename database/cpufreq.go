@@ -0,0 +1,45 @@
+package database
+
+// CPU MHz. The unique key is RunID, Timestamp and CPU. CPU is -1 for the
+// average of all CPUs.
+type CPUFreq struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	CPU int     // CPU
+	MHz float64 // MHz
+}
+
+// SQL queries for cpufreq table.
+var (
+	InsertCPUFreq = `
+INSERT INTO cpufreq (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	cpu,
+	mhz
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:cpu,
+	:mhz
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectCPUFreqByRunID = `
+SELECT runid, timestamp, start, duration, cpu, mhz
+FROM cpufreq
+WHERE runid = $1
+ORDER BY timestamp, cpu;
+`
+)
@@ -2,9 +2,16 @@ package database
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ErrData is wrapped by the errors of the insert functions when the database
+// rejected the record itself, e.g. because a value is out of range. Inserting
+// the same record again fails the same way. Records that were inserted before
+// are silently skipped, inserts are idempotent.
+var ErrData = errors.New("record rejected")
+
 type Database interface {
 	Create() error // Create schema. Database is NOT Opened!
 	Open() error   // Open database connection and create+upgrade schema
@@ -13,23 +20,54 @@ type Database interface {
 	// Insert measurement and return fresh run id
 	MeasurementsInsert(context.Context, *Measurements) (uint64, error)
 
-	StatInsert(context.Context, []Stat) error         // Insert stat record.
-	MeminfoInsert(context.Context, *Meminfo) error    // Insert meminfo record.
-	NetDevInsert(context.Context, []NetDev) error     // Insert netdev record.
-	DiskstatInsert(context.Context, []Diskstat) error // Insert diskstat record.
+	// Record the end of a run in UNIX nanoseconds
+	MeasurementsStop(ctx context.Context, runID uint64, stop int64) error
+
+	StatInsert(context.Context, []Stat) error            // Insert stat record.
+	MeminfoInsert(context.Context, *Meminfo) error       // Insert meminfo record.
+	NetDevInsert(context.Context, []NetDev) error        // Insert netdev record.
+	DiskstatInsert(context.Context, []Diskstat) error    // Insert diskstat record.
+	PidstatInsert(context.Context, []Pidstat) error      // Insert pidstat record.
+	CgroupInsert(context.Context, *Cgroup) error         // Insert cgroup record.
+	PressureInsert(context.Context, *Pressure) error     // Insert pressure record.
+	VmstatInsert(context.Context, *Vmstat) error         // Insert vmstat record.
+	LoadavgInsert(context.Context, *Loadavg) error       // Insert loadavg record.
+	NetSNMPInsert(context.Context, *NetSNMP) error       // Insert netsnmp record.
+	NetstatInsert(context.Context, *Netstat) error       // Insert netstat record.
+	InterruptsInsert(context.Context, []Interrupt) error // Insert interrupts record.
+	StatfsInsert(context.Context, []Statfs) error        // Insert statfs record.
+	CPUFreqInsert(context.Context, []CPUFreq) error      // Insert cpufreq record.
+	NodeInsert(context.Context, []Node) error            // Insert node record.
+	EventInsert(context.Context, *Event) error           // Insert event record.
 
 	// Query methods for data retrieval
 	StatSelect(ctx context.Context, runID uint64) ([]Stat, error)                // Get stat records for a run
+	StatSelectCPU(ctx context.Context, runID uint64, cpu int) ([]Stat, error)    // Get stat records of a single CPU for a run
 	MeminfoSelect(ctx context.Context, runID uint64) ([]Meminfo, error)          // Get meminfo records for a run
 	NetDevSelect(ctx context.Context, runID uint64) ([]NetDev, error)            // Get netdev records for a run
 	DiskstatSelect(ctx context.Context, runID uint64) ([]Diskstat, error)        // Get diskstat records for a run
+	PidstatSelect(ctx context.Context, runID uint64) ([]Pidstat, error)          // Get pidstat records for a run
+	CgroupSelect(ctx context.Context, runID uint64) ([]Cgroup, error)            // Get cgroup records for a run
+	PressureSelect(ctx context.Context, runID uint64) ([]Pressure, error)        // Get pressure records for a run
+	VmstatSelect(ctx context.Context, runID uint64) ([]Vmstat, error)            // Get vmstat records for a run
+	LoadavgSelect(ctx context.Context, runID uint64) ([]Loadavg, error)          // Get loadavg records for a run
+	NetSNMPSelect(ctx context.Context, runID uint64) ([]NetSNMP, error)          // Get netsnmp records for a run
+	NetstatSelect(ctx context.Context, runID uint64) ([]Netstat, error)          // Get netstat records for a run
+	InterruptsSelect(ctx context.Context, runID uint64) ([]Interrupt, error)     // Get interrupts records for a run
+	StatfsSelect(ctx context.Context, runID uint64) ([]Statfs, error)            // Get statfs records for a run
+	CPUFreqSelect(ctx context.Context, runID uint64) ([]CPUFreq, error)          // Get cpufreq records for a run
+	NodeSelect(ctx context.Context, runID uint64) ([]Node, error)                // Get node records for a run
+	EventSelect(ctx context.Context, runID uint64) ([]Event, error)              // Get event records for a run
 	MeasurementsSelect(ctx context.Context, runID uint64) (*Measurements, error) // Get measurements by run ID
 	ListRuns(ctx context.Context) ([]Measurements, error)                        // List all runs
+
+	// Get measurements of all runs with the provided name
+	MeasurementsSelectByName(ctx context.Context, name string) ([]Measurements, error)
 }
 
 const (
 	Name    = "performancedata"
-	Version = 1
+	Version = 17
 )
 
 var (
@@ -39,32 +77,70 @@ var (
 )
 
 // Measurements is a lookup table that joins site, host and run identifiers so
-// that the measurements can be reconstituted.
+// that the measurements can be reconstituted. Every row is a run, i.e. a
+// collection of a host from the moment it started until it stopped.
 type Measurements struct {
 	RunID  uint64 // Run identifier
 	SiteID uint64 // Site identifier
 	HostID uint64 // Host Identifier
+
+	Name        string // Name of the run
+	Collection  string // Name of the collection
+	Label       string // Operator label
+	Description string // Free-form description
+	Parameters  string // Collection parameters in JSON
+	Start       int64  // UNIX nanoseconds the run started
+	Stop        int64  // UNIX nanoseconds the run stopped, 0 while running
 }
 
 var (
 	InsertMeasurements = `
 INSERT INTO measurements (
 	siteid,
-	hostid
+	hostid,
+
+	name,
+	collection,
+	label,
+	description,
+	parameters,
+	start,
+	stop
 )
 VALUES(
 	:siteid,
-	:hostid
+	:hostid,
+
+	:name,
+	:collection,
+	:label,
+	:description,
+	:parameters,
+	:start,
+	:stop
 )
 RETURNING runid;
+`
+	UpdateMeasurementsStop = `
+UPDATE measurements SET stop = $2
+WHERE runid = $1;
 `
 	SelectMeasurementsByRunID = `
-SELECT runid, siteid, hostid
+SELECT runid, siteid, hostid, name, collection, label, description,
+	parameters, start, stop
 FROM measurements
 WHERE runid = $1;
+`
+	SelectMeasurementsByName = `
+SELECT runid, siteid, hostid, name, collection, label, description,
+	parameters, start, stop
+FROM measurements
+WHERE name = $1
+ORDER BY runid;
 `
 	SelectAllMeasurements = `
-SELECT runid, siteid, hostid
+SELECT runid, siteid, hostid, name, collection, label, description,
+	parameters, start, stop
 FROM measurements
 ORDER BY runid;
 `
@@ -73,10 +149,32 @@ ORDER BY runid;
 // Collection is prefixed after identifiers on every measurement that is being
 // stored.
 type Collection struct {
-	Timestamp int64         // Time of collection
+	Timestamp int64         // Time of collection in UNIX nanoseconds
 	Duration  time.Duration // Time collection took
 }
 
+// Upgrades contains the schema upgrades keyed by the version they upgrade
+// the database to. Upgrades are applied in order, each in its own
+// transaction, and must set the new version in the version table.
+var Upgrades = map[int][]string{
+	2:  SchemaV2,
+	3:  SchemaV3,
+	4:  SchemaV4,
+	5:  SchemaV5,
+	6:  SchemaV6,
+	7:  SchemaV7,
+	8:  SchemaV8,
+	9:  SchemaV9,
+	10: SchemaV10,
+	11: SchemaV11,
+	12: SchemaV12,
+	13: SchemaV13,
+	14: SchemaV14,
+	15: SchemaV15,
+	16: SchemaV16,
+	17: SchemaV17,
+}
+
 var (
 	SchemaV1 = []string{`
 CREATE TABLE version (Version int);
@@ -174,5 +272,408 @@ CREATE TABLE diskstat (
 	PRIMARY KEY		(runid, timestamp, name),
 	UNIQUE			(runid, timestamp, name)
 );
+`}
+
+	// SchemaV2 stores timestamps in UNIX nanoseconds to support sub-second
+	// collection frequencies.
+	SchemaV2 = []string{`
+UPDATE stat SET timestamp = timestamp * 1000000000, start = start * 1000000000;
+`, `
+UPDATE meminfo SET timestamp = timestamp * 1000000000, start = start * 1000000000;
+`, `
+UPDATE netdev SET timestamp = timestamp * 1000000000, start = start * 1000000000;
+`, `
+UPDATE diskstat SET timestamp = timestamp * 1000000000, start = start * 1000000000;
+`, `
+UPDATE version SET Version = 2;
+`}
+
+	// SchemaV3 adds per-process measurements.
+	SchemaV3 = []string{`
+CREATE TABLE pidstat (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	pid			BIGINT NOT NULL,
+	uid			BIGINT,
+	name			TEXT,
+	usert			NUMERIC,
+	system			NUMERIC,
+	cpu			NUMERIC,
+	minflt			NUMERIC,
+	majflt			NUMERIC,
+	vsz			BIGINT,
+	rss			BIGINT,
+	kbread			NUMERIC,
+	kbwrite			NUMERIC,
+	kbccwr			NUMERIC,
+	cswch			NUMERIC,
+	nvcswch			NUMERIC,
+	threads			BIGINT,
+
+	PRIMARY KEY		(runid, timestamp, pid),
+	UNIQUE			(runid, timestamp, pid)
+);
+`, `
+UPDATE version SET Version = 3;
+`}
+
+	// SchemaV4 adds per-cgroup measurements.
+	SchemaV4 = []string{`
+CREATE TABLE cgroup (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	name			TEXT NOT NULL,
+	usert			NUMERIC,
+	system			NUMERIC,
+	cpu			NUMERIC,
+	throttled		NUMERIC,
+	nrthrottled		NUMERIC,
+	memcurrent		BIGINT,
+	memanon			BIGINT,
+	memfile			BIGINT,
+	pgfault			NUMERIC,
+	pgmajfault		NUMERIC,
+	rkbytes			NUMERIC,
+	wkbytes			NUMERIC,
+	rios			NUMERIC,
+	wios			NUMERIC,
+	cpusome			NUMERIC,
+	cpufull			NUMERIC,
+
+	PRIMARY KEY		(runid, timestamp, name),
+	UNIQUE			(runid, timestamp, name)
+);
+`, `
+UPDATE version SET Version = 4;
+`}
+
+	// SchemaV5 adds pressure stall information.
+	SchemaV5 = []string{`
+CREATE TABLE pressure (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	resource		TEXT NOT NULL,
+	someavg10		NUMERIC,
+	someavg60		NUMERIC,
+	someavg300		NUMERIC,
+	somestall		NUMERIC,
+	fullavg10		NUMERIC,
+	fullavg60		NUMERIC,
+	fullavg300		NUMERIC,
+	fullstall		NUMERIC,
+
+	PRIMARY KEY		(runid, timestamp, resource),
+	UNIQUE			(runid, timestamp, resource)
+);
+`, `
+UPDATE version SET Version = 5;
+`}
+
+	// SchemaV6 adds paging and swapping rates.
+	SchemaV6 = []string{`
+CREATE TABLE vmstat (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	pgpgin			NUMERIC,
+	pgpgout			NUMERIC,
+	fault			NUMERIC,
+	majflt			NUMERIC,
+	pgfree			NUMERIC,
+	pgscank			NUMERIC,
+	pgscand			NUMERIC,
+	pgsteal			NUMERIC,
+	vmeff			NUMERIC,
+	pswpin			NUMERIC,
+	pswpout			NUMERIC,
+
+	PRIMARY KEY		(runid, timestamp),
+	UNIQUE			(runid, timestamp)
+);
+`, `
+UPDATE version SET Version = 6;
+`}
+
+	// SchemaV7 adds the run queue and load averages.
+	SchemaV7 = []string{`
+CREATE TABLE loadavg (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	runqsz			BIGINT,
+	plistsz			BIGINT,
+	ldavg1			NUMERIC,
+	ldavg5			NUMERIC,
+	ldavg15			NUMERIC,
+	blocked			BIGINT,
+
+	PRIMARY KEY		(runid, timestamp),
+	UNIQUE			(runid, timestamp)
+);
+`, `
+UPDATE version SET Version = 7;
+`}
+
+	// SchemaV8 adds IP, TCP and UDP protocol rates.
+	SchemaV8 = []string{`
+CREATE TABLE netsnmp (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	irec			NUMERIC,
+	fwddgm			NUMERIC,
+	idel			NUMERIC,
+	orq			NUMERIC,
+	asmrq			NUMERIC,
+	asmok			NUMERIC,
+	fragok			NUMERIC,
+	fragcrt			NUMERIC,
+	active			NUMERIC,
+	passive			NUMERIC,
+	iseg			NUMERIC,
+	oseg			NUMERIC,
+	atmptf			NUMERIC,
+	estres			NUMERIC,
+	retrans			NUMERIC,
+	isegerr			NUMERIC,
+	orsts			NUMERIC,
+	idgm			NUMERIC,
+	odgm			NUMERIC,
+	noport			NUMERIC,
+	idgmerr			NUMERIC,
+
+	PRIMARY KEY		(runid, timestamp),
+	UNIQUE			(runid, timestamp)
+);
+`, `
+CREATE TABLE netstat (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	lstovf			NUMERIC,
+	lstdrp			NUMERIC,
+	tmout			NUMERIC,
+	synretr			NUMERIC,
+	fretr			NUMERIC,
+	lostretr		NUMERIC,
+	abortto			NUMERIC,
+
+	PRIMARY KEY		(runid, timestamp),
+	UNIQUE			(runid, timestamp)
+);
+`, `
+UPDATE version SET Version = 8;
+`}
+	// SchemaV9 adds the network interface error rates. Existing
+	// measurements did not record errors and are set to 0.
+	SchemaV9 = []string{`
+ALTER TABLE netdev
+	ADD COLUMN rxerrors	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN txerrors	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN collisions	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN rxdropped	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN txdropped	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN txcarrier	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN rxframe	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN rxfifo	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN txfifo	NUMERIC NOT NULL DEFAULT 0;
+`, `
+UPDATE version SET Version = 9;
+`}
+	// SchemaV10 adds the extended disk statistics. Existing measurements
+	// are set to 0.
+	SchemaV10 = []string{`
+ALTER TABLE diskstat
+	ADD COLUMN rrqm		NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN wrqm		NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN rawait	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN wawait	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN aqusz	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN areqsz	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN util		NUMERIC NOT NULL DEFAULT 0;
+`, `
+UPDATE version SET Version = 10;
+`}
+
+	// SchemaV11 adds the interrupt and guest CPU utilization. Existing
+	// measurements are set to 0.
+	SchemaV11 = []string{`
+ALTER TABLE stat
+	ADD COLUMN irq		NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN soft		NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN guest	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN gnice	NUMERIC NOT NULL DEFAULT 0;
+`, `
+UPDATE version SET Version = 11;
+`}
+
+	// SchemaV12 adds the interrupt and softirq rates.
+	SchemaV12 = []string{`
+CREATE TABLE interrupts (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	cpu			SMALLINT NOT NULL,
+	source			TEXT NOT NULL,
+	description		TEXT,
+	intr			NUMERIC,
+
+	PRIMARY KEY		(runid, timestamp, cpu, source),
+	UNIQUE			(runid, timestamp, cpu, source)
+);
+`, `
+UPDATE version SET Version = 12;
+`}
+
+	// SchemaV13 adds the filesystem capacity.
+	SchemaV13 = []string{`
+CREATE TABLE statfs (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	name			TEXT,
+	mountpoint		TEXT NOT NULL,
+	fstype			TEXT,
+	mbsize			NUMERIC,
+	mbfree			NUMERIC,
+	mbused			NUMERIC,
+	fsused			NUMERIC,
+	ufsused			NUMERIC,
+	inodes			BIGINT,
+	ifree			BIGINT,
+	iused			BIGINT,
+	percentiused		NUMERIC,
+
+	PRIMARY KEY		(runid, timestamp, mountpoint),
+	UNIQUE			(runid, timestamp, mountpoint)
+);
+`, `
+UPDATE version SET Version = 13;
+`}
+
+	// SchemaV14 adds the swap and hugepage utilization. Existing
+	// measurements are set to 0.
+	SchemaV14 = []string{`
+ALTER TABLE meminfo
+	ADD COLUMN swapfree		BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN swapused		BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN percentswapused	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN swapcached		BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN percentswapcached	NUMERIC NOT NULL DEFAULT 0,
+	ADD COLUMN hugefree		BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN hugeused		BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN percenthugeused	NUMERIC NOT NULL DEFAULT 0;
+`, `
+UPDATE version SET Version = 14;
+`}
+
+	// SchemaV15 adds the CPU frequency and the NUMA node memory usage.
+	SchemaV15 = []string{`
+CREATE TABLE cpufreq (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	cpu			SMALLINT NOT NULL,
+	mhz			NUMERIC,
+
+	PRIMARY KEY		(runid, timestamp, cpu),
+	UNIQUE			(runid, timestamp, cpu)
+);
+`, `
+CREATE TABLE node (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	node			SMALLINT NOT NULL,
+	memtotal		BIGINT,
+	memfree			BIGINT,
+	memused			BIGINT,
+	percentused		NUMERIC,
+	active			BIGINT,
+	inactive		BIGINT,
+	filepages		BIGINT,
+	anonpages		BIGINT,
+	slab			BIGINT,
+	dirty			BIGINT,
+
+	PRIMARY KEY		(runid, timestamp, node),
+	UNIQUE			(runid, timestamp, node)
+);
+`, `
+UPDATE version SET Version = 15;
+`}
+
+	// SchemaV16 adds events, e.g. counter resets caused by a reboot.
+	SchemaV16 = []string{`
+CREATE TABLE events (
+	runid			BIGSERIAL NOT NULL,
+
+	timestamp		BIGINT NOT NULL,
+	start			BIGINT NOT NULL,
+	duration		BIGINT NOT NULL,
+
+	system			TEXT NOT NULL,
+	type			TEXT NOT NULL,
+	name			TEXT NOT NULL DEFAULT '',
+	description		TEXT,
+
+	PRIMARY KEY		(runid, timestamp, system, type, name),
+	UNIQUE			(runid, timestamp, system, type, name)
+);
+`, `
+UPDATE version SET Version = 16;
+`}
+
+	// SchemaV17 turns measurements into runs. Runs that predate it have
+	// no name and a zero start and stop.
+	SchemaV17 = []string{`
+ALTER TABLE measurements
+	ADD COLUMN name			TEXT NOT NULL DEFAULT '',
+	ADD COLUMN collection		TEXT NOT NULL DEFAULT '',
+	ADD COLUMN label		TEXT NOT NULL DEFAULT '',
+	ADD COLUMN description		TEXT NOT NULL DEFAULT '',
+	ADD COLUMN parameters		TEXT NOT NULL DEFAULT '',
+	ADD COLUMN start		BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN stop			BIGINT NOT NULL DEFAULT 0;
+`, `
+CREATE INDEX measurements_name ON measurements (name);
+`, `
+UPDATE version SET Version = 17;
 `}
 )
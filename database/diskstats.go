@@ -1,11 +1,12 @@
 package database
 
 // tps      rtps      wtps      dtps   bread/s   bwrtn/s   bdscd/s
+// rrqm/s   wrqm/s r_await w_await aqu-sz areq-sz  %util
 type Diskstat struct {
 	RunID uint64 // ID for this measurement
 
-	Timestamp int64 // UNIX timestamp of overall measurement
-	Start     int64 // UNIX timestamp of this measurement
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
 	Duration  int64 // Duration of measurement in nano seconds
 
 	Name  string
@@ -16,6 +17,14 @@ type Diskstat struct {
 	Bread float64
 	Bwrtn float64
 	Bdscd float64
+
+	Rrqm   float64 // rrqm/s
+	Wrqm   float64 // wrqm/s
+	RAwait float64 // r_await in milliseconds
+	WAwait float64 // w_await in milliseconds
+	AquSz  float64 // aqu-sz
+	AreqSz float64 // areq-sz in kB
+	Util   float64 // %util
 }
 
 // SQL queries for diskstat table.
@@ -34,7 +43,14 @@ INSERT INTO diskstat (
 	dtps,
 	bread,
 	bwrtn,
-	bdscd
+	bdscd,
+	rrqm,
+	wrqm,
+	rawait,
+	wawait,
+	aqusz,
+	areqsz,
+	util
 )
 VALUES(
 	:runid,
@@ -49,11 +65,20 @@ VALUES(
 	:dtps,
 	:bread,
 	:bwrtn,
-	:bdscd
-);
+	:bdscd,
+	:rrqm,
+	:wrqm,
+	:rawait,
+	:wawait,
+	:aqusz,
+	:areqsz,
+	:util
+)
+ON CONFLICT DO NOTHING;
 `
 	SelectDiskstatByRunID = `
-SELECT runid, timestamp, start, duration, name, tps, rtps, wtps, dtps, bread, bwrtn, bdscd
+SELECT runid, timestamp, start, duration, name, tps, rtps, wtps, dtps, bread, bwrtn, bdscd,
+       rrqm, wrqm, rawait, wawait, aqusz, areqsz, util
 FROM diskstat
 WHERE runid = $1
 ORDER BY timestamp, name;
@@ -0,0 +1,59 @@
+package database
+
+// Event types.
+const (
+	EventReset     = "reset"     // Counters were reset, the interval was dropped
+	EventAppear    = "appear"    // Device appeared, it is cubed from the next interval
+	EventDisappear = "disappear" // Device disappeared
+)
+
+// Event records something that happened to a system during a run, e.g. a
+// reboot of the host. Events mark boundaries in the measurements; rates are
+// never calculated across them.
+type Event struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	System      string // System the event occurred on, e.g. /proc/stat
+	Type        string // Event type, e.g. reset
+	Name        string // Device the event occurred on, if any
+	Description string // Human readable description
+}
+
+// SQL queries for events table.
+var (
+	InsertEvent = `
+INSERT INTO events (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	system,
+	type,
+	name,
+	description
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:system,
+	:type,
+	:name,
+	:description
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectEventByRunID = `
+SELECT runid, timestamp, start, duration, system, type, name, description
+FROM events
+WHERE runid = $1
+ORDER BY timestamp, system, name;
+`
+)
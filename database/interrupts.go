@@ -0,0 +1,51 @@
+package database
+
+// CPU SOURCE intr/s. The unique key is RunID, Timestamp, CPU and Source. CPU
+// is -1 for the sum of all CPUs.
+type Interrupt struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	CPU         int     // CPU
+	Source      string  // IRQ number or name, or softirq name
+	Description string  // Device of numbered interrupts
+	Intr        float64 // intr/s
+}
+
+// SQL queries for interrupts table.
+var (
+	InsertInterrupt = `
+INSERT INTO interrupts (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	cpu,
+	source,
+	description,
+	intr
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:cpu,
+	:source,
+	:description,
+	:intr
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectInterruptsByRunID = `
+SELECT runid, timestamp, start, duration, cpu, source, description, intr
+FROM interrupts
+WHERE runid = $1
+ORDER BY timestamp, source, cpu;
+`
+)
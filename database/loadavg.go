@@ -0,0 +1,57 @@
+package database
+
+// runq-sz plist-sz ldavg-1 ldavg-5 ldavg-15 blocked
+type Loadavg struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	RunqSz  uint64  // runq-sz
+	PlistSz uint64  // plist-sz
+	Ldavg1  float64 // ldavg-1
+	Ldavg5  float64 // ldavg-5
+	Ldavg15 float64 // ldavg-15
+	Blocked int64   // blocked, -1 when unknown
+}
+
+// SQL queries for loadavg table.
+var (
+	InsertLoadavg = `
+INSERT INTO loadavg (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	runqsz,
+	plistsz,
+	ldavg1,
+	ldavg5,
+	ldavg15,
+	blocked
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:runqsz,
+	:plistsz,
+	:ldavg1,
+	:ldavg5,
+	:ldavg15,
+	:blocked
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectLoadavgByRunID = `
+SELECT runid, timestamp, start, duration, runqsz, plistsz, ldavg1, ldavg5,
+       ldavg15, blocked
+FROM loadavg
+WHERE runid = $1
+ORDER BY timestamp;
+`
+)
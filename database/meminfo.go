@@ -3,8 +3,8 @@ package database
 type Meminfo struct {
 	RunID uint64 // ID for this measurement
 
-	Timestamp int64 // UNIX timestamp of overall measurement
-	Start     int64 // UNIX timestamp of this measurement
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
 	Duration  int64 // Duration of measurement in nano seconds
 
 	MemFree       uint64  // kbmemfree
@@ -18,6 +18,15 @@ type Meminfo struct {
 	Active        uint64  // kbactive
 	Inactive      uint64  // kbinactive
 	Dirty         uint64  // kbdirty
+
+	SwapFree          uint64  // kbswpfree
+	SwapUsed          uint64  // kbswpused
+	PercentSwapUsed   float64 // %swpused
+	SwapCached        uint64  // kbswpcad
+	PercentSwapCached float64 // %swpcad
+	HugeFree          uint64  // kbhugfree
+	HugeUsed          uint64  // kbhugused
+	PercentHugeUsed   float64 // %hugused
 }
 
 // SQL queries for meminfo table.
@@ -39,7 +48,15 @@ INSERT INTO meminfo (
 	percentcommit,
 	active,
 	inactive,
-	dirty
+	dirty,
+	swapfree,
+	swapused,
+	percentswapused,
+	swapcached,
+	percentswapcached,
+	hugefree,
+	hugeused,
+	percenthugeused
 )
 VALUES(
 	:runid,
@@ -57,12 +74,23 @@ VALUES(
 	:percentcommit,
 	:active,
 	:inactive,
-	:dirty
-);
+	:dirty,
+	:swapfree,
+	:swapused,
+	:percentswapused,
+	:swapcached,
+	:percentswapcached,
+	:hugefree,
+	:hugeused,
+	:percenthugeused
+)
+ON CONFLICT DO NOTHING;
 `
 	SelectMeminfoByRunID = `
 SELECT runid, timestamp, start, duration, memfree, memavailable, memused, percentused,
-       buffers, cached, commit, percentcommit, active, inactive, dirty
+       buffers, cached, commit, percentcommit, active, inactive, dirty,
+       swapfree, swapused, percentswapused, swapcached, percentswapcached,
+       hugefree, hugeused, percenthugeused
 FROM meminfo
 WHERE runid = $1
 ORDER BY timestamp;
@@ -3,8 +3,8 @@ package database
 type NetDev struct {
 	RunID uint64 // ID for this measurement
 
-	Timestamp int64 // UNIX timestamp of overall measurement
-	Start     int64 // UNIX timestamp of this measurement
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
 	Duration  int64 // Duration of measurement in nano seconds
 
 	Name         string  // IFACE
@@ -16,6 +16,15 @@ type NetDev struct {
 	TxCompressed float64 // txcmp/s
 	RxMulticast  float64 // rxmcst/s
 	IfUtil       float64 // %ifutil
+	RxErrors     float64 // rxerr/s
+	TxErrors     float64 // txerr/s
+	Collisions   float64 // coll/s
+	RxDropped    float64 // rxdrop/s
+	TxDropped    float64 // txdrop/s
+	TxCarrier    float64 // txcarr/s
+	RxFrame      float64 // rxfram/s
+	RxFIFO       float64 // rxfifo/s
+	TxFIFO       float64 // txfifo/s
 }
 
 // SQL queries for netdev table.
@@ -35,7 +44,16 @@ INSERT INTO netdev (
 	rxcompressed,
 	txcompressed,
 	rxmulticast,
-	ifutil
+	ifutil,
+	rxerrors,
+	txerrors,
+	collisions,
+	rxdropped,
+	txdropped,
+	txcarrier,
+	rxframe,
+	rxfifo,
+	txfifo
 )
 VALUES(
 	:runid,
@@ -51,12 +69,24 @@ VALUES(
 	:rxcompressed,
 	:txcompressed,
 	:rxmulticast,
-	:ifutil
-);
+	:ifutil,
+	:rxerrors,
+	:txerrors,
+	:collisions,
+	:rxdropped,
+	:txdropped,
+	:txcarrier,
+	:rxframe,
+	:rxfifo,
+	:txfifo
+)
+ON CONFLICT DO NOTHING;
 `
 	SelectNetDevByRunID = `
 SELECT runid, timestamp, start, duration, name, rxpackets, txpackets, rxkbytes,
-       txkbytes, rxcompressed, txcompressed, rxmulticast, ifutil
+       txkbytes, rxcompressed, txcompressed, rxmulticast, ifutil, rxerrors,
+       txerrors, collisions, rxdropped, txdropped, txcarrier, rxframe, rxfifo,
+       txfifo
 FROM netdev
 WHERE runid = $1
 ORDER BY timestamp, name;
@@ -0,0 +1,104 @@
+package database
+
+// irec/s fwddgm/s idel/s orq/s asmrq/s asmok/s fragok/s fragcrt/s active/s passive/s
+// iseg/s oseg/s atmptf/s estres/s retrans/s isegerr/s orsts/s idgm/s odgm/s noport/s idgmerr/s
+type NetSNMP struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	Irec    float64 // irec/s
+	Fwddgm  float64 // fwddgm/s
+	Idel    float64 // idel/s
+	Orq     float64 // orq/s
+	Asmrq   float64 // asmrq/s
+	Asmok   float64 // asmok/s
+	Fragok  float64 // fragok/s
+	Fragcrt float64 // fragcrt/s
+	Active  float64 // active/s
+	Passive float64 // passive/s
+	Iseg    float64 // iseg/s
+	Oseg    float64 // oseg/s
+	Atmptf  float64 // atmptf/s
+	Estres  float64 // estres/s
+	Retrans float64 // retrans/s
+	Isegerr float64 // isegerr/s
+	Orsts   float64 // orsts/s
+	Idgm    float64 // idgm/s
+	Odgm    float64 // odgm/s
+	Noport  float64 // noport/s
+	Idgmerr float64 // idgmerr/s
+}
+
+// SQL queries for netsnmp table.
+var (
+	InsertNetSNMP = `
+INSERT INTO netsnmp (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	irec,
+	fwddgm,
+	idel,
+	orq,
+	asmrq,
+	asmok,
+	fragok,
+	fragcrt,
+	active,
+	passive,
+	iseg,
+	oseg,
+	atmptf,
+	estres,
+	retrans,
+	isegerr,
+	orsts,
+	idgm,
+	odgm,
+	noport,
+	idgmerr
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:irec,
+	:fwddgm,
+	:idel,
+	:orq,
+	:asmrq,
+	:asmok,
+	:fragok,
+	:fragcrt,
+	:active,
+	:passive,
+	:iseg,
+	:oseg,
+	:atmptf,
+	:estres,
+	:retrans,
+	:isegerr,
+	:orsts,
+	:idgm,
+	:odgm,
+	:noport,
+	:idgmerr
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectNetSNMPByRunID = `
+SELECT runid, timestamp, start, duration, irec, fwddgm, idel, orq, asmrq,
+       asmok, fragok, fragcrt, active, passive, iseg, oseg, atmptf, estres,
+       retrans, isegerr, orsts, idgm, odgm, noport, idgmerr
+FROM netsnmp
+WHERE runid = $1
+ORDER BY timestamp;
+`
+)
@@ -0,0 +1,60 @@
+package database
+
+// lstovf/s lstdrp/s tmout/s synretr/s fretr/s lostretr/s abortto/s
+type Netstat struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	Lstovf   float64 // lstovf/s, ListenOverflows
+	Lstdrp   float64 // lstdrp/s, ListenDrops
+	Tmout    float64 // tmout/s, TCPTimeouts
+	Synretr  float64 // synretr/s, TCPSynRetrans
+	Fretr    float64 // fretr/s, TCPFastRetrans
+	Lostretr float64 // lostretr/s, TCPLostRetransmit
+	Abortto  float64 // abortto/s, TCPAbortOnTimeout
+}
+
+// SQL queries for netstat table.
+var (
+	InsertNetstat = `
+INSERT INTO netstat (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	lstovf,
+	lstdrp,
+	tmout,
+	synretr,
+	fretr,
+	lostretr,
+	abortto
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:lstovf,
+	:lstdrp,
+	:tmout,
+	:synretr,
+	:fretr,
+	:lostretr,
+	:abortto
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectNetstatByRunID = `
+SELECT runid, timestamp, start, duration, lstovf, lstdrp, tmout, synretr,
+       fretr, lostretr, abortto
+FROM netstat
+WHERE runid = $1
+ORDER BY timestamp;
+`
+)
@@ -0,0 +1,73 @@
+package database
+
+// NODE kbmemtotal kbmemfree kbmemused %memused kbactive kbinact kbfile kbanon
+// kbslab kbdirty. The unique key is RunID, Timestamp and Node.
+type Node struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	Node        int     // NODE
+	MemTotal    uint64  // kbmemtotal
+	MemFree     uint64  // kbmemfree
+	MemUsed     uint64  // kbmemused
+	PercentUsed float64 // %memused
+	Active      uint64  // kbactive
+	Inactive    uint64  // kbinact
+	FilePages   uint64  // kbfile
+	AnonPages   uint64  // kbanon
+	Slab        uint64  // kbslab
+	Dirty       uint64  // kbdirty
+}
+
+// SQL queries for node table.
+var (
+	InsertNode = `
+INSERT INTO node (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	node,
+	memtotal,
+	memfree,
+	memused,
+	percentused,
+	active,
+	inactive,
+	filepages,
+	anonpages,
+	slab,
+	dirty
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:node,
+	:memtotal,
+	:memfree,
+	:memused,
+	:percentused,
+	:active,
+	:inactive,
+	:filepages,
+	:anonpages,
+	:slab,
+	:dirty
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectNodeByRunID = `
+SELECT runid, timestamp, start, duration, node, memtotal, memfree, memused,
+       percentused, active, inactive, filepages, anonpages, slab, dirty
+FROM node
+WHERE runid = $1
+ORDER BY timestamp, node;
+`
+)
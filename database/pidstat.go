@@ -0,0 +1,88 @@
+package database
+
+// UID PID %usr %system %CPU minflt/s majflt/s VSZ RSS kB_rd/s kB_wr/s kB_ccwr/s cswch/s nvcswch/s threads Command
+type Pidstat struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	PID     int64   // PID
+	UID     uint64  // UID
+	Name    string  // Command
+	UserT   float64 // %usr
+	System  float64 // %system
+	CPU     float64 // %CPU
+	MinFlt  float64 // minflt/s
+	MajFlt  float64 // majflt/s
+	VSZ     uint64  // VSZ
+	RSS     uint64  // RSS
+	KBRead  float64 // kB_rd/s, -1 if not permitted
+	KBWrite float64 // kB_wr/s, -1 if not permitted
+	KBCcwr  float64 // kB_ccwr/s, -1 if not permitted
+	Cswch   float64 // cswch/s
+	Nvcswch float64 // nvcswch/s
+	Threads uint64  // threads
+}
+
+// SQL queries for pidstat table.
+var (
+	InsertPidstat = `
+INSERT INTO pidstat (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	pid,
+	uid,
+	name,
+	usert,
+	system,
+	cpu,
+	minflt,
+	majflt,
+	vsz,
+	rss,
+	kbread,
+	kbwrite,
+	kbccwr,
+	cswch,
+	nvcswch,
+	threads
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:pid,
+	:uid,
+	:name,
+	:usert,
+	:system,
+	:cpu,
+	:minflt,
+	:majflt,
+	:vsz,
+	:rss,
+	:kbread,
+	:kbwrite,
+	:kbccwr,
+	:cswch,
+	:nvcswch,
+	:threads
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectPidstatByRunID = `
+SELECT runid, timestamp, start, duration, pid, uid, name, usert, system, cpu,
+       minflt, majflt, vsz, rss, kbread, kbwrite, kbccwr, cswch, nvcswch,
+       threads
+FROM pidstat
+WHERE runid = $1
+ORDER BY timestamp, pid;
+`
+)
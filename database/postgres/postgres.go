@@ -2,11 +2,12 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/businessperformancetuning/perfcollector/database"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type postgres struct {
@@ -16,6 +17,19 @@ type postgres struct {
 
 var _ database.Database = (*postgres)(nil)
 
+// dataError wraps database.ErrData into err if postgres rejected the record
+// itself, i.e. a data exception or an integrity constraint violation.
+func dataError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "22", "23":
+			return fmt.Errorf("%w: %v", database.ErrData, err)
+		}
+	}
+	return err
+}
+
 func (p *postgres) Open() error {
 	log.Tracef("postgres.Open")
 
@@ -39,14 +53,41 @@ func (p *postgres) Open() error {
 	}
 
 	// Run schema updates
+	for version < database.Version {
+		if err := p.upgrade(version + 1); err != nil {
+			return fmt.Errorf("upgrade to version %v: %v",
+				version+1, err)
+		}
+		version++
+	}
 	if version != database.Version {
-		log.Infof("Upgrading database to version %v", database.Version)
-		return fmt.Errorf("add database upgrade code here")
+		return fmt.Errorf("unsupported database version %v", version)
 	}
 
 	return nil
 }
 
+// upgrade upgrades the database schema to the provided version.
+func (p *postgres) upgrade(version int) error {
+	upgrade, ok := database.Upgrades[version]
+	if !ok {
+		return fmt.Errorf("no upgrade available")
+	}
+	log.Infof("Upgrading database to version %v", version)
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	for k, v := range upgrade {
+		if _, err := tx.Exec(v); err != nil {
+			err2 := tx.Rollback()
+			return fmt.Errorf("%v: %v; Rollback: %v", k, err, err2)
+		}
+	}
+	return tx.Commit()
+}
+
 func (p *postgres) Close() error {
 	log.Tracef("postgres.Close")
 
@@ -78,6 +119,7 @@ func (p *postgres) MeasurementsInsert(ctx context.Context, m *database.Measureme
 	if err != nil {
 		return 0, err
 	}
+	defer rows.Close()
 	var runId uint64
 	if rows.Next() {
 		err = rows.Scan(&runId)
@@ -101,14 +143,136 @@ func (p *postgres) StatInsert(ctx context.Context, s []database.Stat) error {
 		_, err = tx.NamedExec(database.InsertStat, s[k])
 		if err != nil {
 			err2 := tx.Rollback()
-			return fmt.Errorf("NamedExec: %v; Rollback: %v",
-				err, err2)
+			return fmt.Errorf("NamedExec: %w; Rollback: %v",
+				dataError(err), err2)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) InterruptsInsert(ctx context.Context, in []database.Interrupt) error {
+	log.Tracef("postgres.InterruptsInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for k := range in {
+		_, err = tx.NamedExec(database.InsertInterrupt, in[k])
+		if err != nil {
+			err2 := tx.Rollback()
+			return fmt.Errorf("NamedExec: %w; Rollback: %v",
+				dataError(err), err2)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) StatfsInsert(ctx context.Context, s []database.Statfs) error {
+	log.Tracef("postgres.StatfsInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for k := range s {
+		_, err = tx.NamedExec(database.InsertStatfs, s[k])
+		if err != nil {
+			err2 := tx.Rollback()
+			return fmt.Errorf("NamedExec: %w; Rollback: %v",
+				dataError(err), err2)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) CPUFreqInsert(ctx context.Context, cf []database.CPUFreq) error {
+	log.Tracef("postgres.CPUFreqInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for k := range cf {
+		_, err = tx.NamedExec(database.InsertCPUFreq, cf[k])
+		if err != nil {
+			err2 := tx.Rollback()
+			return fmt.Errorf("NamedExec: %w; Rollback: %v",
+				dataError(err), err2)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) NodeInsert(ctx context.Context, n []database.Node) error {
+	log.Tracef("postgres.NodeInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for k := range n {
+		_, err = tx.NamedExec(database.InsertNode, n[k])
+		if err != nil {
+			err2 := tx.Rollback()
+			return fmt.Errorf("NamedExec: %w; Rollback: %v",
+				dataError(err), err2)
 		}
 	}
 
 	return tx.Commit()
 }
 
+func (p *postgres) MeasurementsStop(ctx context.Context, runID uint64, stop int64) error {
+	log.Tracef("postgres.MeasurementsStop")
+
+	res, err := p.db.ExecContext(ctx, database.UpdateMeasurementsStop,
+		runID, stop)
+	if err != nil {
+		return fmt.Errorf("postgres.MeasurementsStop: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("postgres.MeasurementsStop: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("postgres.MeasurementsStop: run not found: %v",
+			runID)
+	}
+	return nil
+}
+
+func (p *postgres) EventInsert(ctx context.Context, e *database.Event) error {
+	log.Tracef("postgres.EventInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(database.InsertEvent, e)
+	if err != nil {
+		err2 := tx.Rollback()
+		return fmt.Errorf("postgres.EventInsert NamedExec: %w; "+
+			"Rollback: %v", dataError(err), err2)
+	}
+
+	return tx.Commit()
+}
+
 func (p *postgres) MeminfoInsert(ctx context.Context, m *database.Meminfo) error {
 	log.Tracef("postgres.MeminfoInsert")
 
@@ -121,8 +285,8 @@ func (p *postgres) MeminfoInsert(ctx context.Context, m *database.Meminfo) error
 	_, err = tx.NamedExec(database.InsertMeminfo, m)
 	if err != nil {
 		err2 := tx.Rollback()
-		return fmt.Errorf("postgres.MeminfoInsert NamedExec: %v; "+
-			"Rollback: %v", err, err2)
+		return fmt.Errorf("postgres.MeminfoInsert NamedExec: %w; "+
+			"Rollback: %v", dataError(err), err2)
 	}
 
 	return tx.Commit()
@@ -142,7 +306,7 @@ func (p *postgres) NetDevInsert(ctx context.Context, nd []database.NetDev) error
 		if err != nil {
 			err2 := tx.Rollback()
 			return fmt.Errorf("postgres.NetDevInsert NamedExec: "+
-				"%v; Rollback: %v", err, err2)
+				"%w; Rollback: %v", dataError(err), err2)
 		}
 	}
 
@@ -163,13 +327,148 @@ func (p *postgres) DiskstatInsert(ctx context.Context, ds []database.Diskstat) e
 		if err != nil {
 			err2 := tx.Rollback()
 			return fmt.Errorf("postgres.DiskstatInsert NamedExec: "+
-				"%v; Rollback: %v", err, err2)
+				"%w; Rollback: %v", dataError(err), err2)
 		}
 	}
 
 	return tx.Commit()
 }
 
+func (p *postgres) PidstatInsert(ctx context.Context, ps []database.Pidstat) error {
+	log.Tracef("postgres.PidstatInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for k := range ps {
+		_, err = tx.NamedExec(database.InsertPidstat, ps[k])
+		if err != nil {
+			err2 := tx.Rollback()
+			return fmt.Errorf("postgres.PidstatInsert NamedExec: "+
+				"%w; Rollback: %v", dataError(err), err2)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) CgroupInsert(ctx context.Context, c *database.Cgroup) error {
+	log.Tracef("postgres.CgroupInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(database.InsertCgroup, c)
+	if err != nil {
+		err2 := tx.Rollback()
+		return fmt.Errorf("postgres.CgroupInsert NamedExec: %w; "+
+			"Rollback: %v", dataError(err), err2)
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) PressureInsert(ctx context.Context, ps *database.Pressure) error {
+	log.Tracef("postgres.PressureInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(database.InsertPressure, ps)
+	if err != nil {
+		err2 := tx.Rollback()
+		return fmt.Errorf("postgres.PressureInsert NamedExec: %w; "+
+			"Rollback: %v", dataError(err), err2)
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) VmstatInsert(ctx context.Context, v *database.Vmstat) error {
+	log.Tracef("postgres.VmstatInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(database.InsertVmstat, v)
+	if err != nil {
+		err2 := tx.Rollback()
+		return fmt.Errorf("postgres.VmstatInsert NamedExec: %w; "+
+			"Rollback: %v", dataError(err), err2)
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) LoadavgInsert(ctx context.Context, l *database.Loadavg) error {
+	log.Tracef("postgres.LoadavgInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(database.InsertLoadavg, l)
+	if err != nil {
+		err2 := tx.Rollback()
+		return fmt.Errorf("postgres.LoadavgInsert NamedExec: %w; "+
+			"Rollback: %v", dataError(err), err2)
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) NetSNMPInsert(ctx context.Context, n *database.NetSNMP) error {
+	log.Tracef("postgres.NetSNMPInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(database.InsertNetSNMP, n)
+	if err != nil {
+		err2 := tx.Rollback()
+		return fmt.Errorf("postgres.NetSNMPInsert NamedExec: %w; "+
+			"Rollback: %v", dataError(err), err2)
+	}
+
+	return tx.Commit()
+}
+
+func (p *postgres) NetstatInsert(ctx context.Context, n *database.Netstat) error {
+	log.Tracef("postgres.NetstatInsert")
+
+	// Use BeginTxx with ctx
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedExec(database.InsertNetstat, n)
+	if err != nil {
+		err2 := tx.Rollback()
+		return fmt.Errorf("postgres.NetstatInsert NamedExec: %w; "+
+			"Rollback: %v", dataError(err), err2)
+	}
+
+	return tx.Commit()
+}
+
 func (p *postgres) StatSelect(ctx context.Context, runID uint64) ([]database.Stat, error) {
 	log.Tracef("postgres.StatSelect")
 
@@ -181,6 +480,79 @@ func (p *postgres) StatSelect(ctx context.Context, runID uint64) ([]database.Sta
 	return stats, nil
 }
 
+func (p *postgres) StatSelectCPU(ctx context.Context, runID uint64, cpu int) ([]database.Stat, error) {
+	log.Tracef("postgres.StatSelectCPU")
+
+	var stats []database.Stat
+	err := p.db.SelectContext(ctx, &stats, database.SelectStatByRunIDCPU,
+		runID, cpu)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.StatSelectCPU: %w", err)
+	}
+	return stats, nil
+}
+
+func (p *postgres) InterruptsSelect(ctx context.Context, runID uint64) ([]database.Interrupt, error) {
+	log.Tracef("postgres.InterruptsSelect")
+
+	var interrupts []database.Interrupt
+	err := p.db.SelectContext(ctx, &interrupts,
+		database.SelectInterruptsByRunID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.InterruptsSelect: %w", err)
+	}
+	return interrupts, nil
+}
+
+func (p *postgres) StatfsSelect(ctx context.Context, runID uint64) ([]database.Statfs, error) {
+	log.Tracef("postgres.StatfsSelect")
+
+	var statfs []database.Statfs
+	err := p.db.SelectContext(ctx, &statfs, database.SelectStatfsByRunID,
+		runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.StatfsSelect: %w", err)
+	}
+	return statfs, nil
+}
+
+func (p *postgres) CPUFreqSelect(ctx context.Context, runID uint64) ([]database.CPUFreq, error) {
+	log.Tracef("postgres.CPUFreqSelect")
+
+	var cpufreq []database.CPUFreq
+	err := p.db.SelectContext(ctx, &cpufreq, database.SelectCPUFreqByRunID,
+		runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.CPUFreqSelect: %w", err)
+	}
+	return cpufreq, nil
+}
+
+func (p *postgres) NodeSelect(ctx context.Context, runID uint64) ([]database.Node, error) {
+	log.Tracef("postgres.NodeSelect")
+
+	var nodes []database.Node
+	err := p.db.SelectContext(ctx, &nodes, database.SelectNodeByRunID,
+		runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.NodeSelect: %w", err)
+	}
+	return nodes, nil
+}
+
+func (p *postgres) EventSelect(ctx context.Context, runID uint64) ([]database.Event, error) {
+	log.Tracef("postgres.EventSelect")
+
+	var events []database.Event
+	err := p.db.SelectContext(ctx, &events, database.SelectEventByRunID,
+		runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.EventSelect: %w", err)
+	}
+
+	return events, nil
+}
+
 func (p *postgres) MeminfoSelect(ctx context.Context, runID uint64) ([]database.Meminfo, error) {
 	log.Tracef("postgres.MeminfoSelect")
 
@@ -214,6 +586,83 @@ func (p *postgres) DiskstatSelect(ctx context.Context, runID uint64) ([]database
 	return diskstat, nil
 }
 
+func (p *postgres) PidstatSelect(ctx context.Context, runID uint64) ([]database.Pidstat, error) {
+	log.Tracef("postgres.PidstatSelect")
+
+	var pidstat []database.Pidstat
+	err := p.db.SelectContext(ctx, &pidstat, database.SelectPidstatByRunID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.PidstatSelect: %w", err)
+	}
+	return pidstat, nil
+}
+
+func (p *postgres) CgroupSelect(ctx context.Context, runID uint64) ([]database.Cgroup, error) {
+	log.Tracef("postgres.CgroupSelect")
+
+	var cgroup []database.Cgroup
+	err := p.db.SelectContext(ctx, &cgroup, database.SelectCgroupByRunID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.CgroupSelect: %w", err)
+	}
+	return cgroup, nil
+}
+
+func (p *postgres) PressureSelect(ctx context.Context, runID uint64) ([]database.Pressure, error) {
+	log.Tracef("postgres.PressureSelect")
+
+	var pressure []database.Pressure
+	err := p.db.SelectContext(ctx, &pressure, database.SelectPressureByRunID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.PressureSelect: %w", err)
+	}
+	return pressure, nil
+}
+
+func (p *postgres) VmstatSelect(ctx context.Context, runID uint64) ([]database.Vmstat, error) {
+	log.Tracef("postgres.VmstatSelect")
+
+	var vmstat []database.Vmstat
+	err := p.db.SelectContext(ctx, &vmstat, database.SelectVmstatByRunID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.VmstatSelect: %w", err)
+	}
+	return vmstat, nil
+}
+
+func (p *postgres) LoadavgSelect(ctx context.Context, runID uint64) ([]database.Loadavg, error) {
+	log.Tracef("postgres.LoadavgSelect")
+
+	var loadavg []database.Loadavg
+	err := p.db.SelectContext(ctx, &loadavg, database.SelectLoadavgByRunID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.LoadavgSelect: %w", err)
+	}
+	return loadavg, nil
+}
+
+func (p *postgres) NetSNMPSelect(ctx context.Context, runID uint64) ([]database.NetSNMP, error) {
+	log.Tracef("postgres.NetSNMPSelect")
+
+	var netsnmp []database.NetSNMP
+	err := p.db.SelectContext(ctx, &netsnmp, database.SelectNetSNMPByRunID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.NetSNMPSelect: %w", err)
+	}
+	return netsnmp, nil
+}
+
+func (p *postgres) NetstatSelect(ctx context.Context, runID uint64) ([]database.Netstat, error) {
+	log.Tracef("postgres.NetstatSelect")
+
+	var netstat []database.Netstat
+	err := p.db.SelectContext(ctx, &netstat, database.SelectNetstatByRunID, runID)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.NetstatSelect: %w", err)
+	}
+	return netstat, nil
+}
+
 func (p *postgres) MeasurementsSelect(ctx context.Context, runID uint64) (*database.Measurements, error) {
 	log.Tracef("postgres.MeasurementsSelect")
 
@@ -225,6 +674,19 @@ func (p *postgres) MeasurementsSelect(ctx context.Context, runID uint64) (*datab
 	return &m, nil
 }
 
+func (p *postgres) MeasurementsSelectByName(ctx context.Context, name string) ([]database.Measurements, error) {
+	log.Tracef("postgres.MeasurementsSelectByName")
+
+	var measurements []database.Measurements
+	err := p.db.SelectContext(ctx, &measurements,
+		database.SelectMeasurementsByName, name)
+	if err != nil {
+		return nil, fmt.Errorf("postgres.MeasurementsSelectByName: %w",
+			err)
+	}
+	return measurements, nil
+}
+
 func (p *postgres) ListRuns(ctx context.Context) ([]database.Measurements, error) {
 	log.Tracef("postgres.ListRuns")
 
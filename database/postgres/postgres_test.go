@@ -83,10 +83,26 @@ func TestPostgres(t *testing.T) {
 	}
 	defer db.Close()
 
+	// New databases are upgraded to the latest version.
+	var version int
+	err = db.db.Get(&version, database.SelectVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != database.Version {
+		t.Fatalf("got version %v, want %v", version, database.Version)
+	}
+
 	// Insert Measurements
 	m := database.Measurements{
 		SiteID: 1,
 		HostID: 2,
+
+		Name:       "baseline",
+		Collection: "default",
+		Label:      "ticket-1",
+		Parameters: `{"Name":"default"}`,
+		Start:      1700000000000000000,
 	}
 	runId, err := db.MeasurementsInsert(ctx, &m)
 	if err != nil {
@@ -109,8 +125,8 @@ func TestPostgres(t *testing.T) {
 	for i := 0; i < 5; i++ {
 		s = append(s, database.Stat{
 			RunID:     runId,
-			Timestamp: ts.Unix(),
-			Start:     ts.Add(time.Duration(i+1) * time.Microsecond).Unix(),
+			Timestamp: ts.UnixNano(),
+			Start:     ts.Add(time.Duration(i+1) * time.Microsecond).UnixNano(),
 			Duration:  1234,
 
 			CPU:    i,
@@ -119,6 +135,10 @@ func TestPostgres(t *testing.T) {
 			System: float64(i),
 			IOWait: float64(i),
 			Steal:  float64(i),
+			IRQ:    float64(i),
+			Soft:   float64(i),
+			Guest:  float64(i),
+			GNice:  float64(i),
 			Idle:   float64(i),
 		})
 	}
@@ -130,8 +150,8 @@ func TestPostgres(t *testing.T) {
 	// Insert meminfo
 	mi := database.Meminfo{
 		RunID:     runId,
-		Timestamp: ts.Unix(),
-		Start:     ts.Add(time.Duration(time.Microsecond)).Unix(),
+		Timestamp: ts.UnixNano(),
+		Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
 		Duration:  1234,
 
 		MemFree:       54321,
@@ -145,19 +165,34 @@ func TestPostgres(t *testing.T) {
 		Active:        54321,
 		Inactive:      54321,
 		Dirty:         54321,
+
+		SwapFree:          54321,
+		SwapUsed:          12345,
+		PercentSwapUsed:   0.19,
+		SwapCached:        1234,
+		PercentSwapCached: 0.1,
+		HugeFree:          2048,
+		HugeUsed:          4096,
+		PercentHugeUsed:   0.67,
 	}
 	err = db.MeminfoInsert(ctx, &mi)
 	if err != nil {
 		t.Fatal(err)
 	}
 
+	// Records that are sent again after a restart are skipped.
+	err = db.MeminfoInsert(ctx, &mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// Insert netdev
 	nd := make([]database.NetDev, 0, 5)
 	for i := 0; i < 5; i++ {
 		nd = append(nd, database.NetDev{
 			RunID:     runId,
-			Timestamp: ts.Unix(),
-			Start:     ts.Add(time.Duration(time.Microsecond)).Unix(),
+			Timestamp: ts.UnixNano(),
+			Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
 			Duration:  1234,
 
 			Name:         fmt.Sprintf("eno%v", i),
@@ -169,6 +204,8 @@ func TestPostgres(t *testing.T) {
 			TxCompressed: 39.34,
 			RxMulticast:  40.34,
 			IfUtil:       0.99,
+			RxErrors:     0.5,
+			TxDropped:    1.5,
 		})
 	}
 	err = db.NetDevInsert(ctx, nd)
@@ -181,8 +218,8 @@ func TestPostgres(t *testing.T) {
 	for i := 0; i < 5; i++ {
 		ds = append(ds, database.Diskstat{
 			RunID:     runId,
-			Timestamp: ts.Unix(),
-			Start:     ts.Add(time.Duration(time.Microsecond)).Unix(),
+			Timestamp: ts.UnixNano(),
+			Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
 			Duration:  1234,
 
 			Name:  fmt.Sprintf("sda%v", i),
@@ -193,6 +230,11 @@ func TestPostgres(t *testing.T) {
 			Bread: 38.34,
 			Bwrtn: 39.34,
 			Bdscd: 40.34,
+
+			RAwait: 1.25,
+			WAwait: 2.5,
+			AquSz:  0.75,
+			Util:   42.5,
 		})
 	}
 	err = db.DiskstatInsert(ctx, ds)
@@ -200,6 +242,240 @@ func TestPostgres(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// Insert Pidstat
+	ps := make([]database.Pidstat, 0, 5)
+	for i := 0; i < 5; i++ {
+		ps = append(ps, database.Pidstat{
+			RunID:     runId,
+			Timestamp: ts.UnixNano(),
+			Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+			Duration:  1234,
+
+			PID:     int64(1000 + i),
+			UID:     1000,
+			Name:    fmt.Sprintf("postgres %v", i),
+			UserT:   12.34,
+			System:  35.34,
+			CPU:     47.68,
+			MinFlt:  36.34,
+			MajFlt:  0,
+			VSZ:     123456,
+			RSS:     23456,
+			KBRead:  -1,
+			KBWrite: -1,
+			KBCcwr:  -1,
+			Cswch:   38.34,
+			Nvcswch: 39.34,
+			Threads: 4,
+		})
+	}
+	err = db.PidstatInsert(ctx, ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert Cgroup
+	for i := 0; i < 5; i++ {
+		err = db.CgroupInsert(ctx, &database.Cgroup{
+			RunID:     runId,
+			Timestamp: ts.UnixNano(),
+			Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+			Duration:  1234,
+
+			Name:       fmt.Sprintf("/system.slice/service%v.service", i),
+			UserT:      12.34,
+			System:     35.34,
+			CPU:        47.68,
+			MemCurrent: 123456,
+			RKBytes:    36.34,
+			WKBytes:    37.34,
+			CPUSome:    1.5,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Insert Pressure
+	for i, resource := range []string{"cpu", "memory", "io"} {
+		err = db.PressureInsert(ctx, &database.Pressure{
+			RunID:     runId,
+			Timestamp: ts.UnixNano(),
+			Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+			Duration:  1234,
+
+			Resource:  resource,
+			SomeAvg10: 1.5,
+			SomeStall: float64(i) + 0.25,
+			FullStall: float64(i),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Insert Vmstat
+	err = db.VmstatInsert(ctx, &database.Vmstat{
+		RunID:     runId,
+		Timestamp: ts.UnixNano(),
+		Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+		Duration:  1234,
+
+		Pgpgin:  12.34,
+		Pgpgout: 23.45,
+		Fault:   1234.5,
+		Pgscank: 100,
+		Pgsteal: 90,
+		Vmeff:   90,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert Loadavg
+	err = db.LoadavgInsert(ctx, &database.Loadavg{
+		RunID:     runId,
+		Timestamp: ts.UnixNano(),
+		Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+		Duration:  1234,
+
+		RunqSz:  3,
+		PlistSz: 812,
+		Ldavg1:  1.25,
+		Ldavg5:  0.75,
+		Ldavg15: 0.5,
+		Blocked: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert NetSNMP and Netstat
+	err = db.NetSNMPInsert(ctx, &database.NetSNMP{
+		RunID:     runId,
+		Timestamp: ts.UnixNano(),
+		Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+		Duration:  1234,
+
+		Active:  1.5,
+		Passive: 12.5,
+		Iseg:    1234.5,
+		Oseg:    1200.5,
+		Retrans: 3.5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.NetstatInsert(ctx, &database.Netstat{
+		RunID:     runId,
+		Timestamp: ts.UnixNano(),
+		Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+		Duration:  1234,
+
+		Lstovf: 2.5,
+		Lstdrp: 2.5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var in []database.Interrupt
+	for _, cpu := range []int{-1, 0, 1} {
+		in = append(in, database.Interrupt{
+			RunID:     runId,
+			Timestamp: ts.UnixNano(),
+			Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+			Duration:  1234,
+
+			CPU:         cpu,
+			Source:      "24",
+			Description: "IR-PCI-MSI 524288-edge eth0-rx-0",
+			Intr:        float64(cpu + 2),
+		})
+	}
+	err = db.InterruptsInsert(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.StatfsInsert(ctx, []database.Statfs{{
+		RunID:     runId,
+		Timestamp: ts.UnixNano(),
+		Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+		Duration:  1234,
+
+		Name:         "/dev/sda1",
+		MountPoint:   "/",
+		FSType:       "ext4",
+		MBSize:       1024,
+		MBFree:       256,
+		MBUsed:       768,
+		FSUsed:       75,
+		UFSUsed:      80,
+		Inodes:       1000,
+		IFree:        250,
+		IUsed:        750,
+		PercentIUsed: 75,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.CPUFreqInsert(ctx, []database.CPUFreq{{
+		RunID:     runId,
+		Timestamp: ts.UnixNano(),
+		Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+		Duration:  1234,
+
+		CPU: -1,
+		MHz: 2400,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.NodeInsert(ctx, []database.Node{{
+		RunID:     runId,
+		Timestamp: ts.UnixNano(),
+		Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+		Duration:  1234,
+
+		Node:        0,
+		MemTotal:    1024,
+		MemFree:     256,
+		MemUsed:     768,
+		PercentUsed: 75,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.EventInsert(ctx, &database.Event{
+		RunID:     runId,
+		Timestamp: ts.UnixNano(),
+		Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+		Duration:  1234,
+
+		System:      "/proc/stat",
+		Type:        database.EventReset,
+		Description: "counter reset: boot time changed",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Several devices may appear at the same time.
+	for _, name := range []string{"veth1", "veth2"} {
+		err = db.EventInsert(ctx, &database.Event{
+			RunID:     runId,
+			Timestamp: ts.UnixNano(),
+			Start:     ts.Add(time.Duration(time.Microsecond)).UnixNano(),
+			Duration:  1234,
+
+			System:      "/proc/net/dev",
+			Type:        database.EventAppear,
+			Name:        name,
+			Description: "interface " + name + " appeared",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
 	// Test SELECT methods
 	t.Run("StatSelect", func(t *testing.T) {
 		stats, err := db.StatSelect(ctx, runId)
@@ -219,6 +495,19 @@ func TestPostgres(t *testing.T) {
 		}
 	})
 
+	t.Run("StatSelectCPU", func(t *testing.T) {
+		stats, err := db.StatSelectCPU(ctx, runId, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stats) != 1 {
+			t.Fatalf("expected 1 stat, got %d", len(stats))
+		}
+		if stats[0].CPU != 3 || stats[0].Soft != 3 || stats[0].GNice != 3 {
+			t.Errorf("unexpected stat: %+v", stats[0])
+		}
+	})
+
 	t.Run("MeminfoSelect", func(t *testing.T) {
 		meminfos, err := db.MeminfoSelect(ctx, runId)
 		if err != nil {
@@ -233,6 +522,9 @@ func TestPostgres(t *testing.T) {
 		if meminfos[0].MemFree != 54321 {
 			t.Errorf("meminfo.MemFree = %d, want 54321", meminfos[0].MemFree)
 		}
+		if meminfos[0].SwapUsed != 12345 || meminfos[0].HugeUsed != 4096 {
+			t.Errorf("unexpected meminfo: %+v", meminfos[0])
+		}
 	})
 
 	t.Run("NetDevSelect", func(t *testing.T) {
@@ -247,6 +539,10 @@ func TestPostgres(t *testing.T) {
 			if nd.RunID != runId {
 				t.Errorf("netdev[%d].RunID = %d, want %d", i, nd.RunID, runId)
 			}
+			if nd.RxErrors != 0.5 || nd.TxDropped != 1.5 {
+				t.Errorf("netdev[%d] errors = %v %v, want 0.5 1.5", i,
+					nd.RxErrors, nd.TxDropped)
+			}
 		}
 	})
 
@@ -262,6 +558,184 @@ func TestPostgres(t *testing.T) {
 			if ds.RunID != runId {
 				t.Errorf("diskstat[%d].RunID = %d, want %d", i, ds.RunID, runId)
 			}
+			if ds.Util != 42.5 {
+				t.Errorf("diskstat[%d].Util = %v, want 42.5", i, ds.Util)
+			}
+		}
+	})
+
+	t.Run("PidstatSelect", func(t *testing.T) {
+		pidstats, err := db.PidstatSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pidstats) != 5 {
+			t.Fatalf("expected 5 pidstats, got %d", len(pidstats))
+		}
+		for i, ps := range pidstats {
+			if ps.PID != int64(1000+i) {
+				t.Errorf("pidstat[%d].PID = %d, want %d", i, ps.PID, 1000+i)
+			}
+		}
+	})
+
+	t.Run("CgroupSelect", func(t *testing.T) {
+		cgroups, err := db.CgroupSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cgroups) != 5 {
+			t.Fatalf("expected 5 cgroups, got %d", len(cgroups))
+		}
+		for i, cg := range cgroups {
+			if cg.RunID != runId {
+				t.Errorf("cgroup[%d].RunID = %d, want %d", i, cg.RunID, runId)
+			}
+		}
+	})
+
+	t.Run("PressureSelect", func(t *testing.T) {
+		pressure, err := db.PressureSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pressure) != 3 {
+			t.Fatalf("expected 3 pressure, got %d", len(pressure))
+		}
+		for i, ps := range pressure {
+			if ps.RunID != runId {
+				t.Errorf("pressure[%d].RunID = %d, want %d", i, ps.RunID, runId)
+			}
+		}
+	})
+
+	t.Run("VmstatSelect", func(t *testing.T) {
+		vmstat, err := db.VmstatSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vmstat) != 1 {
+			t.Fatalf("expected 1 vmstat, got %d", len(vmstat))
+		}
+		if vmstat[0].RunID != runId {
+			t.Errorf("vmstat.RunID = %d, want %d", vmstat[0].RunID, runId)
+		}
+		if vmstat[0].Vmeff != 90 {
+			t.Errorf("vmstat.Vmeff = %v, want 90", vmstat[0].Vmeff)
+		}
+	})
+
+	t.Run("LoadavgSelect", func(t *testing.T) {
+		loadavg, err := db.LoadavgSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(loadavg) != 1 {
+			t.Fatalf("expected 1 loadavg, got %d", len(loadavg))
+		}
+		if loadavg[0].RunID != runId {
+			t.Errorf("loadavg.RunID = %d, want %d", loadavg[0].RunID, runId)
+		}
+		if loadavg[0].PlistSz != 812 {
+			t.Errorf("loadavg.PlistSz = %d, want 812", loadavg[0].PlistSz)
+		}
+	})
+
+	t.Run("NetSNMPSelect", func(t *testing.T) {
+		netsnmp, err := db.NetSNMPSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(netsnmp) != 1 {
+			t.Fatalf("expected 1 netsnmp, got %d", len(netsnmp))
+		}
+		if netsnmp[0].Retrans != 3.5 {
+			t.Errorf("netsnmp.Retrans = %v, want 3.5", netsnmp[0].Retrans)
+		}
+	})
+
+	t.Run("NetstatSelect", func(t *testing.T) {
+		netstat, err := db.NetstatSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(netstat) != 1 {
+			t.Fatalf("expected 1 netstat, got %d", len(netstat))
+		}
+		if netstat[0].Lstdrp != 2.5 {
+			t.Errorf("netstat.Lstdrp = %v, want 2.5", netstat[0].Lstdrp)
+		}
+	})
+
+	t.Run("InterruptsSelect", func(t *testing.T) {
+		interrupts, err := db.InterruptsSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(interrupts) != 3 {
+			t.Fatalf("expected 3 interrupts, got %d", len(interrupts))
+		}
+		for i, v := range interrupts {
+			if v.CPU != i-1 || v.Intr != float64(i+1) {
+				t.Errorf("interrupts[%d] = %+v", i, v)
+			}
+		}
+	})
+
+	t.Run("StatfsSelect", func(t *testing.T) {
+		statfs, err := db.StatfsSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(statfs) != 1 {
+			t.Fatalf("expected 1 statfs, got %d", len(statfs))
+		}
+		if statfs[0].MountPoint != "/" || statfs[0].IUsed != 750 {
+			t.Errorf("unexpected statfs: %+v", statfs[0])
+		}
+	})
+
+	t.Run("CPUFreqSelect", func(t *testing.T) {
+		cpufreq, err := db.CPUFreqSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cpufreq) != 1 {
+			t.Fatalf("expected 1 cpufreq, got %d", len(cpufreq))
+		}
+		if cpufreq[0].CPU != -1 || cpufreq[0].MHz != 2400 {
+			t.Errorf("unexpected cpufreq: %+v", cpufreq[0])
+		}
+	})
+
+	t.Run("NodeSelect", func(t *testing.T) {
+		nodes, err := db.NodeSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(nodes) != 1 {
+			t.Fatalf("expected 1 node, got %d", len(nodes))
+		}
+		if nodes[0].MemUsed != 768 || nodes[0].PercentUsed != 75 {
+			t.Errorf("unexpected node: %+v", nodes[0])
+		}
+	})
+
+	t.Run("EventSelect", func(t *testing.T) {
+		events, err := db.EventSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 3 {
+			t.Fatalf("expected 3 events, got %d", len(events))
+		}
+		if events[0].Name != "veth1" || events[1].Name != "veth2" ||
+			events[1].Type != database.EventAppear {
+			t.Errorf("unexpected events: %+v", events)
+		}
+		if events[2].System != "/proc/stat" ||
+			events[2].Type != database.EventReset {
+			t.Errorf("unexpected event: %+v", events[2])
 		}
 	})
 
@@ -279,6 +753,44 @@ func TestPostgres(t *testing.T) {
 		if measurements.HostID != 2 {
 			t.Errorf("measurements.HostID = %d, want 2", measurements.HostID)
 		}
+		if measurements.Name != "baseline" || measurements.Label != "ticket-1" ||
+			measurements.Stop != 0 {
+			t.Errorf("unexpected run: %+v", measurements)
+		}
+	})
+
+	t.Run("MeasurementsStop", func(t *testing.T) {
+		err := db.MeasurementsStop(ctx, runId, m.Start+int64(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		measurements, err := db.MeasurementsSelect(ctx, runId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if measurements.Stop != m.Start+int64(time.Hour) {
+			t.Errorf("measurements.Stop = %d", measurements.Stop)
+		}
+		if err := db.MeasurementsStop(ctx, 1000, 1); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("MeasurementsSelectByName", func(t *testing.T) {
+		runs, err := db.MeasurementsSelectByName(ctx, "baseline")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != 2 || runs[0].RunID != 1 || runs[1].RunID != 2 {
+			t.Fatalf("unexpected runs: %+v", runs)
+		}
+		runs, err = db.MeasurementsSelectByName(ctx, "unknown")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != 0 {
+			t.Fatalf("unexpected runs: %+v", runs)
+		}
 	})
 
 	t.Run("ListRuns", func(t *testing.T) {
@@ -0,0 +1,66 @@
+package database
+
+// RESOURCE %some10 %some60 %some300 %some %full10 %full60 %full300 %full
+type Pressure struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	Resource   string  // RESOURCE
+	SomeAvg10  float64 // %some10
+	SomeAvg60  float64 // %some60
+	SomeAvg300 float64 // %some300
+	SomeStall  float64 // %some
+	FullAvg10  float64 // %full10
+	FullAvg60  float64 // %full60
+	FullAvg300 float64 // %full300
+	FullStall  float64 // %full
+}
+
+// SQL queries for pressure table.
+var (
+	InsertPressure = `
+INSERT INTO pressure (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	resource,
+	someavg10,
+	someavg60,
+	someavg300,
+	somestall,
+	fullavg10,
+	fullavg60,
+	fullavg300,
+	fullstall
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:resource,
+	:someavg10,
+	:someavg60,
+	:someavg300,
+	:somestall,
+	:fullavg10,
+	:fullavg60,
+	:fullavg300,
+	:fullstall
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectPressureByRunID = `
+SELECT runid, timestamp, start, duration, resource, someavg10, someavg60,
+       someavg300, somestall, fullavg10, fullavg60, fullavg300, fullstall
+FROM pressure
+WHERE runid = $1
+ORDER BY timestamp, resource;
+`
+)
@@ -5,17 +5,21 @@ package database
 type Stat struct {
 	RunID uint64 // ID for this measurement
 
-	Timestamp int64 // UNIX timestamp of overall measurement
-	Start     int64 // UNIX timestamp of this measurement
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
 	Duration  int64 // Duration of measurement in nano seconds
 
-	CPU    int
-	UserT  float64
-	Nice   float64
-	System float64
-	IOWait float64
-	Steal  float64
-	Idle   float64
+	CPU    int     // CPU, -1 for all
+	UserT  float64 // %usr
+	Nice   float64 // %nice
+	System float64 // %sys
+	IOWait float64 // %iowait
+	Steal  float64 // %steal
+	IRQ    float64 // %irq
+	Soft   float64 // %soft
+	Guest  float64 // %guest
+	GNice  float64 // %gnice
+	Idle   float64 // %idle
 }
 
 // SQL queries for stat table.
@@ -33,6 +37,10 @@ INSERT INTO stat (
 	system,
 	iowait,
 	steal,
+	irq,
+	soft,
+	guest,
+	gnice,
 	idle
 )
 VALUES(
@@ -47,13 +55,26 @@ VALUES(
 	:system,
 	:iowait,
 	:steal,
+	:irq,
+	:soft,
+	:guest,
+	:gnice,
 	:idle
-);
+)
+ON CONFLICT DO NOTHING;
 `
 	SelectStatByRunID = `
-SELECT runid, timestamp, start, duration, cpu, usert, nice, system, iowait, steal, idle
+SELECT runid, timestamp, start, duration, cpu, usert, nice, system, iowait,
+       steal, irq, soft, guest, gnice, idle
 FROM stat
 WHERE runid = $1
 ORDER BY timestamp, cpu;
+`
+	SelectStatByRunIDCPU = `
+SELECT runid, timestamp, start, duration, cpu, usert, nice, system, iowait,
+       steal, irq, soft, guest, gnice, idle
+FROM stat
+WHERE runid = $1 AND cpu = $2
+ORDER BY timestamp;
 `
 )
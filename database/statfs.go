@@ -0,0 +1,75 @@
+package database
+
+// FILESYSTEM MBfssize MBfsfree MBfsused %fsused %ufsused Inodes Ifree Iused %Iused
+type Statfs struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	Name         string  // FILESYSTEM
+	MountPoint   string  // Mount point
+	FSType       string  // Filesystem type
+	MBSize       float64 // MBfssize
+	MBFree       float64 // MBfsfree
+	MBUsed       float64 // MBfsused
+	FSUsed       float64 // %fsused
+	UFSUsed      float64 // %ufsused
+	Inodes       uint64  // Inodes
+	IFree        uint64  // Ifree
+	IUsed        uint64  // Iused
+	PercentIUsed float64 // %Iused
+}
+
+// SQL queries for statfs table.
+var (
+	InsertStatfs = `
+INSERT INTO statfs (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	name,
+	mountpoint,
+	fstype,
+	mbsize,
+	mbfree,
+	mbused,
+	fsused,
+	ufsused,
+	inodes,
+	ifree,
+	iused,
+	percentiused
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:name,
+	:mountpoint,
+	:fstype,
+	:mbsize,
+	:mbfree,
+	:mbused,
+	:fsused,
+	:ufsused,
+	:inodes,
+	:ifree,
+	:iused,
+	:percentiused
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectStatfsByRunID = `
+SELECT runid, timestamp, start, duration, name, mountpoint, fstype, mbsize,
+       mbfree, mbused, fsused, ufsused, inodes, ifree, iused, percentiused
+FROM statfs
+WHERE runid = $1
+ORDER BY timestamp, mountpoint;
+`
+)
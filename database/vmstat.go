@@ -0,0 +1,72 @@
+package database
+
+// pgpgin/s pgpgout/s fault/s majflt/s pgfree/s pgscank/s pgscand/s pgsteal/s %vmeff pswpin/s pswpout/s
+type Vmstat struct {
+	RunID uint64 // ID for this measurement
+
+	Timestamp int64 // UNIX nanoseconds of overall measurement
+	Start     int64 // UNIX nanoseconds of this measurement
+	Duration  int64 // Duration of measurement in nano seconds
+
+	Pgpgin  float64 // pgpgin/s
+	Pgpgout float64 // pgpgout/s
+	Fault   float64 // fault/s
+	MajFlt  float64 // majflt/s
+	Pgfree  float64 // pgfree/s
+	Pgscank float64 // pgscank/s
+	Pgscand float64 // pgscand/s
+	Pgsteal float64 // pgsteal/s
+	Vmeff   float64 // %vmeff
+	Pswpin  float64 // pswpin/s
+	Pswpout float64 // pswpout/s
+}
+
+// SQL queries for vmstat table.
+var (
+	InsertVmstat = `
+INSERT INTO vmstat (
+	runid,
+	timestamp,
+	start,
+	duration,
+
+	pgpgin,
+	pgpgout,
+	fault,
+	majflt,
+	pgfree,
+	pgscank,
+	pgscand,
+	pgsteal,
+	vmeff,
+	pswpin,
+	pswpout
+)
+VALUES(
+	:runid,
+	:timestamp,
+	:start,
+	:duration,
+
+	:pgpgin,
+	:pgpgout,
+	:fault,
+	:majflt,
+	:pgfree,
+	:pgscank,
+	:pgscand,
+	:pgsteal,
+	:vmeff,
+	:pswpin,
+	:pswpout
+)
+ON CONFLICT DO NOTHING;
+`
+	SelectVmstatByRunID = `
+SELECT runid, timestamp, start, duration, pgpgin, pgpgout, fault, majflt,
+       pgfree, pgscank, pgscand, pgsteal, vmeff, pswpin, pswpout
+FROM vmstat
+WHERE runid = $1
+ORDER BY timestamp;
+`
+)
@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// CgroupCPUStat is parsed from the cpu.stat file of a cgroup. The throttling
+// fields are only reported when the cpu controller is enabled.
+type CgroupCPUStat struct {
+	UsageUsec     uint64 // CPU time in microseconds
+	UserUsec      uint64 // CPU time in user mode in microseconds
+	SystemUsec    uint64 // CPU time in kernel mode in microseconds
+	NrPeriods     uint64 // Enforcement periods
+	NrThrottled   uint64 // Periods in which the cgroup was throttled
+	ThrottledUsec uint64 // Time throttled in microseconds
+}
+
+// CgroupIOStat is a single device of the io.stat file of a cgroup.
+type CgroupIOStat struct {
+	RBytes uint64 // Bytes read
+	WBytes uint64 // Bytes written
+	RIOs   uint64 // Read operations
+	WIOs   uint64 // Write operations
+	DBytes uint64 // Bytes discarded
+	DIOs   uint64 // Discard operations
+}
+
+// Cgroup is parsed from a cgroup system measurement.
+type Cgroup struct {
+	Name          string                  // Path below types.PCCgroupRoot, / for the root
+	CPU           CgroupCPUStat           // cpu.stat
+	CPUPressure   Pressure                // cpu.pressure
+	MemoryCurrent uint64                  // memory.current in bytes
+	MemoryStat    map[string]uint64       // memory.stat
+	IO            map[string]CgroupIOStat // io.stat keyed by major:minor
+}
+
+// CgroupName returns the name of the cgroup system s, i.e. its path below
+// types.PCCgroupRoot.
+func CgroupName(s string) string {
+	name := strings.TrimPrefix(filepath.Clean(s), types.PCCgroupRoot)
+	if name == "" {
+		return "/"
+	}
+	return name
+}
+
+// processFlatKeyed parses a file with a key and a value on every line.
+func processFlatKeyed(b []byte) (map[string]uint64, error) {
+	m := make(map[string]uint64)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v: %v", fields[0], err)
+		}
+		m[fields[0]] = v
+	}
+	return m, s.Err()
+}
+
+// ProcessCgroupCPUStat parses the cpu.stat file of a cgroup.
+func ProcessCgroupCPUStat(b []byte) (CgroupCPUStat, error) {
+	m, err := processFlatKeyed(b)
+	if err != nil {
+		return CgroupCPUStat{}, err
+	}
+	return CgroupCPUStat{
+		UsageUsec:     m["usage_usec"],
+		UserUsec:      m["user_usec"],
+		SystemUsec:    m["system_usec"],
+		NrPeriods:     m["nr_periods"],
+		NrThrottled:   m["nr_throttled"],
+		ThrottledUsec: m["throttled_usec"],
+	}, nil
+}
+
+// ProcessCgroupIOStat parses the io.stat file of a cgroup.
+func ProcessCgroupIOStat(b []byte) (map[string]CgroupIOStat, error) {
+	m := make(map[string]CgroupIOStat)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		// 8:0 rbytes=1 wbytes=2 rios=3 wios=4 dbytes=5 dios=6
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var io CgroupIOStat
+		for _, v := range fields[1:] {
+			kv := strings.SplitN(v, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid io.stat field: %v",
+					v)
+			}
+			var f *uint64
+			switch kv[0] {
+			case "rbytes":
+				f = &io.RBytes
+			case "wbytes":
+				f = &io.WBytes
+			case "rios":
+				f = &io.RIOs
+			case "wios":
+				f = &io.WIOs
+			case "dbytes":
+				f = &io.DBytes
+			case "dios":
+				f = &io.DIOs
+			default:
+				continue
+			}
+			var err error
+			*f, err = strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid io.stat %v: %v",
+					kv[0], err)
+			}
+		}
+		m[fields[0]] = io
+	}
+	return m, s.Err()
+}
+
+// ProcessCgroup parses a cgroup system measurement.
+func ProcessCgroup(b []byte) (*Cgroup, error) {
+	sections, err := types.DecodeSections(string(b))
+	if err != nil {
+		return nil, err
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("empty cgroup measurement")
+	}
+
+	cg := &Cgroup{
+		Name: CgroupName(filepath.Dir(sections[0].Name)),
+	}
+	for _, v := range sections {
+		switch filepath.Base(v.Name) {
+		case "cpu.stat":
+			cg.CPU, err = ProcessCgroupCPUStat(v.Data)
+		case "cpu.pressure":
+			cg.CPUPressure, err = ProcessPressure(v.Data)
+		case "memory.current":
+			cg.MemoryCurrent, err = strconv.ParseUint(
+				strings.TrimSpace(string(v.Data)), 10, 64)
+		case "memory.stat":
+			cg.MemoryStat, err = processFlatKeyed(v.Data)
+		case "io.stat":
+			cg.IO, err = ProcessCgroupIOStat(v.Data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", v.Name, err)
+		}
+	}
+
+	return cg, nil
+}
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+const sampleCgroupCPUStat = `usage_usec %v
+user_usec %v
+system_usec %v
+nr_periods 0
+nr_throttled 0
+throttled_usec 0
+`
+
+const sampleCgroupPressure = `some avg10=1.50 avg60=0.75 avg300=0.25 total=%v
+full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+`
+
+const sampleCgroupMemoryStat = `anon 104857600
+file 52428800
+kernel_stack 163840
+pgfault %v
+pgmajfault 12
+`
+
+const sampleCgroupIOStat = `8:0 rbytes=%v wbytes=4096 rios=%v wios=1 dbytes=0 dios=0
+253:0 rbytes=0 wbytes=0 rios=0 wios=0 dbytes=0 dios=0
+`
+
+func sampleCgroup(t *testing.T, usage, user, system, some, pgfault, rbytes, rios int) *Cgroup {
+	t.Helper()
+
+	dir := types.PCCgroupRoot + "/system.slice/postgres.service/"
+	sections := []types.PCSection{
+		{
+			Name: dir + "cpu.stat",
+			Data: []byte(fmt.Sprintf(sampleCgroupCPUStat, usage,
+				user, system)),
+		},
+		{
+			Name: dir + "cpu.pressure",
+			Data: []byte(fmt.Sprintf(sampleCgroupPressure, some)),
+		},
+		{
+			Name: dir + "memory.current",
+			Data: []byte("209715200\n"),
+		},
+		{
+			Name: dir + "memory.stat",
+			Data: []byte(fmt.Sprintf(sampleCgroupMemoryStat,
+				pgfault)),
+		},
+		{
+			Name: dir + "io.stat",
+			Data: []byte(fmt.Sprintf(sampleCgroupIOStat, rbytes,
+				rios)),
+		},
+	}
+	cg, err := ProcessCgroup([]byte(types.EncodeSections(sections)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cg
+}
+
+func TestProcessCgroup(t *testing.T) {
+	cg := sampleCgroup(t, 3000000, 2000000, 1000000, 500000, 100, 8192, 2)
+	if cg.Name != "/system.slice/postgres.service" {
+		t.Fatalf("unexpected name: %v", cg.Name)
+	}
+	if cg.CPU.UsageUsec != 3000000 || cg.CPU.UserUsec != 2000000 ||
+		cg.CPU.SystemUsec != 1000000 {
+		t.Fatalf("unexpected cpu.stat: %+v", cg.CPU)
+	}
+	if cg.CPUPressure.Some.Avg10 != 1.5 ||
+		cg.CPUPressure.Some.Total != 500000 {
+		t.Fatalf("unexpected cpu.pressure: %+v", cg.CPUPressure)
+	}
+	if cg.MemoryCurrent != 209715200 || cg.MemoryStat["anon"] != 104857600 {
+		t.Fatalf("unexpected memory: %v %v", cg.MemoryCurrent,
+			cg.MemoryStat)
+	}
+	if len(cg.IO) != 2 || cg.IO["8:0"].RBytes != 8192 {
+		t.Fatalf("unexpected io.stat: %+v", cg.IO)
+	}
+
+	if name := CgroupName(types.PCCgroupRoot); name != "/" {
+		t.Fatalf("unexpected root name: %v", name)
+	}
+}
+
+func TestCubeCgroup(t *testing.T) {
+	t1 := sampleCgroup(t, 3000000, 2000000, 1000000, 500000, 100, 8192, 2)
+	t2 := sampleCgroup(t, 5500000, 4000000, 1500000, 1000000, 300, 1056768,
+		52)
+
+	// 5 second interval.
+	r, err := CubeCgroup(0, 0, 0, 0, t1, t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.CPU != 50 || r.UserT != 40 || r.System != 10 {
+		t.Fatalf("unexpected cpu: %+v", r)
+	}
+	if r.CPUSome != 10 {
+		t.Fatalf("unexpected pressure: %v", r.CPUSome)
+	}
+	if r.MemCurrent != 204800 || r.MemAnon != 102400 || r.MemFile != 51200 {
+		t.Fatalf("unexpected memory: %+v", r)
+	}
+	if r.PgFault != 40 || r.RKBytes != 204.8 || r.Rios != 10 {
+		t.Fatalf("unexpected rates: %+v", r)
+	}
+
+	// Different cgroups can not be cubed.
+	t2.Name = "/other"
+	if _, err := CubeCgroup(0, 0, 0, 0, t1, t2, 500); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,39 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// CPUFreq is parsed from the types.PCCPUFreqSystem system. It contains the
+// current frequency, in kHz, of every online CPU keyed by CPU number.
+type CPUFreq map[int]uint64
+
+// ProcessCPUFreq parses a cpufreq system measurement.
+func ProcessCPUFreq(b []byte) (CPUFreq, error) {
+	sections, err := types.DecodeSections(string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	cf := make(CPUFreq, len(sections))
+	for _, v := range sections {
+		// /sys/devices/system/cpu/cpu12/cpufreq/scaling_cur_freq
+		dir := filepath.Base(filepath.Dir(filepath.Dir(v.Name)))
+		cpu, err := strconv.Atoi(strings.TrimPrefix(dir, "cpu"))
+		if !strings.HasPrefix(dir, "cpu") || err != nil {
+			return nil, fmt.Errorf("invalid cpufreq cpu: %v", v.Name)
+		}
+		cf[cpu], err = strconv.ParseUint(strings.TrimSpace(string(v.Data)),
+			10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", v.Name, err)
+		}
+	}
+
+	return cf, nil
+}
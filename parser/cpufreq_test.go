@@ -0,0 +1,36 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+func TestProcessCPUFreq(t *testing.T) {
+	dir := "/sys/devices/system/cpu/"
+	m := types.EncodeSections([]types.PCSection{
+		{Name: dir + "cpu0/cpufreq/scaling_cur_freq", Data: []byte("2000000\n")},
+		{Name: dir + "cpu2/cpufreq/scaling_cur_freq", Data: []byte("3000000\n")},
+	})
+	cf, err := ProcessCPUFreq([]byte(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cf) != 2 || cf[0] != 2000000 || cf[2] != 3000000 {
+		t.Fatalf("unexpected cpufreq: %v", cf)
+	}
+
+	r, err := CubeCPUFreq(0, 0, 0, 0, cf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 3 || r[0].CPU != -1 || r[0].MHz != 2500 ||
+		r[1].CPU != 0 || r[1].MHz != 2000 || r[2].CPU != 2 ||
+		r[2].MHz != 3000 {
+		t.Fatalf("unexpected cpufreq: %+v", r)
+	}
+
+	if _, err := CubeCPUFreq(0, 0, 0, 0, CPUFreq{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -3,6 +3,9 @@ package parser
 import (
 	"fmt"
 	"math"
+	"slices"
+	"sort"
+	"time"
 
 	"github.com/businessperformancetuning/perfcollector/database"
 )
@@ -13,6 +16,12 @@ type NIC struct {
 	Speed  uint64
 }
 
+// NICKey returns the key of interface name of a site, host and run in the NIC
+// maps that are passed to CubeNetDev.
+func NICKey(site, host, run uint64, name string) string {
+	return fmt.Sprintf("%v %v %v %v", site, host, run, name)
+}
+
 func getAllBusy(t *CPUStat) (float64, float64) {
 	busy := t.User + t.System + t.Nice + t.Iowait + t.IRQ + t.SoftIRQ +
 		t.Steal
@@ -42,7 +51,9 @@ func calculateUser(t1, t2 *CPUStat) float64 {
 	if t2All <= t1All {
 		return 100
 	}
-	return math.Min(100, math.Max(0, (t2.User-t1.User)/(t2All-t1All)*100))
+	// User time includes guest time, report it separately like sar.
+	return math.Min(100, math.Max(0, ((t2.User-t2.Guest)-(t1.User-t1.Guest))/
+		(t2All-t1All)*100))
 }
 
 func calculateNice(t1, t2 *CPUStat) float64 {
@@ -55,7 +66,9 @@ func calculateNice(t1, t2 *CPUStat) float64 {
 	if t2All <= t1All {
 		return 100
 	}
-	return math.Min(100, math.Max(0, (t2.Nice-t1.Nice)/(t2All-t1All)*100))
+	// Nice time includes guest nice time, report it separately like sar.
+	return math.Min(100, math.Max(0, ((t2.Nice-t2.GuestNice)-
+		(t1.Nice-t1.GuestNice))/(t2All-t1All)*100))
 }
 
 func calculateSystem(t1, t2 *CPUStat) float64 {
@@ -84,6 +97,58 @@ func calculateSteal(t1, t2 *CPUStat) float64 {
 	return math.Min(100, math.Max(0, (t2.Steal-t1.Steal)/(t2All-t1All)*100))
 }
 
+func calculateIRQ(t1, t2 *CPUStat) float64 {
+	t1All, t1Busy := getAllBusy(t1)
+	t2All, t2Busy := getAllBusy(t2)
+
+	if t2Busy <= t1Busy {
+		return 0
+	}
+	if t2All <= t1All {
+		return 100
+	}
+	return math.Min(100, math.Max(0, (t2.IRQ-t1.IRQ)/(t2All-t1All)*100))
+}
+
+func calculateSoftIRQ(t1, t2 *CPUStat) float64 {
+	t1All, t1Busy := getAllBusy(t1)
+	t2All, t2Busy := getAllBusy(t2)
+
+	if t2Busy <= t1Busy {
+		return 0
+	}
+	if t2All <= t1All {
+		return 100
+	}
+	return math.Min(100, math.Max(0, (t2.SoftIRQ-t1.SoftIRQ)/(t2All-t1All)*100))
+}
+
+func calculateGuest(t1, t2 *CPUStat) float64 {
+	t1All, t1Busy := getAllBusy(t1)
+	t2All, t2Busy := getAllBusy(t2)
+
+	if t2Busy <= t1Busy {
+		return 0
+	}
+	if t2All <= t1All {
+		return 100
+	}
+	return math.Min(100, math.Max(0, (t2.Guest-t1.Guest)/(t2All-t1All)*100))
+}
+
+func calculateGuestNice(t1, t2 *CPUStat) float64 {
+	t1All, t1Busy := getAllBusy(t1)
+	t2All, t2Busy := getAllBusy(t2)
+
+	if t2Busy <= t1Busy {
+		return 0
+	}
+	if t2All <= t1All {
+		return 100
+	}
+	return math.Min(100, math.Max(0, (t2.GuestNice-t1.GuestNice)/(t2All-t1All)*100))
+}
+
 func calculateBusy(t1, t2 *CPUStat) float64 {
 	t1All, t1Busy := getAllBusy(t1)
 	t2All, t2Busy := getAllBusy(t2)
@@ -97,23 +162,47 @@ func calculateBusy(t1, t2 *CPUStat) float64 {
 	return math.Min(100, math.Max(0, (t2Busy-t1Busy)/(t2All-t1All)*100))
 }
 
+// CubeStat returns the CPU utilization between t1 and t2 like sar -P ALL -u
+// ALL. The first row is the total with CPU set to -1, followed by a row for
+// every CPU in order. CPUs that were offline in either sample, including
+// CPUs that were added or removed between the samples, are reported with all
+// fields set to zero. Utilization is relative to the jiffies that elapsed
+// between t1 and t2 and therefore does not depend on the collection frequency.
+// A ResetError is returned if the host rebooted between t1 and t2.
 func CubeStat(runID uint64, timestamp, start, duration int64, t1, t2 *Stat) ([]database.Stat, error) {
-	if len(t1.CPU) != len(t2.CPU) {
-		return nil, fmt.Errorf("invalid CPU length %v %v", len(t1.CPU),
-			len(t2.CPU))
+	if err := statReset(t1, t2); err != nil {
+		return nil, err
+	}
+	cpus := len(t1.CPU)
+	if len(t2.CPU) > cpus {
+		cpus = len(t2.CPU)
 	}
-	s := make([]database.Stat, len(t1.CPU)+1)
+	s := make([]database.Stat, cpus+1)
 
 	// Individual CPUs
 	for k := range s {
-		var cpu1, cpu2 *CPUStat
+		var cpu1, cpu2 CPUStat
 		idx := k - 1
 		if k == 0 {
-			cpu1 = &t1.CPUTotal
-			cpu2 = &t2.CPUTotal
+			cpu1 = t1.CPUTotal
+			cpu2 = t2.CPUTotal
 		} else {
-			cpu1 = &t1.CPU[idx]
-			cpu2 = &t2.CPU[idx]
+			if idx < len(t1.CPU) {
+				cpu1 = t1.CPU[idx]
+			}
+			if idx < len(t2.CPU) {
+				cpu2 = t2.CPU[idx]
+			}
+		}
+		if cpu1 == (CPUStat{}) || cpu2 == (CPUStat{}) {
+			s[k] = database.Stat{
+				RunID:     runID,
+				Timestamp: timestamp,
+				Start:     start,
+				Duration:  duration,
+				CPU:       idx,
+			}
+			continue
 		}
 		s[k] = database.Stat{
 			RunID:     runID,
@@ -121,12 +210,16 @@ func CubeStat(runID uint64, timestamp, start, duration int64, t1, t2 *Stat) ([]d
 			Start:     start,
 			Duration:  duration,
 			CPU:       idx,
-			UserT:     calculateUser(cpu1, cpu2),
-			Nice:      calculateNice(cpu1, cpu2),
-			System:    calculateSystem(cpu1, cpu2),
-			IOWait:    calculateIO(cpu1, cpu2),
-			Steal:     calculateSteal(cpu1, cpu2),
-			Idle:      100 - calculateBusy(cpu1, cpu2),
+			UserT:     calculateUser(&cpu1, &cpu2),
+			Nice:      calculateNice(&cpu1, &cpu2),
+			System:    calculateSystem(&cpu1, &cpu2),
+			IOWait:    calculateIO(&cpu1, &cpu2),
+			Steal:     calculateSteal(&cpu1, &cpu2),
+			IRQ:       calculateIRQ(&cpu1, &cpu2),
+			Soft:      calculateSoftIRQ(&cpu1, &cpu2),
+			Guest:     calculateGuest(&cpu1, &cpu2),
+			GNice:     calculateGuestNice(&cpu1, &cpu2),
+			Idle:      100 - calculateBusy(&cpu1, &cpu2),
 		}
 	}
 
@@ -146,7 +239,7 @@ func CubeMeminfo(runID uint64, timestamp, start, duration int64, mi *Meminfo) (*
 	if usedMem > mi.MemTotal {
 		usedMem = mi.MemTotal
 	}
-	return &database.Meminfo{
+	ms := &database.Meminfo{
 		RunID:     runID,
 		Timestamp: timestamp,
 		Start:     start,
@@ -165,7 +258,59 @@ func CubeMeminfo(runID uint64, timestamp, start, duration int64, mi *Meminfo) (*
 		Active:   mi.Active,
 		Inactive: mi.Inactive,
 		Dirty:    mi.Dirty,
-	}, nil
+	}
+
+	// kbswpfree kbswpused  %swpused  kbswpcad   %swpcad
+	if mi.SwapFree <= mi.SwapTotal {
+		ms.SwapFree = mi.SwapFree
+		ms.SwapUsed = mi.SwapTotal - mi.SwapFree
+		ms.SwapCached = mi.SwapCached
+		if mi.SwapTotal != 0 {
+			ms.PercentSwapUsed = float64(ms.SwapUsed) /
+				float64(mi.SwapTotal) * 100
+		}
+		if ms.SwapUsed != 0 {
+			ms.PercentSwapCached = float64(mi.SwapCached) /
+				float64(ms.SwapUsed) * 100
+		}
+	}
+
+	// kbhugfree kbhugused  %hugused
+	if mi.HugePagesFree <= mi.HugePagesTotal {
+		ms.HugeFree = mi.HugePagesFree * mi.Hugepagesize
+		ms.HugeUsed = (mi.HugePagesTotal - mi.HugePagesFree) *
+			mi.Hugepagesize
+		if mi.HugePagesTotal != 0 {
+			ms.PercentHugeUsed = float64(mi.HugePagesTotal-
+				mi.HugePagesFree) / float64(mi.HugePagesTotal) * 100
+		}
+	}
+
+	return ms, nil
+}
+
+// TVI returns the time interval, in jiffies, of the provided collection
+// frequency. Intervals are rounded to the nearest jiffy so that sub-second
+// frequencies are not truncated.
+func TVI(frequency time.Duration) uint64 {
+	return (uint64(frequency)*UserHZ + uint64(time.Second)/2) /
+		uint64(time.Second)
+}
+
+// Interval returns the time interval, in jiffies, between two measurements
+// that started at start1 and start2. Rates must be calculated over the time
+// that actually elapsed; a late or missed tick would otherwise inflate or
+// deflate them. The nominal collection frequency is used when the start
+// times are missing or out of order.
+func Interval(start1, start2 time.Time, frequency time.Duration) uint64 {
+	if start1.IsZero() || !start2.After(start1) {
+		return TVI(frequency)
+	}
+	tvi := TVI(start2.Sub(start1))
+	if tvi == 0 {
+		return TVI(frequency)
+	}
+	return tvi
 }
 
 func svalue(t1, t2, tvi uint64) float64 {
@@ -196,25 +341,88 @@ func ifUtil(name string, nics map[string]NIC, rxKBytes, txKBytes float64) float6
 	return 0
 }
 
-func CubeNetDev(site, host, run uint64, timestamp, start, duration int64, t1, t2 NetDev, tvi uint64, nics map[string]NIC) ([]database.NetDev, error) {
-	if len(t1) != len(t2) {
-		return nil, fmt.Errorf("invalid length %v %v",
-			len(t1), len(t2))
+// deviceEvents returns, in name order, an appear event for every device
+// that is only in names2 and a disappear event for every device that is only
+// in names1. Base provides the common fields of the events.
+func deviceEvents(base database.Event, kind string, names1, names2 map[string]struct{}) []database.Event {
+	names := make([]string, 0, len(names1)+len(names2))
+	for k := range names1 {
+		if _, ok := names2[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	for k := range names2 {
+		if _, ok := names1[k]; !ok {
+			names = append(names, k)
+		}
 	}
+	sort.Strings(names)
 
-	dnd := make([]database.NetDev, 0, len(t1))
+	events := make([]database.Event, 0, len(names))
+	for _, k := range names {
+		e := base
+		e.Name = k
+		if _, ok := names2[k]; ok {
+			e.Type = database.EventAppear
+			e.Description = fmt.Sprintf("%v %v appeared", kind, k)
+		} else {
+			e.Type = database.EventDisappear
+			e.Description = fmt.Sprintf("%v %v disappeared", kind, k)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// CubeNetDev returns the interface statistics between t1 and t2 like sar -n
+// DEV. Only interfaces that are in both samples are cubed; an event is
+// returned for every interface that appeared, disappeared or whose counters
+// went backwards because it was recreated. Those interfaces are cubed again
+// in the next interval.
+func CubeNetDev(site, host, run uint64, timestamp, start, duration int64, t1, t2 NetDev, tvi uint64, nics map[string]NIC) ([]database.NetDev, []database.Event, error) {
+	base := database.Event{
+		RunID:     run,
+		Timestamp: timestamp,
+		Start:     start,
+		Duration:  duration,
+		System:    "/proc/net/dev",
+	}
+	names1 := make(map[string]struct{}, len(t1))
 	for k := range t1 {
-		cur, ok := t2[k]
-		if !ok {
-			return nil, fmt.Errorf("current interface not found: %v",
-				k)
+		names1[k] = struct{}{}
+	}
+	names2 := make(map[string]struct{}, len(t2))
+	for k := range t2 {
+		names2[k] = struct{}{}
+	}
+	events := deviceEvents(base, "interface", names1, names2)
+
+	names := make([]string, 0, len(t2))
+	for k := range t2 {
+		if _, ok := t1[k]; ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	dnd := make([]database.NetDev, 0, len(names))
+	for _, k := range names {
+		cur := t2[k]
+		if netDevDecreased(t1[k], cur) {
+			e := base
+			e.Type = database.EventReset
+			e.Name = k
+			e.Description = fmt.Sprintf("interface %v counters "+
+				"decreased", k)
+			events = append(events, e)
+			continue
 		}
 
 		rxBytes := svalue(t1[k].RxBytes, cur.RxBytes, tvi)
 		txBytes := svalue(t1[k].TxBytes, cur.TxBytes, tvi)
 		rxKBytes := rxBytes / 1024
 		txKBytes := txBytes / 1024
-		cacheName := fmt.Sprintf("%v %v %v %v", site, host, run, k)
+		cacheName := NICKey(site, host, run, k)
 		dnd = append(dnd, database.NetDev{
 			RunID:     run,
 			Timestamp: timestamp,
@@ -230,44 +438,613 @@ func CubeNetDev(site, host, run uint64, timestamp, start, duration int64, t1, t2
 			TxCompressed: svalue(t1[k].TxCompressed, cur.TxCompressed, tvi),
 			RxMulticast:  svalue(t1[k].RxMulticast, cur.RxMulticast, tvi),
 			// XXX not sure why * 10 since this code is a duplcate of sar
-			IfUtil: ifUtil(cacheName, nics, rxKBytes, txKBytes) * 10,
+			IfUtil:     ifUtil(cacheName, nics, rxKBytes, txKBytes) * 10,
+			RxErrors:   svalue(t1[k].RxErrors, cur.RxErrors, tvi),
+			TxErrors:   svalue(t1[k].TxErrors, cur.TxErrors, tvi),
+			Collisions: svalue(t1[k].TxCollisions, cur.TxCollisions, tvi),
+			RxDropped:  svalue(t1[k].RxDropped, cur.RxDropped, tvi),
+			TxDropped:  svalue(t1[k].TxDropped, cur.TxDropped, tvi),
+			TxCarrier:  svalue(t1[k].TxCarrier, cur.TxCarrier, tvi),
+			RxFrame:    svalue(t1[k].RxFrame, cur.RxFrame, tvi),
+			RxFIFO:     svalue(t1[k].RxFIFO, cur.RxFIFO, tvi),
+			TxFIFO:     svalue(t1[k].TxFIFO, cur.TxFIFO, tvi),
 		})
 	}
 
-	return dnd, nil
+	return dnd, events, nil
 }
 
-func CubeDiskstats(runID uint64, timestamp, start, duration int64, t1, t2 []Diskstats, tvi uint64) ([]database.Diskstat, error) {
-	if len(t1) != len(t2) {
-		return nil, fmt.Errorf("invalid length %v %v",
-			len(t1), len(t2))
+// CubeDiskstats returns the disk statistics between t1 and t2 like iostat
+// -dx. Devices are matched by name and only devices that are in both samples
+// are cubed; an event is returned for every device that appeared,
+// disappeared or whose counters went backwards. Those devices are cubed
+// again in the next interval.
+func CubeDiskstats(runID uint64, timestamp, start, duration int64, t1, t2 []Diskstats, tvi uint64) ([]database.Diskstat, []database.Event, error) {
+	base := database.Event{
+		RunID:     runID,
+		Timestamp: timestamp,
+		Start:     start,
+		Duration:  duration,
+		System:    "/proc/diskstats",
 	}
-
-	ds := make([]database.Diskstat, 0, len(t1))
+	prev := make(map[string]*Diskstats, len(t1))
+	names1 := make(map[string]struct{}, len(t1))
 	for k := range t1 {
-		if t1[k].ReadIOs == 0 && t1[k].WriteIOs == 0 && t1[k].DiscardIOs == 0 {
+		prev[t1[k].DeviceName] = &t1[k]
+		names1[t1[k].DeviceName] = struct{}{}
+	}
+	names2 := make(map[string]struct{}, len(t2))
+	for k := range t2 {
+		names2[t2[k].DeviceName] = struct{}{}
+	}
+	events := deviceEvents(base, "device", names1, names2)
+
+	ds := make([]database.Diskstat, 0, len(t2))
+	for k := range t2 {
+		d1, ok := prev[t2[k].DeviceName]
+		if !ok {
+			continue
+		}
+		d2 := &t2[k]
+		if diskstatsDecreased(d1, d2) {
+			e := base
+			e.Type = database.EventReset
+			e.Name = d2.DeviceName
+			e.Description = fmt.Sprintf("device %v counters "+
+				"decreased", d2.DeviceName)
+			events = append(events, e)
+			continue
+		}
+		if d1.ReadIOs == 0 && d1.WriteIOs == 0 && d1.DiscardIOs == 0 {
 			// Unused device, skip.
 			continue
 		}
 
-		t1TotalIOs := t1[k].ReadIOs + t1[k].WriteIOs + t1[k].DiscardIOs
-		t2TotalIOs := t2[k].ReadIOs + t2[k].WriteIOs + t2[k].DiscardIOs
+		t1TotalIOs := d1.ReadIOs + d1.WriteIOs + d1.DiscardIOs
+		t2TotalIOs := d2.ReadIOs + d2.WriteIOs + d2.DiscardIOs
+		t1TotalSectors := d1.ReadSectors + d1.WriteSectors +
+			d1.DiscardSectors
+		t2TotalSectors := d2.ReadSectors + d2.WriteSectors +
+			d2.DiscardSectors
 		ds = append(ds, database.Diskstat{
 			RunID:     runID,
 			Timestamp: timestamp,
 			Start:     start,
 			Duration:  duration,
 
-			Name:  t1[k].DeviceName,
+			Name:  d2.DeviceName,
 			Tps:   svalue(t1TotalIOs, t2TotalIOs, tvi),
-			Rtps:  svalue(t1[k].ReadIOs, t2[k].ReadIOs, tvi),
-			Wtps:  svalue(t1[k].WriteIOs, t2[k].WriteIOs, tvi),
-			Dtps:  svalue(t1[k].DiscardIOs, t2[k].DiscardIOs, tvi),
-			Bread: svalue(t1[k].ReadSectors, t2[k].ReadSectors, tvi),
-			Bwrtn: svalue(t1[k].WriteSectors, t2[k].WriteSectors, tvi),
-			Bdscd: svalue(t1[k].DiscardSectors, t2[k].DiscardSectors, tvi),
+			Rtps:  svalue(d1.ReadIOs, d2.ReadIOs, tvi),
+			Wtps:  svalue(d1.WriteIOs, d2.WriteIOs, tvi),
+			Dtps:  svalue(d1.DiscardIOs, d2.DiscardIOs, tvi),
+			Bread: svalue(d1.ReadSectors, d2.ReadSectors, tvi),
+			Bwrtn: svalue(d1.WriteSectors, d2.WriteSectors, tvi),
+			Bdscd: svalue(d1.DiscardSectors, d2.DiscardSectors, tvi),
+
+			// Ticks are in milliseconds.
+			Rrqm:   svalue(d1.ReadMerges, d2.ReadMerges, tvi),
+			Wrqm:   svalue(d1.WriteMerges, d2.WriteMerges, tvi),
+			RAwait: await(d1.ReadTicks, d2.ReadTicks, d1.ReadIOs, d2.ReadIOs),
+			WAwait: await(d1.WriteTicks, d2.WriteTicks, d1.WriteIOs, d2.WriteIOs),
+			AquSz:  svalue(d1.WeightedIOTicks, d2.WeightedIOTicks, tvi) / 1000,
+			AreqSz: areqSz(t1TotalSectors, t2TotalSectors, t1TotalIOs, t2TotalIOs),
+			Util:   svalue(d1.IOsTotalTicks, d2.IOsTotalTicks, tvi) / 10,
 		})
 	}
 
-	return ds, nil
+	return ds, events, nil
+}
+
+// await returns the average time in milliseconds that the I/Os completed
+// between t1 and t2 took, including the time spent in the queue.
+func await(ticks1, ticks2, ios1, ios2 uint64) float64 {
+	if ios2 <= ios1 {
+		return 0
+	}
+	return (float64(ticks2) - float64(ticks1)) /
+		(float64(ios2) - float64(ios1))
+}
+
+// areqSz returns the average size in kB of the I/Os completed between t1 and
+// t2.
+func areqSz(sectors1, sectors2, ios1, ios2 uint64) float64 {
+	if ios2 <= ios1 {
+		return 0
+	}
+	// Sectors are 512 bytes.
+	return (float64(sectors2) - float64(sectors1)) /
+		(float64(ios2) - float64(ios1)) / 2
+}
+
+// CubePidstat returns the per-process rates between t1 and t2. Processes that
+// were started after t1 are omitted, they are reported at the next interval.
+// CPU percentages are relative to a single CPU, as with pidstat, and may
+// therefore exceed 100. I/O rates are -1 when the collector was not
+// permitted to read the I/O counters of a process.
+func CubePidstat(runID uint64, timestamp, start, duration int64, t1, t2 Pidstat, tvi uint64) ([]database.Pidstat, error) {
+	pids := make([]int, 0, len(t2))
+	for k := range t2 {
+		pids = append(pids, k)
+	}
+	sort.Ints(pids)
+
+	ps := make([]database.Pidstat, 0, len(pids))
+	for _, pid := range pids {
+		cur := t2[pid]
+		prev, ok := t1[pid]
+		if !ok || prev.Stat.StartTime != cur.Stat.StartTime {
+			// New process or pid was reused.
+			continue
+		}
+
+		p := database.Pidstat{
+			RunID:     runID,
+			Timestamp: timestamp,
+			Start:     start,
+			Duration:  duration,
+
+			PID:     int64(pid),
+			UID:     cur.Status.UID,
+			Name:    cur.Stat.Comm,
+			UserT:   svalue(prev.Stat.UTime, cur.Stat.UTime, tvi),
+			System:  svalue(prev.Stat.STime, cur.Stat.STime, tvi),
+			MinFlt:  svalue(prev.Stat.MinFlt, cur.Stat.MinFlt, tvi),
+			MajFlt:  svalue(prev.Stat.MajFlt, cur.Stat.MajFlt, tvi),
+			VSZ:     cur.Status.VmSize,
+			RSS:     cur.Status.VmRSS,
+			KBRead:  -1,
+			KBWrite: -1,
+			KBCcwr:  -1,
+			Cswch: svalue(prev.Status.VoluntaryCtxtSwitches,
+				cur.Status.VoluntaryCtxtSwitches, tvi),
+			Nvcswch: svalue(prev.Status.NonvoluntaryCtxtSwitches,
+				cur.Status.NonvoluntaryCtxtSwitches, tvi),
+			Threads: cur.Stat.NumThreads,
+		}
+		p.CPU = p.UserT + p.System
+		if prev.IO != nil && cur.IO != nil {
+			p.KBRead = svalue(prev.IO.ReadBytes, cur.IO.ReadBytes,
+				tvi) / 1024
+			p.KBWrite = svalue(prev.IO.WriteBytes,
+				cur.IO.WriteBytes, tvi) / 1024
+			p.KBCcwr = svalue(prev.IO.CancelledWriteBytes,
+				cur.IO.CancelledWriteBytes, tvi) / 1024
+		}
+		ps = append(ps, p)
+	}
+
+	return ps, nil
+}
+
+// usecPercent returns the percentage of the interval tvi, in jiffies, that
+// the microsecond counter advanced.
+func usecPercent(t1, t2, tvi uint64) float64 {
+	return (float64(t2) - float64(t1)) /
+		(float64(tvi) * 1000000 / UserHZ) * 100
+}
+
+// CubeCgroup returns the rates of a cgroup between t1 and t2. CPU
+// percentages are relative to a single CPU and may therefore exceed 100. A
+// ResetError is returned if a counter went backwards, e.g. because the
+// cgroup was created again or the host rebooted.
+func CubeCgroup(runID uint64, timestamp, start, duration int64, t1, t2 *Cgroup, tvi uint64) (*database.Cgroup, error) {
+	if t1.Name != t2.Name {
+		return nil, fmt.Errorf("invalid cgroup %v %v", t1.Name,
+			t2.Name)
+	}
+
+	var rb1, rb2, wb1, wb2, ri1, ri2, wi1, wi2 uint64
+	for _, v := range t1.IO {
+		rb1 += v.RBytes
+		wb1 += v.WBytes
+		ri1 += v.RIOs
+		wi1 += v.WIOs
+	}
+	for _, v := range t2.IO {
+		rb2 += v.RBytes
+		wb2 += v.WBytes
+		ri2 += v.RIOs
+		wi2 += v.WIOs
+	}
+	if cgroupDecreased(t1, t2, [4]uint64{rb1, wb1, ri1, wi1},
+		[4]uint64{rb2, wb2, ri2, wi2}) {
+		return nil, ResetError{
+			Reason: "cgroup " + t2.Name + " counters decreased",
+		}
+	}
+
+	return &database.Cgroup{
+		RunID:     runID,
+		Timestamp: timestamp,
+		Start:     start,
+		Duration:  duration,
+
+		Name:   t2.Name,
+		UserT:  usecPercent(t1.CPU.UserUsec, t2.CPU.UserUsec, tvi),
+		System: usecPercent(t1.CPU.SystemUsec, t2.CPU.SystemUsec, tvi),
+		CPU:    usecPercent(t1.CPU.UsageUsec, t2.CPU.UsageUsec, tvi),
+		Throttled: usecPercent(t1.CPU.ThrottledUsec,
+			t2.CPU.ThrottledUsec, tvi),
+		NrThrottled: svalue(t1.CPU.NrThrottled, t2.CPU.NrThrottled,
+			tvi),
+		MemCurrent: t2.MemoryCurrent / 1024,
+		MemAnon:    t2.MemoryStat["anon"] / 1024,
+		MemFile:    t2.MemoryStat["file"] / 1024,
+		PgFault: svalue(t1.MemoryStat["pgfault"],
+			t2.MemoryStat["pgfault"], tvi),
+		PgMajFault: svalue(t1.MemoryStat["pgmajfault"],
+			t2.MemoryStat["pgmajfault"], tvi),
+		RKBytes: svalue(rb1, rb2, tvi) / 1024,
+		WKBytes: svalue(wb1, wb2, tvi) / 1024,
+		Rios:    svalue(ri1, ri2, tvi),
+		Wios:    svalue(wi1, wi2, tvi),
+		CPUSome: usecPercent(t1.CPUPressure.Some.Total,
+			t2.CPUPressure.Some.Total, tvi),
+		CPUFull: usecPercent(t1.CPUPressure.Full.Total,
+			t2.CPUPressure.Full.Total, tvi),
+	}, nil
+}
+
+// CubePressure returns the stall percentages of a pressure stall information
+// resource, i.e. cpu, memory or io, between t1 and t2. The kernel averages of
+// t2 are returned as is.
+func CubePressure(runID uint64, timestamp, start, duration int64, resource string, t1, t2 *Pressure, tvi uint64) (*database.Pressure, error) {
+	if t2.Some.Total < t1.Some.Total || t2.Full.Total < t1.Full.Total {
+		return nil, fmt.Errorf("invalid pressure totals %v", resource)
+	}
+
+	return &database.Pressure{
+		RunID:     runID,
+		Timestamp: timestamp,
+		Start:     start,
+		Duration:  duration,
+
+		Resource:   resource,
+		SomeAvg10:  t2.Some.Avg10,
+		SomeAvg60:  t2.Some.Avg60,
+		SomeAvg300: t2.Some.Avg300,
+		SomeStall:  usecPercent(t1.Some.Total, t2.Some.Total, tvi),
+		FullAvg10:  t2.Full.Avg10,
+		FullAvg60:  t2.Full.Avg60,
+		FullAvg300: t2.Full.Avg300,
+		FullStall:  usecPercent(t1.Full.Total, t2.Full.Total, tvi),
+	}, nil
+}
+
+// CubeVmstat returns the paging and swapping rates between t1 and t2. A
+// ResetError is returned if a counter went backwards.
+func CubeVmstat(runID uint64, timestamp, start, duration int64, t1, t2 *Vmstat, tvi uint64) (*database.Vmstat, error) {
+	if vmstatDecreased(t1, t2) {
+		return nil, ResetError{Reason: "vmstat counters decreased"}
+	}
+
+	// pgpgin/s pgpgout/s fault/s majflt/s pgfree/s pgscank/s pgscand/s pgsteal/s %vmeff
+	// pswpin/s pswpout/s
+	vs := &database.Vmstat{
+		RunID:     runID,
+		Timestamp: timestamp,
+		Start:     start,
+		Duration:  duration,
+
+		Pgpgin:  svalue(t1.Pgpgin, t2.Pgpgin, tvi),
+		Pgpgout: svalue(t1.Pgpgout, t2.Pgpgout, tvi),
+		Fault:   svalue(t1.Pgfault, t2.Pgfault, tvi),
+		MajFlt:  svalue(t1.Pgmajfault, t2.Pgmajfault, tvi),
+		Pgfree:  svalue(t1.Pgfree, t2.Pgfree, tvi),
+		Pgscank: svalue(t1.Pgscank, t2.Pgscank, tvi),
+		Pgscand: svalue(t1.Pgscand, t2.Pgscand, tvi),
+		Pgsteal: svalue(t1.Pgsteal, t2.Pgsteal, tvi),
+		Pswpin:  svalue(t1.Pswpin, t2.Pswpin, tvi),
+		Pswpout: svalue(t1.Pswpout, t2.Pswpout, tvi),
+	}
+
+	// Like sar, only report the efficiency when pages were scanned.
+	if scanned := vs.Pgscank + vs.Pgscand; scanned > 0 {
+		vs.Vmeff = vs.Pgsteal / scanned * 100
+	}
+
+	return vs, nil
+}
+
+// CubeLoadavg returns the run queue and load averages of la. The run queue is
+// always taken from la, so that it is sampled at the same instant as the load
+// averages; like sar, the task reading the counters is not counted. The
+// blocked processes are only reported by /proc/stat and are taken from st, the
+// most recent /proc/stat measurement of the collection, which may be one tick
+// older than la. Without st blocked is -1.
+func CubeLoadavg(runID uint64, timestamp, start, duration int64, la *Loadavg, st *Stat) (*database.Loadavg, error) {
+	// runq-sz plist-sz ldavg-1 ldavg-5 ldavg-15 blocked
+	l := &database.Loadavg{
+		RunID:     runID,
+		Timestamp: timestamp,
+		Start:     start,
+		Duration:  duration,
+
+		RunqSz:  la.Runnable,
+		PlistSz: la.Tasks,
+		Ldavg1:  la.Load1,
+		Ldavg5:  la.Load5,
+		Ldavg15: la.Load15,
+		Blocked: -1,
+	}
+	if st != nil {
+		l.Blocked = int64(st.ProcessesBlocked)
+	}
+	if l.RunqSz > 0 {
+		l.RunqSz--
+	}
+
+	return l, nil
+}
+
+// CubeNetSNMP returns the IP, TCP and UDP rates of /proc/net/snmp between t1
+// and t2. A ResetError is returned if a counter went backwards.
+func CubeNetSNMP(runID uint64, timestamp, start, duration int64, t1, t2 NetProto, tvi uint64) (*database.NetSNMP, error) {
+	// IP: irec/s fwddgm/s idel/s orq/s asmrq/s asmok/s fragok/s fragcrt/s
+	// TCP: active/s passive/s iseg/s oseg/s
+	// ETCP: atmptf/s estres/s retrans/s isegerr/s orsts/s
+	// UDP: idgm/s odgm/s noport/s idgmerr/s
+	var reset bool
+	rate := func(proto, name string) float64 {
+		c1, c2 := t1.counter(proto, name), t2.counter(proto, name)
+		reset = reset || c2 < c1
+		return svalue(c1, c2, tvi)
+	}
+
+	ns := &database.NetSNMP{
+		RunID:     runID,
+		Timestamp: timestamp,
+		Start:     start,
+		Duration:  duration,
+
+		Irec:    rate("Ip", "InReceives"),
+		Fwddgm:  rate("Ip", "ForwDatagrams"),
+		Idel:    rate("Ip", "InDelivers"),
+		Orq:     rate("Ip", "OutRequests"),
+		Asmrq:   rate("Ip", "ReasmReqds"),
+		Asmok:   rate("Ip", "ReasmOKs"),
+		Fragok:  rate("Ip", "FragOKs"),
+		Fragcrt: rate("Ip", "FragCreates"),
+		Active:  rate("Tcp", "ActiveOpens"),
+		Passive: rate("Tcp", "PassiveOpens"),
+		Iseg:    rate("Tcp", "InSegs"),
+		Oseg:    rate("Tcp", "OutSegs"),
+		Atmptf:  rate("Tcp", "AttemptFails"),
+		Estres:  rate("Tcp", "EstabResets"),
+		Retrans: rate("Tcp", "RetransSegs"),
+		Isegerr: rate("Tcp", "InErrs"),
+		Orsts:   rate("Tcp", "OutRsts"),
+		Idgm:    rate("Udp", "InDatagrams"),
+		Odgm:    rate("Udp", "OutDatagrams"),
+		Noport:  rate("Udp", "NoPorts"),
+		Idgmerr: rate("Udp", "InErrors"),
+	}
+	if reset {
+		return nil, ResetError{Reason: "snmp counters decreased"}
+	}
+	return ns, nil
+}
+
+// CubeNetstat returns the extended TCP rates of /proc/net/netstat between t1
+// and t2. A ResetError is returned if a counter went backwards.
+func CubeNetstat(runID uint64, timestamp, start, duration int64, t1, t2 NetProto, tvi uint64) (*database.Netstat, error) {
+	// lstovf/s lstdrp/s tmout/s synretr/s fretr/s lostretr/s abortto/s
+	var reset bool
+	rate := func(name string) float64 {
+		c1, c2 := t1.counter("TcpExt", name), t2.counter("TcpExt", name)
+		reset = reset || c2 < c1
+		return svalue(c1, c2, tvi)
+	}
+
+	ns := &database.Netstat{
+		RunID:     runID,
+		Timestamp: timestamp,
+		Start:     start,
+		Duration:  duration,
+
+		Lstovf:   rate("ListenOverflows"),
+		Lstdrp:   rate("ListenDrops"),
+		Tmout:    rate("TCPTimeouts"),
+		Synretr:  rate("TCPSynRetrans"),
+		Fretr:    rate("TCPFastRetrans"),
+		Lostretr: rate("TCPLostRetransmit"),
+		Abortto:  rate("TCPAbortOnTimeout"),
+	}
+	if reset {
+		return nil, ResetError{Reason: "netstat counters decreased"}
+	}
+	return ns, nil
+}
+
+// interruptDelta returns the difference between two interrupt counts. The
+// kernel keeps the per CPU counts in 32 bits so they wrap on busy systems.
+func interruptDelta(c1, c2 uint64) (uint64, bool) {
+	if c2 >= c1 {
+		return c2 - c1, true
+	}
+	if c1 > math.MaxUint32 {
+		return 0, false
+	}
+	return c2 + math.MaxUint32 + 1 - c1, true
+}
+
+// CubeInterrupts returns the rates of /proc/interrupts or /proc/softirqs
+// between t1 and t2 like sar -I ALL. Every source has a row with CPU set to -1
+// for all CPUs combined followed by a row for every CPU. Sources that only
+// report a total, such as ERR, only have the combined row. Sources that were
+// registered after t1 are skipped.
+func CubeInterrupts(runID uint64, timestamp, start, duration int64, t1, t2 *Interrupts, tvi uint64) ([]database.Interrupt, error) {
+	if !slices.Equal(t1.CPU, t2.CPU) {
+		return nil, fmt.Errorf("invalid interrupts CPUs %v %v", t1.CPU,
+			t2.CPU)
+	}
+	sources := make(map[string]*InterruptSource, len(t1.Sources))
+	for k := range t1.Sources {
+		sources[t1.Sources[k].Name] = &t1.Sources[k]
+	}
+
+	row := func(cpu int, is *InterruptSource, delta uint64) database.Interrupt {
+		return database.Interrupt{
+			RunID:     runID,
+			Timestamp: timestamp,
+			Start:     start,
+			Duration:  duration,
+
+			CPU:         cpu,
+			Source:      is.Name,
+			Description: is.Description,
+			Intr:        svalue(0, delta, tvi),
+		}
+	}
+
+	in := make([]database.Interrupt, 0, len(t2.Sources)*(len(t2.CPU)+1))
+	for k := range t2.Sources {
+		is2 := &t2.Sources[k]
+		is1, ok := sources[is2.Name]
+		if !ok || len(is1.Counts) != len(is2.Counts) {
+			continue
+		}
+		deltas := make([]uint64, len(is2.Counts))
+		var total uint64
+		for i := range is2.Counts {
+			deltas[i], ok = interruptDelta(is1.Counts[i],
+				is2.Counts[i])
+			if !ok {
+				return nil, fmt.Errorf("invalid interrupts count %v",
+					is2.Name)
+			}
+			total += deltas[i]
+		}
+
+		in = append(in, row(-1, is2, total))
+		if len(deltas) != len(t2.CPU) {
+			continue
+		}
+		for i, cpu := range t2.CPU {
+			in = append(in, row(cpu, is2, deltas[i]))
+		}
+	}
+
+	return in, nil
+}
+
+// CubeStatfs returns the capacity and inode usage of every filesystem like
+// sar -F. Filesystems that do not report inodes have zero inode usage.
+func CubeStatfs(runID uint64, timestamp, start, duration int64, st Statfs) ([]database.Statfs, error) {
+	s := make([]database.Statfs, 0, len(st))
+	for _, fs := range st {
+		if fs.Blocks == 0 {
+			continue
+		}
+		if fs.BlocksFree > fs.Blocks || fs.FilesFree > fs.Files {
+			return nil, fmt.Errorf("invalid statfs %v", fs.MountPoint)
+		}
+		mb := func(blocks uint64) float64 {
+			return float64(blocks) * float64(fs.BlockSize) / 1024 / 1024
+		}
+		r := database.Statfs{
+			RunID:     runID,
+			Timestamp: timestamp,
+			Start:     start,
+			Duration:  duration,
+
+			Name:       fs.Device,
+			MountPoint: fs.MountPoint,
+			FSType:     fs.FSType,
+			MBSize:     mb(fs.Blocks),
+			MBFree:     mb(fs.BlocksFree),
+			MBUsed:     mb(fs.Blocks - fs.BlocksFree),
+			FSUsed: float64(fs.Blocks-fs.BlocksFree) /
+				float64(fs.Blocks) * 100,
+			Inodes: fs.Files,
+			IFree:  fs.FilesFree,
+			IUsed:  fs.Files - fs.FilesFree,
+		}
+		if fs.BlocksAvail < fs.Blocks {
+			r.UFSUsed = float64(fs.Blocks-fs.BlocksAvail) /
+				float64(fs.Blocks) * 100
+		}
+		if fs.Files != 0 {
+			r.PercentIUsed = float64(r.IUsed) / float64(fs.Files) * 100
+		}
+		s = append(s, r)
+	}
+
+	return s, nil
+}
+
+// CubeCPUFreq returns the current frequency of every CPU like sar -m CPU. The
+// first row is the average of all CPUs with CPU set to -1, followed by a row
+// for every CPU in order.
+func CubeCPUFreq(runID uint64, timestamp, start, duration int64, cf CPUFreq) ([]database.CPUFreq, error) {
+	if len(cf) == 0 {
+		return nil, fmt.Errorf("no cpufreq")
+	}
+	cpus := make([]int, 0, len(cf))
+	var total uint64
+	for k, v := range cf {
+		cpus = append(cpus, k)
+		total += v
+	}
+	sort.Ints(cpus)
+
+	row := func(cpu int, khz float64) database.CPUFreq {
+		return database.CPUFreq{
+			RunID:     runID,
+			Timestamp: timestamp,
+			Start:     start,
+			Duration:  duration,
+
+			CPU: cpu,
+			MHz: khz / 1000,
+		}
+	}
+	s := make([]database.CPUFreq, 0, len(cf)+1)
+	s = append(s, row(-1, float64(total)/float64(len(cf))))
+	for _, cpu := range cpus {
+		s = append(s, row(cpu, float64(cf[cpu])))
+	}
+
+	return s, nil
+}
+
+// CubeNodeMeminfo returns the memory usage of every NUMA node in node order.
+func CubeNodeMeminfo(runID uint64, timestamp, start, duration int64, nm NodeMeminfo) ([]database.Node, error) {
+	nodes := make([]int, 0, len(nm))
+	for k := range nm {
+		nodes = append(nodes, k)
+	}
+	sort.Ints(nodes)
+
+	s := make([]database.Node, 0, len(nm))
+	for _, node := range nodes {
+		mi := nm[node]
+		if mi["MemFree"] > mi["MemTotal"] {
+			return nil, fmt.Errorf("invalid node %v meminfo", node)
+		}
+		n := database.Node{
+			RunID:     runID,
+			Timestamp: timestamp,
+			Start:     start,
+			Duration:  duration,
+
+			Node:      node,
+			MemTotal:  mi["MemTotal"],
+			MemFree:   mi["MemFree"],
+			MemUsed:   mi["MemTotal"] - mi["MemFree"],
+			Active:    mi["Active"],
+			Inactive:  mi["Inactive"],
+			FilePages: mi["FilePages"],
+			AnonPages: mi["AnonPages"],
+			Slab:      mi["Slab"],
+			Dirty:     mi["Dirty"],
+		}
+		if n.MemTotal != 0 {
+			n.PercentUsed = float64(n.MemUsed) /
+				float64(n.MemTotal) * 100
+		}
+		s = append(s, n)
+	}
+
+	return s, nil
 }
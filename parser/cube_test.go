@@ -0,0 +1,384 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTVI(t *testing.T) {
+	tests := []struct {
+		frequency time.Duration
+		want      uint64
+	}{
+		{5 * time.Second, 500},
+		{time.Second, 100},
+		{500 * time.Millisecond, 50},
+		{100 * time.Millisecond, 10},
+		{105 * time.Millisecond, 11},
+		{104 * time.Millisecond, 10},
+	}
+	for _, tt := range tests {
+		if got := TVI(tt.frequency); got != tt.want {
+			t.Errorf("TVI(%v) = %v, want %v", tt.frequency, got,
+				tt.want)
+		}
+	}
+}
+
+func TestSvalueSubSecond(t *testing.T) {
+	// 25 packets in 100ms is 250 packets per second.
+	got := svalue(100, 125, TVI(100*time.Millisecond))
+	if got != 250 {
+		t.Errorf("expected 250, got %v", got)
+	}
+}
+
+func TestCubeStatAll(t *testing.T) {
+	c1 := CPUStat{User: 100, Nice: 100, System: 100, Idle: 100,
+		Iowait: 100, IRQ: 100, SoftIRQ: 100, Steal: 100, Guest: 100,
+		GuestNice: 100}
+	c2 := CPUStat{User: 124, Nice: 108, System: 108, Idle: 108,
+		Iowait: 104, IRQ: 104, SoftIRQ: 104, Steal: 104, Guest: 108,
+		GuestNice: 104}
+	t1 := Stat{CPUTotal: c1, CPU: []CPUStat{c1, {}}}
+	t2 := Stat{CPUTotal: c2, CPU: []CPUStat{c2, {}}}
+
+	r, err := CubeStat(0, 0, 0, 0, &t1, &t2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 3 {
+		t.Fatalf("unexpected length: %v", len(r))
+	}
+	for _, s := range r[:2] {
+		if s.UserT != 25 || s.Nice != 6.25 || s.System != 12.5 ||
+			s.IOWait != 6.25 || s.Steal != 6.25 || s.IRQ != 6.25 ||
+			s.Soft != 6.25 || s.Guest != 12.5 || s.GNice != 6.25 ||
+			s.Idle != 12.5 {
+			t.Fatalf("unexpected stat: %+v", s)
+		}
+	}
+	if r[0].CPU != -1 || r[1].CPU != 0 || r[2].CPU != 1 {
+		t.Fatalf("unexpected cpus: %v %v %v", r[0].CPU, r[1].CPU,
+			r[2].CPU)
+	}
+
+	// Offline CPUs are all zeroes.
+	if r[2].UserT != 0 || r[2].Idle != 0 {
+		t.Fatalf("unexpected offline stat: %+v", r[2])
+	}
+}
+
+func TestCubeStatHotplug(t *testing.T) {
+	c1 := CPUStat{User: 100, Idle: 100}
+	c2 := CPUStat{User: 150, Idle: 150}
+
+	// CPU 1 was added and CPU 2 is no longer reported.
+	t1 := Stat{CPUTotal: c1, CPU: []CPUStat{c1}}
+	t2 := Stat{CPUTotal: c2, CPU: []CPUStat{c2, c2}}
+	r, err := CubeStat(0, 0, 0, 0, &t1, &t2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 3 || r[0].UserT != 50 || r[1].UserT != 50 ||
+		r[2].CPU != 1 || r[2].UserT != 0 || r[2].Idle != 0 {
+		t.Fatalf("unexpected stat: %+v", r)
+	}
+
+	t1 = Stat{CPUTotal: c1, CPU: []CPUStat{c1, c1, c1}}
+	t2 = Stat{CPUTotal: c2, CPU: []CPUStat{c2}}
+	r, err = CubeStat(0, 0, 0, 0, &t1, &t2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 4 || r[0].UserT != 50 || r[1].UserT != 50 ||
+		r[3].CPU != 2 || r[3].UserT != 0 {
+		t.Fatalf("unexpected stat: %+v", r)
+	}
+}
+
+func TestCubeMeminfoSwapHuge(t *testing.T) {
+	mi := Meminfo{
+		MemTotal:       1000,
+		MemFree:        500,
+		SwapTotal:      1000,
+		SwapFree:       750,
+		SwapCached:     50,
+		HugePagesTotal: 8,
+		HugePagesFree:  6,
+		Hugepagesize:   2048,
+	}
+	r, err := CubeMeminfo(0, 0, 0, 0, &mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SwapFree != 750 || r.SwapUsed != 250 || r.PercentSwapUsed != 25 ||
+		r.SwapCached != 50 || r.PercentSwapCached != 20 {
+		t.Fatalf("unexpected swap: %+v", r)
+	}
+	if r.HugeFree != 12288 || r.HugeUsed != 4096 || r.PercentHugeUsed != 25 {
+		t.Fatalf("unexpected hugepages: %+v", r)
+	}
+
+	// No swap and no hugepages.
+	mi.SwapTotal, mi.SwapFree, mi.SwapCached = 0, 0, 0
+	mi.HugePagesTotal, mi.HugePagesFree = 0, 0
+	r, err = CubeMeminfo(0, 0, 0, 0, &mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.PercentSwapUsed != 0 || r.PercentSwapCached != 0 ||
+		r.PercentHugeUsed != 0 {
+		t.Fatalf("unexpected percentages: %+v", r)
+	}
+}
+
+func TestCubeNetDevErrors(t *testing.T) {
+	t1 := NetDev{"eth0": {Name: "eth0", RxErrors: 10, TxErrors: 20,
+		TxCollisions: 0, RxDropped: 100, TxFIFO: 5}}
+	t2 := NetDev{"eth0": {Name: "eth0", RxErrors: 60, TxErrors: 20,
+		TxCollisions: 5, RxDropped: 600, TxFIFO: 10}}
+
+	// 5 second interval.
+	r, _, err := CubeNetDev(0, 0, 0, 0, 0, 0, t1, t2, 500, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("unexpected length: %v", len(r))
+	}
+	if r[0].RxErrors != 10 || r[0].TxErrors != 0 || r[0].Collisions != 1 ||
+		r[0].RxDropped != 100 || r[0].TxFIFO != 1 {
+		t.Fatalf("unexpected errors: %+v", r[0])
+	}
+}
+
+func TestCubeDiskstatsExtended(t *testing.T) {
+	t1 := []Diskstats{{
+		Info: Info{DeviceName: "sda"},
+		IOStats: IOStats{ReadIOs: 100, ReadMerges: 10,
+			ReadSectors: 800, ReadTicks: 100, WriteIOs: 100,
+			WriteMerges: 0, WriteSectors: 1600, WriteTicks: 500,
+			IOsTotalTicks: 1000, WeightedIOTicks: 2000},
+	}}
+	t2 := []Diskstats{{
+		Info: Info{DeviceName: "sda"},
+		IOStats: IOStats{ReadIOs: 600, ReadMerges: 60,
+			ReadSectors: 8800, ReadTicks: 1100, WriteIOs: 100,
+			WriteMerges: 0, WriteSectors: 1600, WriteTicks: 500,
+			IOsTotalTicks: 3500, WeightedIOTicks: 7000},
+	}}
+
+	// 5 second interval.
+	r, _, err := CubeDiskstats(0, 0, 0, 0, t1, t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("unexpected length: %v", len(r))
+	}
+	if r[0].Rrqm != 10 || r[0].Wrqm != 0 {
+		t.Fatalf("unexpected merges: %+v", r[0])
+	}
+	// 500 reads took 1000ms, no writes completed.
+	if r[0].RAwait != 2 || r[0].WAwait != 0 {
+		t.Fatalf("unexpected await: %+v", r[0])
+	}
+	// 8000 sectors in 500 I/Os is 8kB per request.
+	if r[0].AreqSz != 8 {
+		t.Fatalf("unexpected areq-sz: %v", r[0].AreqSz)
+	}
+	// Busy 2500ms out of 5000ms.
+	if r[0].Util != 50 || r[0].AquSz != 1 {
+		t.Fatalf("unexpected utilization: %+v", r[0])
+	}
+}
+
+func TestCubeStatReset(t *testing.T) {
+	c := CPUStat{User: 100, Idle: 100}
+	s1 := Stat{BootTime: 1700000000, CPUTotal: c, CPU: []CPUStat{c},
+		ContextSwitches: 1000, ProcessCreated: 100}
+	s2 := s1
+
+	// btime wobbles when the clock is adjusted.
+	s2.BootTime++
+	if _, err := CubeStat(0, 0, 0, 0, &s1, &s2); err != nil {
+		t.Fatal(err)
+	}
+	s2.BootTime += 3600
+	if _, err := CubeStat(0, 0, 0, 0, &s1, &s2); !IsReset(err) {
+		t.Fatalf("expected reset, got %v", err)
+	}
+	s2.BootTime = s1.BootTime
+	s2.ContextSwitches = 10
+	if _, err := CubeStat(0, 0, 0, 0, &s1, &s2); !IsReset(err) {
+		t.Fatalf("expected reset, got %v", err)
+	}
+}
+
+func TestCubeVmstatReset(t *testing.T) {
+	v1 := Vmstat{Pgpgin: 1000, Pgpgout: 1000, Pgfault: 5000}
+	v2 := v1
+	v2.Pgfault += 100
+	if _, err := CubeVmstat(0, 0, 0, 0, &v1, &v2, 1000); err != nil {
+		t.Fatal(err)
+	}
+	v2.Pgpgin = 10
+	if _, err := CubeVmstat(0, 0, 0, 0, &v1, &v2, 1000); !IsReset(err) {
+		t.Fatalf("expected reset, got %v", err)
+	}
+}
+
+func TestCubeCgroupReset(t *testing.T) {
+	c1 := Cgroup{
+		Name:       "/system.slice",
+		CPU:        CgroupCPUStat{UsageUsec: 1000, UserUsec: 600},
+		MemoryStat: map[string]uint64{"pgfault": 100},
+		IO: map[string]CgroupIOStat{
+			"8:0": {RBytes: 4096, RIOs: 1},
+		},
+	}
+	c2 := c1
+	c2.CPU.UsageUsec += 1000
+	if _, err := CubeCgroup(0, 0, 0, 0, &c1, &c2, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cgroup was removed and created again.
+	c2.CPU = CgroupCPUStat{UsageUsec: 10, UserUsec: 5}
+	if _, err := CubeCgroup(0, 0, 0, 0, &c1, &c2, 1000); !IsReset(err) {
+		t.Fatalf("expected reset, got %v", err)
+	}
+	c2.CPU = c1.CPU
+	c2.IO = map[string]CgroupIOStat{"8:0": {RBytes: 512, RIOs: 1}}
+	if _, err := CubeCgroup(0, 0, 0, 0, &c1, &c2, 1000); !IsReset(err) {
+		t.Fatalf("expected reset, got %v", err)
+	}
+}
+
+func TestCubeNetSNMPReset(t *testing.T) {
+	n1 := NetProto{
+		"Ip":  {"InReceives": 1000, "OutRequests": 1000},
+		"Tcp": {"ActiveOpens": 10, "RetransSegs": 5},
+	}
+	n2 := NetProto{
+		"Ip":  {"InReceives": 2000, "OutRequests": 2000},
+		"Tcp": {"ActiveOpens": 20, "RetransSegs": 5},
+	}
+	if _, err := CubeNetSNMP(0, 0, 0, 0, n1, n2, 1000); err != nil {
+		t.Fatal(err)
+	}
+	n2["Tcp"]["ActiveOpens"] = 1
+	if _, err := CubeNetSNMP(0, 0, 0, 0, n1, n2, 1000); !IsReset(err) {
+		t.Fatalf("expected reset, got %v", err)
+	}
+}
+
+func TestCubeNetDevHotplug(t *testing.T) {
+	t1 := NetDev{
+		"eth0":  {Name: "eth0", RxBytes: 1024, RxPackets: 10},
+		"veth1": {Name: "veth1", RxBytes: 1000},
+		"veth2": {Name: "veth2", RxBytes: 1000},
+	}
+	t2 := NetDev{
+		"eth0":  {Name: "eth0", RxBytes: 6144, RxPackets: 60},
+		"veth2": {Name: "veth2", RxBytes: 10},
+		"veth3": {Name: "veth3", RxBytes: 10},
+	}
+
+	// 5 second interval.
+	r, events, err := CubeNetDev(0, 0, 0, 0, 0, 0, t1, t2, 500, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 || r[0].Name != "eth0" || r[0].RxKBytes != 1 ||
+		r[0].RxPackets != 10 {
+		t.Fatalf("unexpected netdev: %+v", r)
+	}
+	want := []string{"veth1/disappear", "veth3/appear", "veth2/reset"}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	for k, e := range events {
+		if got := e.Name + "/" + e.Type; got != want[k] ||
+			e.System != "/proc/net/dev" {
+			t.Errorf("event %v: got %v, want %v", k, got, want[k])
+		}
+	}
+}
+
+func TestCubeDiskstatsHotplug(t *testing.T) {
+	t1 := []Diskstats{
+		{Info: Info{DeviceName: "sda"},
+			IOStats: IOStats{ReadIOs: 100, IOsInProgress: 10}},
+		{Info: Info{DeviceName: "sdb"},
+			IOStats: IOStats{ReadIOs: 100}},
+	}
+	t2 := []Diskstats{
+		{Info: Info{DeviceName: "dm-3"},
+			IOStats: IOStats{ReadIOs: 100}},
+		{Info: Info{DeviceName: "sda"},
+			IOStats: IOStats{ReadIOs: 600, IOsInProgress: 1}},
+		{Info: Info{DeviceName: "sdb"},
+			IOStats: IOStats{ReadIOs: 50}},
+	}
+
+	// 5 second interval.
+	r, events, err := CubeDiskstats(0, 0, 0, 0, t1, t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 || r[0].Name != "sda" || r[0].Rtps != 100 {
+		t.Fatalf("unexpected diskstats: %+v", r)
+	}
+	want := []string{"dm-3/appear", "sdb/reset"}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	for k, e := range events {
+		if got := e.Name + "/" + e.Type; got != want[k] ||
+			e.System != "/proc/diskstats" {
+			t.Errorf("event %v: got %v, want %v", k, got, want[k])
+		}
+	}
+}
+
+func TestInterval(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name           string
+		start1, start2 time.Time
+		want           uint64
+	}{
+		{"on time", start, start.Add(5 * time.Second), 500},
+		{"late", start, start.Add(7 * time.Second), 700},
+		{"sub second", start, start.Add(90 * time.Millisecond), 9},
+		{"no previous", time.Time{}, start, 500},
+		{"out of order", start, start.Add(-time.Second), 500},
+		{"same start", start, start, 500},
+	}
+	for _, tt := range tests {
+		got := Interval(tt.start1, tt.start2, 5*time.Second)
+		if got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCubeNetDevIfUtil(t *testing.T) {
+	t1 := NetDev{"eth0": {Name: "eth0"}}
+	t2 := NetDev{"eth0": {Name: "eth0", RxBytes: 125000 * 1024 * 5}}
+	nics := map[string]NIC{
+		NICKey(1, 2, 0, "eth0"): {Duplex: "full", Speed: 1000},
+		NICKey(1, 3, 0, "eth0"): {Duplex: "full", Speed: 10},
+	}
+
+	// 5 second interval.
+	r, _, err := CubeNetDev(1, 2, 0, 0, 0, 0, t1, t2, 500, nics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 1 || r[0].RxKBytes != 125000 || r[0].IfUtil != 1 {
+		t.Fatalf("unexpected netdev: %+v", r)
+	}
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InterruptSource is a single line of /proc/interrupts or /proc/softirqs.
+type InterruptSource struct {
+	Name        string   // IRQ number or name, e.g. 24, NMI or NET_RX
+	Description string   // Remainder of the line, e.g. IR-PCI-MSI eth0-rx-0
+	Counts      []uint64 // Per CPU counts, a single total for ERR and MIS
+}
+
+// Interrupts is parsed from /proc/interrupts or /proc/softirqs. Both files
+// have a header with a column per online CPU followed by a line per source.
+type Interrupts struct {
+	CPU     []int             // CPU number of every count column
+	Sources []InterruptSource // Sources in file order
+}
+
+// ProcessInterrupts parses /proc/interrupts or /proc/softirqs.
+func ProcessInterrupts(b []byte) (*Interrupts, error) {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("empty interrupts")
+	}
+
+	// CPU0       CPU1       CPU3
+	var in Interrupts
+	for _, v := range strings.Fields(s.Text()) {
+		if !strings.HasPrefix(v, "CPU") {
+			return nil, fmt.Errorf("invalid interrupts header: %v", v)
+		}
+		cpu, err := strconv.Atoi(v[3:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid interrupts cpu: %v", v)
+		}
+		in.CPU = append(in.CPU, cpu)
+	}
+
+	for s.Scan() {
+		// 24:   1234   5678   IR-PCI-MSI 524288-edge  eth0-rx-0
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if !strings.HasSuffix(fields[0], ":") {
+			return nil, fmt.Errorf("invalid interrupts line: %v",
+				fields[0])
+		}
+		is := InterruptSource{
+			Name: strings.TrimSuffix(fields[0], ":"),
+		}
+		i := 1
+		for ; i < len(fields) && len(is.Counts) < len(in.CPU); i++ {
+			c, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				break
+			}
+			is.Counts = append(is.Counts, c)
+		}
+		if len(is.Counts) == 0 {
+			return nil, fmt.Errorf("invalid interrupts counts: %v",
+				is.Name)
+		}
+		is.Description = strings.Join(fields[i:], " ")
+		in.Sources = append(in.Sources, is)
+	}
+
+	return &in, s.Err()
+}
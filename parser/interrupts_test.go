@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+const sampleInterrupts = `           CPU0       CPU2
+  0:         44          0   IO-APIC   2-edge      timer
+ 24:       %v       %v  IR-PCI-MSI 524288-edge      eth0-rx-0
+NMI:          0          0   Non-maskable interrupts
+LOC: 4294967290       %v   Local timer interrupts
+ERR:          %v
+`
+
+const sampleSoftirqs = `                    CPU0       CPU1
+          HI:          0          1
+       TIMER:      12345      23456
+      NET_RX:       5000        100
+`
+
+func TestProcessInterrupts(t *testing.T) {
+	in, err := ProcessInterrupts([]byte(fmt.Sprintf(sampleInterrupts, 100,
+		200, 300, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in.CPU) != 2 || in.CPU[0] != 0 || in.CPU[1] != 2 {
+		t.Fatalf("unexpected cpus: %v", in.CPU)
+	}
+	if len(in.Sources) != 5 {
+		t.Fatalf("unexpected sources: %v", len(in.Sources))
+	}
+	eth := in.Sources[1]
+	if eth.Name != "24" || eth.Counts[0] != 100 || eth.Counts[1] != 200 ||
+		eth.Description != "IR-PCI-MSI 524288-edge eth0-rx-0" {
+		t.Fatalf("unexpected source: %+v", eth)
+	}
+	if e := in.Sources[4]; e.Name != "ERR" || len(e.Counts) != 1 ||
+		e.Description != "" {
+		t.Fatalf("unexpected source: %+v", e)
+	}
+
+	si, err := ProcessInterrupts([]byte(sampleSoftirqs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(si.Sources) != 3 || si.Sources[2].Name != "NET_RX" ||
+		si.Sources[2].Counts[0] != 5000 {
+		t.Fatalf("unexpected softirqs: %+v", si)
+	}
+
+	if _, err := ProcessInterrupts([]byte("")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCubeInterrupts(t *testing.T) {
+	t1, err := ProcessInterrupts([]byte(fmt.Sprintf(sampleInterrupts, 100,
+		200, 300, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := ProcessInterrupts([]byte(fmt.Sprintf(sampleInterrupts, 5100,
+		200, 800, 6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// LOC on CPU0 wrapped.
+	t2.Sources[3].Counts[0] = 4
+
+	// 5 second interval.
+	r, err := CubeInterrupts(0, 0, 0, 0, t1, t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 13 {
+		t.Fatalf("unexpected length: %v", len(r))
+	}
+	rates := make(map[string]float64)
+	for _, v := range r {
+		rates[fmt.Sprintf("%v/%v", v.Source, v.CPU)] = v.Intr
+	}
+	tests := map[string]float64{
+		"24/-1":  1000,
+		"24/0":   1000,
+		"24/2":   0,
+		"LOC/0":  2,
+		"LOC/2":  100,
+		"LOC/-1": 102,
+		"ERR/-1": 1,
+	}
+	for k, want := range tests {
+		if got, ok := rates[k]; !ok || got != want {
+			t.Errorf("%v: got %v, want %v", k, got, want)
+		}
+	}
+	if _, ok := rates["ERR/0"]; ok {
+		t.Error("unexpected per CPU ERR")
+	}
+
+	// CPUs that went offline can not be cubed.
+	t2.CPU = []int{0, 1}
+	if _, err := CubeInterrupts(0, 0, 0, 0, t1, t2, 500); err == nil {
+		t.Fatal("expected error")
+	}
+}
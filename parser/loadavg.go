@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Loadavg is parsed from /proc/loadavg.
+type Loadavg struct {
+	Load1    float64 // Load average over the last minute
+	Load5    float64 // Load average over the last 5 minutes
+	Load15   float64 // Load average over the last 15 minutes
+	Runnable uint64  // Runnable tasks, including the reading task
+	Tasks    uint64  // Tasks that currently exist
+	LastPID  uint64  // Most recently assigned pid
+}
+
+// ProcessLoadavg parses /proc/loadavg.
+func ProcessLoadavg(b []byte) (Loadavg, error) {
+	// 0.20 0.18 0.12 1/80 11206
+	fields := strings.Fields(string(b))
+	if len(fields) != 5 {
+		return Loadavg{}, fmt.Errorf("malformed loadavg: %q", b)
+	}
+
+	var (
+		la  Loadavg
+		err error
+	)
+	for k, f := range []*float64{&la.Load1, &la.Load5, &la.Load15} {
+		*f, err = strconv.ParseFloat(fields[k], 64)
+		if err != nil {
+			return Loadavg{}, fmt.Errorf("invalid load average: %v",
+				err)
+		}
+	}
+
+	tasks := strings.SplitN(fields[3], "/", 2)
+	if len(tasks) != 2 {
+		return Loadavg{}, fmt.Errorf("invalid tasks: %v", fields[3])
+	}
+	if la.Runnable, err = strconv.ParseUint(tasks[0], 10, 64); err != nil {
+		return Loadavg{}, fmt.Errorf("invalid runnable tasks: %v", err)
+	}
+	if la.Tasks, err = strconv.ParseUint(tasks[1], 10, 64); err != nil {
+		return Loadavg{}, fmt.Errorf("invalid tasks: %v", err)
+	}
+	if la.LastPID, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+		return Loadavg{}, fmt.Errorf("invalid last pid: %v", err)
+	}
+
+	return la, nil
+}
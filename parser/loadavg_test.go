@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"testing"
+)
+
+const sampleLoadavg = "1.25 0.75 0.50 3/812 11206\n"
+
+func TestProcessLoadavg(t *testing.T) {
+	la, err := ProcessLoadavg([]byte(sampleLoadavg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if la.Load1 != 1.25 || la.Load5 != 0.75 || la.Load15 != 0.5 {
+		t.Fatalf("unexpected load averages: %+v", la)
+	}
+	if la.Runnable != 3 || la.Tasks != 812 || la.LastPID != 11206 {
+		t.Fatalf("unexpected tasks: %+v", la)
+	}
+
+	for _, v := range []string{"", "1.25 0.75 0.50 3 11206",
+		"1.25 x 0.50 3/812 11206"} {
+		if _, err := ProcessLoadavg([]byte(v)); err == nil {
+			t.Fatalf("expected error: %q", v)
+		}
+	}
+}
+
+func TestCubeLoadavg(t *testing.T) {
+	la, err := ProcessLoadavg([]byte(sampleLoadavg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without /proc/stat.
+	r, err := CubeLoadavg(0, 0, 0, 0, &la, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RunqSz != 2 || r.PlistSz != 812 || r.Ldavg1 != 1.25 ||
+		r.Blocked != -1 {
+		t.Fatalf("unexpected loadavg: %+v", r)
+	}
+
+	st := Stat{ProcessesRunning: 5, ProcessesBlocked: 1}
+	r, err = CubeLoadavg(0, 0, 0, 0, &la, &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RunqSz != 2 || r.Blocked != 1 {
+		t.Fatalf("unexpected loadavg: %+v", r)
+	}
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NetProto holds the protocol counters of /proc/net/snmp or
+// /proc/net/netstat keyed by protocol, e.g. Tcp or TcpExt, and counter name.
+type NetProto map[string]map[string]int64
+
+// counter returns the counter name of protocol proto or 0 when it is not
+// reported by the kernel.
+func (n NetProto) counter(proto, name string) uint64 {
+	return uint64(n[proto][name])
+}
+
+// ProcessNetProto parses /proc/net/snmp or /proc/net/netstat. Every protocol
+// is reported as a line with the counter names followed by a line with the
+// values, both prefixed with the protocol.
+func ProcessNetProto(b []byte) (NetProto, error) {
+	np := make(NetProto)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		names := strings.Fields(s.Text())
+		if len(names) == 0 {
+			continue
+		}
+		if !s.Scan() {
+			return nil, fmt.Errorf("missing values: %v", names[0])
+		}
+		values := strings.Fields(s.Text())
+		if len(values) != len(names) || values[0] != names[0] {
+			return nil, fmt.Errorf("invalid values: %v", names[0])
+		}
+
+		proto := strings.TrimSuffix(names[0], ":")
+		counters := make(map[string]int64, len(names)-1)
+		for k := 1; k < len(names); k++ {
+			// Tcp MaxConn is -1 when the limit is dynamic.
+			v, err := strconv.ParseInt(values[k], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %v %v: %v", proto,
+					names[k], err)
+			}
+			counters[names[k]] = v
+		}
+		np[proto] = counters
+	}
+
+	return np, s.Err()
+}
@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+const sampleNetSNMP = `Ip: Forwarding DefaultTTL InReceives InHdrErrors InAddrErrors ForwDatagrams InUnknownProtos InDiscards InDelivers OutRequests OutDiscards OutNoRoutes ReasmTimeout ReasmReqds ReasmOKs ReasmFails FragOKs FragFails FragCreates
+Ip: 1 64 %v 0 0 0 0 0 %v 1000 0 0 0 0 0 0 0 0 0
+Icmp: InMsgs InErrors
+Icmp: 45 0
+Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 %v 50 2 3 10 %v 900 %v 0 4 0
+Udp: InDatagrams NoPorts InErrors OutDatagrams RcvbufErrors SndbufErrors InCsumErrors IgnoredMulti MemErrors
+Udp: 100 5 0 120 0 0 0 0 0
+`
+
+const sampleNetstat = `TcpExt: SyncookiesSent SyncookiesRecv ListenOverflows ListenDrops TCPTimeouts TCPSynRetrans
+TcpExt: 0 0 %v %v 7 1
+IpExt: InNoRoutes InTruncatedPkts
+IpExt: 0 0
+`
+
+func TestProcessNetProto(t *testing.T) {
+	np, err := ProcessNetProto([]byte(fmt.Sprintf(sampleNetSNMP, 2000,
+		1900, 100, 5000, 25)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(np) != 4 {
+		t.Fatalf("unexpected protocols: %v", np)
+	}
+	if np["Tcp"]["MaxConn"] != -1 || np["Tcp"]["RetransSegs"] != 25 ||
+		np["Ip"]["InReceives"] != 2000 || np["Udp"]["NoPorts"] != 5 {
+		t.Fatalf("unexpected counters: %v", np)
+	}
+
+	// Names and values must match.
+	for _, v := range []string{"Tcp: ActiveOpens\n",
+		"Tcp: ActiveOpens InSegs\nTcp: 1\n",
+		"Tcp: ActiveOpens\nUdp: 1\n",
+		"Tcp: ActiveOpens\nTcp: x\n"} {
+		if _, err := ProcessNetProto([]byte(v)); err == nil {
+			t.Fatalf("expected error: %q", v)
+		}
+	}
+}
+
+func TestCubeNetSNMP(t *testing.T) {
+	t1, err := ProcessNetProto([]byte(fmt.Sprintf(sampleNetSNMP, 2000,
+		1900, 100, 5000, 25)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := ProcessNetProto([]byte(fmt.Sprintf(sampleNetSNMP, 7000,
+		6900, 150, 10000, 75)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 second interval.
+	r, err := CubeNetSNMP(0, 0, 0, 0, t1, t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Irec != 1000 || r.Idel != 1000 || r.Orq != 0 {
+		t.Fatalf("unexpected ip: %+v", r)
+	}
+	if r.Active != 10 || r.Passive != 0 || r.Iseg != 1000 ||
+		r.Retrans != 10 {
+		t.Fatalf("unexpected tcp: %+v", r)
+	}
+}
+
+func TestCubeNetstat(t *testing.T) {
+	t1, err := ProcessNetProto([]byte(fmt.Sprintf(sampleNetstat, 10, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := ProcessNetProto([]byte(fmt.Sprintf(sampleNetstat, 60, 110)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 second interval.
+	r, err := CubeNetstat(0, 0, 0, 0, t1, t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Lstovf != 10 || r.Lstdrp != 20 || r.Tmout != 0 {
+		t.Fatalf("unexpected rates: %+v", r)
+	}
+	// Counters missing from older kernels are zero.
+	if r.Abortto != 0 {
+		t.Fatalf("unexpected abortto: %v", r.Abortto)
+	}
+}
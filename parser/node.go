@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// NodeMeminfo is parsed from the types.PCNodeSystem system. It contains the
+// meminfo of every NUMA node keyed by node number. The fields are keyed by
+// name without the colon, e.g. MemFree, and are in kB except for the
+// HugePages fields which are in pages.
+type NodeMeminfo map[int]map[string]uint64
+
+// ProcessNodeMeminfo parses a node system measurement.
+func ProcessNodeMeminfo(b []byte) (NodeMeminfo, error) {
+	sections, err := types.DecodeSections(string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	nm := make(NodeMeminfo, len(sections))
+	for _, v := range sections {
+		s := bufio.NewScanner(bytes.NewReader(v.Data))
+		for s.Scan() {
+			// Node 0 MemFree:         3431548 kB
+			fields := strings.Fields(s.Text())
+			if len(fields) < 4 || fields[0] != "Node" {
+				continue
+			}
+			node, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%v: invalid node: %v",
+					v.Name, fields[1])
+			}
+			value, err := strconv.ParseUint(fields[3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%v: invalid %v: %v",
+					v.Name, fields[2], err)
+			}
+			if nm[node] == nil {
+				nm[node] = make(map[string]uint64)
+			}
+			nm[node][strings.TrimSuffix(fields[2], ":")] = value
+		}
+		if err := s.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return nm, nil
+}
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+const sampleNodeMeminfo = `Node %[1]v MemTotal:        1000 kB
+Node %[1]v MemFree:          %[2]v kB
+Node %[1]v MemUsed:          750 kB
+Node %[1]v Active:           100 kB
+Node %[1]v FilePages:        300 kB
+Node %[1]v HugePages_Total:     0
+`
+
+func TestNodeMeminfo(t *testing.T) {
+	dir := "/sys/devices/system/node/"
+	m := types.EncodeSections([]types.PCSection{
+		{
+			Name: dir + "node1/meminfo",
+			Data: []byte(fmt.Sprintf(sampleNodeMeminfo, 1, 1000)),
+		},
+		{
+			Name: dir + "node0/meminfo",
+			Data: []byte(fmt.Sprintf(sampleNodeMeminfo, 0, 250)),
+		},
+	})
+	nm, err := ProcessNodeMeminfo([]byte(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nm) != 2 || nm[0]["MemFree"] != 250 ||
+		nm[1]["MemFree"] != 1000 || nm[0]["FilePages"] != 300 {
+		t.Fatalf("unexpected node meminfo: %v", nm)
+	}
+
+	r, err := CubeNodeMeminfo(0, 0, 0, 0, nm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("unexpected length: %v", len(r))
+	}
+	if r[0].Node != 0 || r[0].MemUsed != 750 || r[0].PercentUsed != 75 ||
+		r[0].Active != 100 || r[0].FilePages != 300 {
+		t.Fatalf("unexpected node: %+v", r[0])
+	}
+	if r[1].Node != 1 || r[1].MemUsed != 0 || r[1].PercentUsed != 0 {
+		t.Fatalf("unexpected node: %+v", r[1])
+	}
+}
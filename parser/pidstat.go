@@ -0,0 +1,234 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// ProcStat contains the fields of /proc/<pid>/stat that are used. See
+// proc(5) for details.
+type ProcStat struct {
+	PID        int    // Process id
+	Comm       string // Command name
+	State      string // Process state
+	PPID       int    // Parent process id
+	MinFlt     uint64 // Minor faults
+	MajFlt     uint64 // Major faults
+	UTime      uint64 // Time in user mode, in jiffies
+	STime      uint64 // Time in kernel mode, in jiffies
+	NumThreads uint64 // Number of threads
+	StartTime  uint64 // Time the process started after boot, in jiffies
+}
+
+// ProcStatus contains the fields of /proc/<pid>/status that are used.
+type ProcStatus struct {
+	Name                     string // Command name
+	UID                      uint64 // Real user id
+	VmSize                   uint64 // Virtual memory size in kB
+	VmRSS                    uint64 // Resident set size in kB
+	VoluntaryCtxtSwitches    uint64 // Voluntary context switches
+	NonvoluntaryCtxtSwitches uint64 // Involuntary context switches
+}
+
+// ProcIO contains /proc/<pid>/io.
+type ProcIO struct {
+	RChar               uint64 // Bytes read
+	WChar               uint64 // Bytes written
+	SyscR               uint64 // Read system calls
+	SyscW               uint64 // Write system calls
+	ReadBytes           uint64 // Bytes read from storage
+	WriteBytes          uint64 // Bytes written to storage
+	CancelledWriteBytes uint64 // Bytes that were not written after all
+}
+
+// Proc is a single process of a pidstat measurement. IO is nil when the
+// collector was not permitted to read /proc/<pid>/io.
+type Proc struct {
+	Stat   ProcStat
+	Status ProcStatus
+	IO     *ProcIO
+}
+
+// Pidstat is parsed from a types.PCPidstatSystem measurement. The map keys
+// are process ids.
+type Pidstat map[int]*Proc
+
+// ProcessProcStat parses the contents of /proc/<pid>/stat.
+func ProcessProcStat(b []byte) (ProcStat, error) {
+	var ps ProcStat
+
+	// The command name is enclosed in parentheses and may contain spaces
+	// and parentheses.
+	start := bytes.IndexByte(b, '(')
+	end := bytes.LastIndexByte(b, ')')
+	if start < 0 || end < start {
+		return ps, fmt.Errorf("invalid stat: no command name")
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b[:start])))
+	if err != nil {
+		return ps, fmt.Errorf("invalid stat pid: %v", err)
+	}
+	ps.PID = pid
+	ps.Comm = string(b[start+1 : end])
+
+	// Fields start at 3, state, after the command name.
+	fields := strings.Fields(string(b[end+1:]))
+	if len(fields) < 20 {
+		return ps, fmt.Errorf("invalid stat: %v fields", len(fields))
+	}
+	field := func(n int) uint64 {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = strconv.ParseUint(fields[n-3], 10, 64)
+		return v
+	}
+	ps.State = fields[0]
+	ps.PPID = int(field(4))
+	ps.MinFlt = field(10)
+	ps.MajFlt = field(12)
+	ps.UTime = field(14)
+	ps.STime = field(15)
+	ps.NumThreads = field(20)
+	ps.StartTime = field(22)
+	if err != nil {
+		return ps, fmt.Errorf("invalid stat: %v", err)
+	}
+
+	return ps, nil
+}
+
+// ProcessProcStatus parses the contents of /proc/<pid>/status.
+func ProcessProcStatus(b []byte) (ProcStatus, error) {
+	var ps ProcStatus
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		a := strings.SplitN(s.Text(), ":", 2)
+		if len(a) != 2 {
+			continue
+		}
+		fields := strings.Fields(a[1])
+		if len(fields) == 0 {
+			continue
+		}
+
+		var (
+			v   *uint64
+			err error
+		)
+		switch a[0] {
+		case "Name":
+			ps.Name = strings.TrimSpace(a[1])
+			continue
+		case "Uid":
+			v = &ps.UID
+		case "VmSize":
+			v = &ps.VmSize
+		case "VmRSS":
+			v = &ps.VmRSS
+		case "voluntary_ctxt_switches":
+			v = &ps.VoluntaryCtxtSwitches
+		case "nonvoluntary_ctxt_switches":
+			v = &ps.NonvoluntaryCtxtSwitches
+		default:
+			continue
+		}
+		*v, err = strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return ps, fmt.Errorf("invalid status %v: %v", a[0],
+				err)
+		}
+	}
+
+	return ps, s.Err()
+}
+
+// ProcessProcIO parses the contents of /proc/<pid>/io.
+func ProcessProcIO(b []byte) (ProcIO, error) {
+	var pi ProcIO
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+
+		var v *uint64
+		switch fields[0] {
+		case "rchar:":
+			v = &pi.RChar
+		case "wchar:":
+			v = &pi.WChar
+		case "syscr:":
+			v = &pi.SyscR
+		case "syscw:":
+			v = &pi.SyscW
+		case "read_bytes:":
+			v = &pi.ReadBytes
+		case "write_bytes:":
+			v = &pi.WriteBytes
+		case "cancelled_write_bytes:":
+			v = &pi.CancelledWriteBytes
+		default:
+			continue
+		}
+		var err error
+		*v, err = strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return pi, fmt.Errorf("invalid io %v %v", fields[0],
+				err)
+		}
+	}
+
+	return pi, s.Err()
+}
+
+// ProcessPidstat parses a types.PCPidstatSystem measurement. Processes
+// without a stat or status section are omitted.
+func ProcessPidstat(b []byte) (Pidstat, error) {
+	sections, err := types.DecodeSections(string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	pidstat := make(Pidstat)
+	for _, v := range sections {
+		// /proc/<pid>/<file>
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(v.Name)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid section %v", v.Name)
+		}
+		p, ok := pidstat[pid]
+		if !ok {
+			p = &Proc{}
+			pidstat[pid] = p
+		}
+		switch filepath.Base(v.Name) {
+		case "stat":
+			p.Stat, err = ProcessProcStat(v.Data)
+		case "status":
+			p.Status, err = ProcessProcStatus(v.Data)
+		case "io":
+			var pi ProcIO
+			pi, err = ProcessProcIO(v.Data)
+			p.IO = &pi
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", v.Name, err)
+		}
+	}
+	for k, v := range pidstat {
+		if v.Stat.PID != k || v.Status.Name == "" {
+			delete(pidstat, k)
+		}
+	}
+
+	return pidstat, nil
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+const samplePidStatFile = "1234 (my (odd) cmd) S 1 1234 1234 0 -1 4194560 " +
+	"%v 0 %v 0 %v %v 0 0 20 0 4 0 5678 1048576000 2048 " +
+	"18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n"
+
+const samplePidStatusFile = `Name:	my (odd) cmd
+Umask:	0022
+State:	S (sleeping)
+Tgid:	1234
+Pid:	1234
+PPid:	1
+Uid:	1000	1000	1000	1000
+Gid:	1000	1000	1000	1000
+VmPeak:	 1100000 kB
+VmSize:	 1024000 kB
+VmRSS:	    8192 kB
+Threads:	4
+voluntary_ctxt_switches:	%v
+nonvoluntary_ctxt_switches:	%v
+`
+
+const samplePidIOFile = `rchar: 1000000
+wchar: 2000000
+syscr: 100
+syscw: 200
+read_bytes: %v
+write_bytes: %v
+cancelled_write_bytes: 0
+`
+
+func samplePidstat(t *testing.T, utime, stime, minflt, cswch, rd, wr int, io bool) Pidstat {
+	t.Helper()
+
+	sections := []types.PCSection{
+		{
+			Name: "/proc/1234/stat",
+			Data: []byte(fmt.Sprintf(samplePidStatFile, minflt, 0, utime,
+				stime)),
+		},
+		{
+			Name: "/proc/1234/status",
+			Data: []byte(fmt.Sprintf(samplePidStatusFile, cswch, 1)),
+		},
+	}
+	if io {
+		sections = append(sections, types.PCSection{
+			Name: "/proc/1234/io",
+			Data: []byte(fmt.Sprintf(samplePidIOFile, rd, wr)),
+		})
+	}
+	p, err := ProcessPidstat([]byte(types.EncodeSections(sections)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestProcessPidstat(t *testing.T) {
+	p := samplePidstat(t, 100, 50, 10, 5, 4096, 8192, true)
+	proc, ok := p[1234]
+	if !ok {
+		t.Fatalf("pid not found: %v", p)
+	}
+	if proc.Stat.Comm != "my (odd) cmd" {
+		t.Fatalf("unexpected comm: %q", proc.Stat.Comm)
+	}
+	if proc.Stat.UTime != 100 || proc.Stat.STime != 50 ||
+		proc.Stat.MinFlt != 10 || proc.Stat.NumThreads != 4 ||
+		proc.Stat.StartTime != 5678 || proc.Stat.PPID != 1 {
+		t.Fatalf("unexpected stat: %+v", proc.Stat)
+	}
+	if proc.Status.UID != 1000 || proc.Status.VmRSS != 8192 ||
+		proc.Status.VmSize != 1024000 ||
+		proc.Status.VoluntaryCtxtSwitches != 5 {
+		t.Fatalf("unexpected status: %+v", proc.Status)
+	}
+	if proc.IO == nil || proc.IO.ReadBytes != 4096 ||
+		proc.IO.WriteBytes != 8192 {
+		t.Fatalf("unexpected io: %+v", proc.IO)
+	}
+
+	// io is optional.
+	p = samplePidstat(t, 100, 50, 10, 5, 0, 0, false)
+	if p[1234].IO != nil {
+		t.Fatalf("unexpected io: %+v", p[1234].IO)
+	}
+}
+
+func TestCubePidstat(t *testing.T) {
+	t1 := samplePidstat(t, 100, 50, 10, 5, 0, 0, true)
+	t2 := samplePidstat(t, 150, 75, 30, 25, 10240, 20480, true)
+
+	// 5 second interval.
+	ps, err := CubePidstat(0, 0, 0, 0, t1, t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 1 {
+		t.Fatalf("expected 1 process, got %v", len(ps))
+	}
+	p := ps[0]
+	if p.PID != 1234 || p.UID != 1000 || p.Name != "my (odd) cmd" {
+		t.Fatalf("unexpected process: %+v", p)
+	}
+	if p.UserT != 10 || p.System != 5 || p.CPU != 15 {
+		t.Fatalf("unexpected cpu: %+v", p)
+	}
+	if p.MinFlt != 4 || p.Cswch != 4 {
+		t.Fatalf("unexpected rates: %+v", p)
+	}
+	if p.KBRead != 2 || p.KBWrite != 4 {
+		t.Fatalf("unexpected io: %+v", p)
+	}
+
+	// Processes without io report -1.
+	t2 = samplePidstat(t, 150, 75, 30, 25, 0, 0, false)
+	ps, err = CubePidstat(0, 0, 0, 0, t1, t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps[0].KBRead != -1 || ps[0].KBWrite != -1 {
+		t.Fatalf("unexpected io: %+v", ps[0])
+	}
+
+	// New processes are skipped.
+	ps, err = CubePidstat(0, 0, 0, 0, Pidstat{}, t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps) != 0 {
+		t.Fatalf("expected no processes, got %v", len(ps))
+	}
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// PressureLine is a single line of a pressure stall information file.
+type PressureLine struct {
+	Avg10  float64 // Percentage of stalled time over the last 10 seconds
+	Avg60  float64 // Percentage of stalled time over the last 60 seconds
+	Avg300 float64 // Percentage of stalled time over the last 300 seconds
+	Total  uint64  // Total stalled time in microseconds
+}
+
+// Pressure is parsed from a pressure stall information file such as
+// /proc/pressure/cpu or the cpu.pressure file of a cgroup. Some is the time
+// that at least one task was stalled, Full the time that all non-idle tasks
+// were stalled. Full is zero when it is not reported.
+type Pressure struct {
+	Some PressureLine
+	Full PressureLine
+}
+
+// ProcessPressure parses a pressure stall information file.
+func ProcessPressure(b []byte) (Pressure, error) {
+	var p Pressure
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var pl *PressureLine
+		switch fields[0] {
+		case "some":
+			pl = &p.Some
+		case "full":
+			pl = &p.Full
+		default:
+			return p, fmt.Errorf("invalid pressure line: %v",
+				fields[0])
+		}
+		for _, v := range fields[1:] {
+			kv := strings.SplitN(v, "=", 2)
+			if len(kv) != 2 {
+				return p, fmt.Errorf("invalid pressure field: %v",
+					v)
+			}
+			var err error
+			switch kv[0] {
+			case "avg10":
+				pl.Avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				pl.Avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				pl.Avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				pl.Total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return p, fmt.Errorf("invalid pressure %v: %v",
+					kv[0], err)
+			}
+		}
+	}
+
+	return p, s.Err()
+}
+
+// PressureResource returns the resource, i.e. cpu, memory or io, of the
+// pressure stall information system s.
+func PressureResource(s string) string {
+	return filepath.Base(s)
+}
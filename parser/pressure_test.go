@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+const samplePressure = `some avg10=2.04 avg60=0.75 avg300=0.40 total=%v
+full avg10=1.01 avg60=0.30 avg300=0.10 total=%v
+`
+
+func TestProcessPressure(t *testing.T) {
+	p, err := ProcessPressure([]byte(fmt.Sprintf(samplePressure, 157656722,
+		83227425)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Some.Avg10 != 2.04 || p.Some.Avg60 != 0.75 ||
+		p.Some.Avg300 != 0.4 || p.Some.Total != 157656722 {
+		t.Fatalf("unexpected some: %+v", p.Some)
+	}
+	if p.Full.Avg10 != 1.01 || p.Full.Total != 83227425 {
+		t.Fatalf("unexpected full: %+v", p.Full)
+	}
+
+	// Older kernels do not report full for cpu.
+	p, err = ProcessPressure([]byte("some avg10=0.00 avg60=0.00 " +
+		"avg300=0.00 total=12\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Some.Total != 12 || p.Full.Total != 0 {
+		t.Fatalf("unexpected pressure: %+v", p)
+	}
+
+	if _, err := ProcessPressure([]byte("none avg10=0.00\n")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCubePressure(t *testing.T) {
+	t1, err := ProcessPressure([]byte(fmt.Sprintf(samplePressure, 1000000,
+		500000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := ProcessPressure([]byte(fmt.Sprintf(samplePressure, 1500000,
+		750000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 second interval.
+	r, err := CubePressure(0, 0, 0, 0, PressureResource("/proc/pressure/io"),
+		&t1, &t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Resource != "io" {
+		t.Fatalf("unexpected resource: %v", r.Resource)
+	}
+	if r.SomeStall != 10 || r.FullStall != 5 {
+		t.Fatalf("unexpected stall: %+v", r)
+	}
+	if r.SomeAvg10 != 2.04 || r.FullAvg300 != 0.1 {
+		t.Fatalf("unexpected averages: %+v", r)
+	}
+
+	// Counters can not go backwards.
+	if _, err := CubePressure(0, 0, 0, 0, "io", &t2, &t1, 500); err == nil {
+		t.Fatal("expected error")
+	}
+}
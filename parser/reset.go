@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// bootTimeSlack is the number of seconds btime may move between samples
+// without being considered a reboot. The kernel derives btime from the
+// current time and the uptime, so it wobbles when the clock is adjusted.
+const bootTimeSlack = 1
+
+// ResetError is returned by the cube functions when the counters of the
+// second sample can not be compared to those of the first one because the
+// host rebooted. The interval must be dropped and the second sample used as
+// the new baseline.
+type ResetError struct {
+	Reason string
+}
+
+// Error satisfies the error interface.
+func (e ResetError) Error() string {
+	return "counter reset: " + e.Reason
+}
+
+// IsReset returns true if err is, or wraps, a ResetError.
+func IsReset(err error) bool {
+	var re ResetError
+	return errors.As(err, &re)
+}
+
+// decreased returns true if any counter went backwards. Counters are passed
+// as pairs of the first and second sample.
+func decreased(counters ...[2]uint64) bool {
+	for _, c := range counters {
+		if c[1] < c[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// statReset returns a ResetError if t2 can not be compared to t1.
+func statReset(t1, t2 *Stat) error {
+	if t1.BootTime != 0 && t2.BootTime != 0 &&
+		(t2.BootTime > t1.BootTime+bootTimeSlack ||
+			t1.BootTime > t2.BootTime+bootTimeSlack) {
+		return ResetError{
+			Reason: fmt.Sprintf("boot time changed from %v to %v",
+				t1.BootTime, t2.BootTime),
+		}
+	}
+	// The cpu line only sums online CPUs but context switches and forks
+	// are counted over all CPUs and never go backwards.
+	if decreased(
+		[2]uint64{t1.ContextSwitches, t2.ContextSwitches},
+		[2]uint64{t1.ProcessCreated, t2.ProcessCreated},
+	) {
+		return ResetError{Reason: "stat counters decreased"}
+	}
+	return nil
+}
+
+// netDevDecreased returns true if a counter of the interface went backwards.
+func netDevDecreased(t1, t2 NetDevLine) bool {
+	return decreased(
+		[2]uint64{t1.RxBytes, t2.RxBytes},
+		[2]uint64{t1.RxPackets, t2.RxPackets},
+		[2]uint64{t1.RxErrors, t2.RxErrors},
+		[2]uint64{t1.RxDropped, t2.RxDropped},
+		[2]uint64{t1.RxFIFO, t2.RxFIFO},
+		[2]uint64{t1.RxFrame, t2.RxFrame},
+		[2]uint64{t1.RxCompressed, t2.RxCompressed},
+		[2]uint64{t1.RxMulticast, t2.RxMulticast},
+		[2]uint64{t1.TxBytes, t2.TxBytes},
+		[2]uint64{t1.TxPackets, t2.TxPackets},
+		[2]uint64{t1.TxErrors, t2.TxErrors},
+		[2]uint64{t1.TxDropped, t2.TxDropped},
+		[2]uint64{t1.TxFIFO, t2.TxFIFO},
+		[2]uint64{t1.TxCollisions, t2.TxCollisions},
+		[2]uint64{t1.TxCarrier, t2.TxCarrier},
+		[2]uint64{t1.TxCompressed, t2.TxCompressed},
+	)
+}
+
+// diskstatsDecreased returns true if a counter of the device went backwards.
+// IOsInProgress is a gauge and therefore not checked.
+func diskstatsDecreased(t1, t2 *Diskstats) bool {
+	return decreased(
+		[2]uint64{t1.ReadIOs, t2.ReadIOs},
+		[2]uint64{t1.ReadMerges, t2.ReadMerges},
+		[2]uint64{t1.ReadSectors, t2.ReadSectors},
+		[2]uint64{t1.ReadTicks, t2.ReadTicks},
+		[2]uint64{t1.WriteIOs, t2.WriteIOs},
+		[2]uint64{t1.WriteMerges, t2.WriteMerges},
+		[2]uint64{t1.WriteSectors, t2.WriteSectors},
+		[2]uint64{t1.WriteTicks, t2.WriteTicks},
+		[2]uint64{t1.IOsTotalTicks, t2.IOsTotalTicks},
+		[2]uint64{t1.WeightedIOTicks, t2.WeightedIOTicks},
+		[2]uint64{t1.DiscardIOs, t2.DiscardIOs},
+		[2]uint64{t1.DiscardMerges, t2.DiscardMerges},
+		[2]uint64{t1.DiscardSectors, t2.DiscardSectors},
+		[2]uint64{t1.DiscardTicks, t2.DiscardTicks},
+	)
+}
+
+// vmstatDecreased returns true if a paging or swapping counter went backwards.
+func vmstatDecreased(t1, t2 *Vmstat) bool {
+	return decreased(
+		[2]uint64{t1.Pgpgin, t2.Pgpgin},
+		[2]uint64{t1.Pgpgout, t2.Pgpgout},
+		[2]uint64{t1.Pswpin, t2.Pswpin},
+		[2]uint64{t1.Pswpout, t2.Pswpout},
+		[2]uint64{t1.Pgfault, t2.Pgfault},
+		[2]uint64{t1.Pgmajfault, t2.Pgmajfault},
+		[2]uint64{t1.Pgfree, t2.Pgfree},
+		[2]uint64{t1.Pgscank, t2.Pgscank},
+		[2]uint64{t1.Pgscand, t2.Pgscand},
+		[2]uint64{t1.Pgsteal, t2.Pgsteal},
+	)
+}
+
+// cgroupDecreased returns true if a counter of the cgroup went backwards,
+// e.g. because the cgroup was removed and created again. The I/O counters
+// are the sums over all devices. Memory usage is a gauge and therefore not
+// checked.
+func cgroupDecreased(t1, t2 *Cgroup, io1, io2 [4]uint64) bool {
+	return decreased(
+		[2]uint64{t1.CPU.UsageUsec, t2.CPU.UsageUsec},
+		[2]uint64{t1.CPU.UserUsec, t2.CPU.UserUsec},
+		[2]uint64{t1.CPU.SystemUsec, t2.CPU.SystemUsec},
+		[2]uint64{t1.CPU.ThrottledUsec, t2.CPU.ThrottledUsec},
+		[2]uint64{t1.CPU.NrThrottled, t2.CPU.NrThrottled},
+		[2]uint64{t1.MemoryStat["pgfault"], t2.MemoryStat["pgfault"]},
+		[2]uint64{t1.MemoryStat["pgmajfault"],
+			t2.MemoryStat["pgmajfault"]},
+		[2]uint64{io1[0], io2[0]},
+		[2]uint64{io1[1], io2[1]},
+		[2]uint64{io1[2], io2[2]},
+		[2]uint64{io1[3], io2[3]},
+		[2]uint64{t1.CPUPressure.Some.Total, t2.CPUPressure.Some.Total},
+		[2]uint64{t1.CPUPressure.Full.Total, t2.CPUPressure.Full.Total},
+	)
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// Filesystem is a single filesystem of the statfs system.
+type Filesystem struct {
+	Device      string // Mount source, e.g. /dev/sda1
+	MountPoint  string // Mount point
+	FSType      string // Filesystem type, e.g. ext4
+	BlockSize   uint64 // Size of a block in bytes
+	Blocks      uint64 // Total blocks
+	BlocksFree  uint64 // Free blocks
+	BlocksAvail uint64 // Blocks available to unprivileged users
+	Files       uint64 // Total inodes
+	FilesFree   uint64 // Free inodes
+}
+
+// Statfs is parsed from the types.PCStatfsSystem system.
+type Statfs []Filesystem
+
+// ProcessStatfs parses a statfs system measurement.
+func ProcessStatfs(b []byte) (Statfs, error) {
+	var st Statfs
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		// /dev/sda1 / ext4 4096 2000000 1000000 900000 500000 400000
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 9 {
+			return nil, fmt.Errorf("malformed statfs: %q", s.Text())
+		}
+		fs := Filesystem{
+			Device:     fields[0],
+			MountPoint: types.UnescapeMount(fields[1]),
+			FSType:     fields[2],
+		}
+		for k, f := range []*uint64{&fs.BlockSize, &fs.Blocks,
+			&fs.BlocksFree, &fs.BlocksAvail, &fs.Files, &fs.FilesFree} {
+			var err error
+			*f, err = strconv.ParseUint(fields[k+3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid statfs %v: %v",
+					fs.MountPoint, err)
+			}
+		}
+		st = append(st, fs)
+	}
+
+	return st, s.Err()
+}
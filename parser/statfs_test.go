@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"testing"
+)
+
+const sampleStatfs = `/dev/sda1 / ext4 4096 1000 250 200 800 200
+/dev/sda2 /mnt/my\040data btrfs 4096 256 128 128 0 0
+`
+
+func TestProcessStatfs(t *testing.T) {
+	st, err := ProcessStatfs([]byte(sampleStatfs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st) != 2 {
+		t.Fatalf("unexpected length: %v", len(st))
+	}
+	want := Filesystem{
+		Device:      "/dev/sda1",
+		MountPoint:  "/",
+		FSType:      "ext4",
+		BlockSize:   4096,
+		Blocks:      1000,
+		BlocksFree:  250,
+		BlocksAvail: 200,
+		Files:       800,
+		FilesFree:   200,
+	}
+	if st[0] != want {
+		t.Fatalf("unexpected filesystem: %+v", st[0])
+	}
+	if st[1].MountPoint != "/mnt/my data" {
+		t.Fatalf("unexpected mount point: %q", st[1].MountPoint)
+	}
+
+	if _, err := ProcessStatfs([]byte("/dev/sda1 / ext4 4096\n")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCubeStatfs(t *testing.T) {
+	st, err := ProcessStatfs([]byte(sampleStatfs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := CubeStatfs(0, 0, 0, 0, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("unexpected length: %v", len(r))
+	}
+	if r[0].MBSize != 3.90625 || r[0].MBFree != 0.9765625 ||
+		r[0].FSUsed != 75 || r[0].UFSUsed != 80 || r[0].IUsed != 600 ||
+		r[0].PercentIUsed != 75 {
+		t.Fatalf("unexpected statfs: %+v", r[0])
+	}
+	if r[1].MBSize != 1 || r[1].FSUsed != 50 || r[1].PercentIUsed != 0 {
+		t.Fatalf("unexpected statfs: %+v", r[1])
+	}
+}
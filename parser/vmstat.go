@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Vmstat holds the paging and swapping counters of /proc/vmstat. Counters
+// that older kernels report per zone or per reclaim path are summed, the same
+// way sar does.
+type Vmstat struct {
+	Pgpgin     uint64 // KiB paged in from disk
+	Pgpgout    uint64 // KiB paged out to disk
+	Pswpin     uint64 // Pages swapped in
+	Pswpout    uint64 // Pages swapped out
+	Pgfault    uint64 // Minor and major page faults
+	Pgmajfault uint64 // Major page faults
+	Pgfree     uint64 // Pages placed on the free list
+	Pgscank    uint64 // Pages scanned by kswapd
+	Pgscand    uint64 // Pages scanned directly
+	Pgsteal    uint64 // Pages reclaimed
+}
+
+// ProcessVmstat parses /proc/vmstat.
+func ProcessVmstat(b []byte) (Vmstat, error) {
+	var vs Vmstat
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			return Vmstat{}, fmt.Errorf("malformed vmstat line: %q",
+				s.Text())
+		}
+
+		var f *uint64
+		switch name := fields[0]; {
+		case name == "pgpgin":
+			f = &vs.Pgpgin
+		case name == "pgpgout":
+			f = &vs.Pgpgout
+		case name == "pswpin":
+			f = &vs.Pswpin
+		case name == "pswpout":
+			f = &vs.Pswpout
+		case name == "pgfault":
+			f = &vs.Pgfault
+		case name == "pgmajfault":
+			f = &vs.Pgmajfault
+		case name == "pgfree":
+			f = &vs.Pgfree
+		case strings.HasPrefix(name, "pgscan_kswapd"):
+			f = &vs.Pgscank
+		case strings.HasPrefix(name, "pgscan_direct") &&
+			name != "pgscan_direct_throttle":
+			f = &vs.Pgscand
+		case strings.HasPrefix(name, "pgsteal_"):
+			f = &vs.Pgsteal
+		default:
+			continue
+		}
+
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return Vmstat{}, fmt.Errorf("invalid %v: %v", fields[0],
+				err)
+		}
+		*f += v
+	}
+
+	return vs, s.Err()
+}
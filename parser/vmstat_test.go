@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+const sampleVmstat = `nr_free_pages 1833162
+pgpgin %v
+pgpgout 2048
+pswpin 0
+pswpout %v
+pgalloc_normal 1000
+pgfree 5000
+pgfault %v
+pgmajfault 10
+pgsteal_kswapd %v
+pgsteal_direct 100
+pgscan_kswapd %v
+pgscan_direct 200
+pgscan_direct_throttle 999
+`
+
+func TestProcessVmstat(t *testing.T) {
+	vs, err := ProcessVmstat([]byte(fmt.Sprintf(sampleVmstat, 1024, 5,
+		100000, 400, 600)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vs.Pgpgin != 1024 || vs.Pgpgout != 2048 || vs.Pswpout != 5 {
+		t.Fatalf("unexpected paging: %+v", vs)
+	}
+	if vs.Pgfault != 100000 || vs.Pgmajfault != 10 || vs.Pgfree != 5000 {
+		t.Fatalf("unexpected faults: %+v", vs)
+	}
+	// Steal and scan are summed over kswapd and direct reclaim.
+	if vs.Pgsteal != 500 || vs.Pgscank != 600 || vs.Pgscand != 200 {
+		t.Fatalf("unexpected reclaim: %+v", vs)
+	}
+
+	if _, err := ProcessVmstat([]byte("pgpgin\n")); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCubeVmstat(t *testing.T) {
+	t1, err := ProcessVmstat([]byte(fmt.Sprintf(sampleVmstat, 1024, 5,
+		100000, 400, 600)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := ProcessVmstat([]byte(fmt.Sprintf(sampleVmstat, 6144, 55,
+		150000, 1200, 1600)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 second interval.
+	r, err := CubeVmstat(0, 0, 0, 0, &t1, &t2, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Pgpgin != 1024 || r.Pgpgout != 0 || r.Pswpout != 10 {
+		t.Fatalf("unexpected paging: %+v", r)
+	}
+	if r.Fault != 10000 || r.Pgscank != 200 || r.Pgsteal != 160 {
+		t.Fatalf("unexpected rates: %+v", r)
+	}
+	if r.Vmeff != 80 {
+		t.Fatalf("unexpected %%vmeff: %v", r.Vmeff)
+	}
+
+	// No scanning, no efficiency.
+	r, err = CubeVmstat(0, 0, 0, 0, &t1, &t1, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Vmeff != 0 {
+		t.Fatalf("unexpected %%vmeff: %v", r.Vmeff)
+	}
+}
@@ -3,11 +3,15 @@ package types
 import (
 	"bytes"
 	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	PCVersion = 1 // Protocol version.
+	PCVersion = 2 // Protocol version.
 
 	// Command identifiers
 	PCCmd      = "cmd"   // Generic encapsulating command
@@ -28,10 +32,49 @@ const (
 	PCStartCollectionCmd = "startcollection" // Start collecting measurements
 	PCStopCollectionCmd  = "stopcollection"  // Stop collecting measurements
 	PCRegisterSinkCmd    = "registersink"    // Register a sink
+	PCAckSequenceCmd     = "acksequence"     // Acknowledge stored measurements
 
 	PCChannel = "collector" // SSH channel name
+
+	PCDefaultCollection = "default" // Name of unnamed collections
+	PCDefaultSink       = "default" // Name of unnamed sinks
+
+	PCMinFrequency = 100 * time.Millisecond // Minimum collection frequency
+
+	// Virtual systems do not exist as a single file. The collector
+	// assembles their measurement from several files, see PCSection.
+	PCPidstatSystem = "/proc/pidstat" // Per-process stat, status and io
+	PCStatfsSystem  = "/proc/statfs"  // Filesystem capacity of mounts
+	PCCPUFreqSystem = "/proc/cpufreq" // Per-CPU scaling_cur_freq
+	PCNodeSystem    = "/proc/node"    // Per-NUMA node meminfo
+
+	// PCRunSystem is not measured. The collector spools it when a
+	// collection starts and stops, see PCRun.
+	PCRunSystem = "run"
+
+	// PCCPUFreqGlob and PCNodeGlob are the files that the PCCPUFreqSystem
+	// and PCNodeSystem systems are assembled from. They are expanded on
+	// every measurement so that CPUs and nodes that come online are
+	// picked up.
+	PCCPUFreqGlob = "/sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_cur_freq"
+	PCNodeGlob    = "/sys/devices/system/node/node[0-9]*/meminfo"
+
+	// PCCgroupRoot is the cgroup v2 mount point. Every directory below it,
+	// and the root itself, is a cgroup system whose measurement contains
+	// the cgroup files listed in PCCgroupFiles.
+	PCCgroupRoot = "/sys/fs/cgroup"
 )
 
+// PCCgroupFiles are the files that are measured for a cgroup system. Files
+// that do not exist, e.g. memory.current in the root cgroup, are omitted.
+var PCCgroupFiles = []string{
+	"cpu.stat",
+	"cpu.pressure",
+	"memory.current",
+	"memory.stat",
+	"io.stat",
+}
+
 // PCCommand encapsulates commands with a version and a tag.
 type PCCommand struct {
 	Version uint   // Protocol version
@@ -69,9 +112,63 @@ type PCCollectDirectoriesReply struct {
 // PCStartCollection instructs the collector to start gathering data with the
 // provided parameters.
 type PCStartCollection struct {
+	Name       string        // Name of the collection
 	Frequency  time.Duration // Collect performance data with this frequency
 	Systems    []string      // Performance statistics to grab.
-	QueueDepth int           // Max measurements before spilling
+	QueueDepth int           // Max spooled measurements, 0 is unlimited
+
+	Processes PCProcessSelector // Processes measured by PCPidstatSystem
+
+	Run         string // Name of the run, defaults to name and start time
+	Label       string // Operator label, e.g. a ticket or the operator
+	Description string // Free-form description of the run
+}
+
+// PCRun is a run, i.e. a collection from the moment it starts until it stops.
+// The collector spools a PCRunSystem measurement that contains the run when
+// the collection starts and again, with Stop set, when it stops. Sinks
+// therefore see the boundaries of a run in order with its measurements.
+type PCRun struct {
+	Name            string            // Name of the run
+	Start           time.Time         // Start of the run
+	Stop            time.Time         // End of the run, zero while running
+	StartCollection PCStartCollection // Parameters of the collection
+}
+
+// PCProcessSelector selects the processes that are measured by the
+// PCPidstatSystem system. A process must match every selector that is set
+// and matches a selector when it matches any of its values. When no selector
+// is set all processes are measured.
+type PCProcessSelector struct {
+	Names   []string // Glob patterns matched against the command name
+	Users   []string // User names or numerical user ids
+	Cgroups []string // Glob patterns matched against the cgroup or a parent
+}
+
+// PCStopCollection instructs the collector to stop the named collection. An
+// empty name stops all collections.
+type PCStopCollection struct {
+	Name string // Name of the collection
+}
+
+// PCRegisterSink registers the caller as a sink. A collector sends every
+// measurement to all registered sinks and tracks the delivery state of each
+// sink by Name. Resume contains, per collection, the sequence number of the
+// first measurement that the sink has not durably stored. The collector sends
+// the spooled measurements starting at Resume. A missing or 0 Resume means
+// that the sink has no prior state and that all spooled measurements must be
+// sent.
+type PCRegisterSink struct {
+	Name   string            // Sink name, PCDefaultSink if empty
+	Resume map[string]uint64 // First sequence number to send per collection
+}
+
+// PCAckSequence acknowledges that all measurements of a collection up to and
+// including Sequence have been durably stored by the sink. The collector will
+// not retransmit acknowledged measurements.
+type PCAckSequence struct {
+	Collection string // Name of the collection
+	Sequence   uint64 // Last durably stored sequence number
 }
 
 // PCPrepareReplay instructs the collector to start replaying a load that is
@@ -86,24 +183,151 @@ type PCPrepareReplayReply struct {
 	Training map[int]int // Training data in 10% increments
 }
 
-// PCStatusCollectionReply is the status of the collection.
+// PCCollectionStatus is the status of a single named collection. Collections
+// that were stopped are reported until their spool has been drained.
+type PCCollectionStatus struct {
+	StartCollection PCStartCollection // Original start collection command
+	Running         bool              // Is the collection running
+	QueueFree       int               // Number of open slots on queue
+	Spooled         int               // Measurements not yet acknowledged
+	SpoolSize       int64             // On-disk size of the spool
+	Missed          uint64            // Ticks missed since start
+	Lateness        time.Duration     // Lateness of the last tick
+}
+
+// PCSinkStatus is the status of a sink.
+type PCSinkStatus struct {
+	Name      string    // Sink name
+	Connected bool      // Is the sink connected
+	LastSeen  time.Time // Last time the sink was connected
+	Backlog   int       // Measurements not yet acknowledged by the sink
+}
+
+// PCStatusCollectionReply is the status of the collector.
 type PCStatusCollectionReply struct {
-	StartCollection    *PCStartCollection // Original start collection dommand
-	QueueFree          int                // Number of open slots on queue
-	SinkEnabled        bool               // Is the sink enabled
-	MeasurementEnabled bool               // Are measurements enabled
+	SinkEnabled        bool                 // Is any sink connected
+	MeasurementEnabled bool                 // Is any collection running
+	Collections        []PCCollectionStatus // Status per collection
+	Sinks              []PCSinkStatus       // Status per known sink
 }
 
 // PCCollection is a raw measurement that is sunk into the network.
 type PCCollection struct {
-	Timestamp   time.Time     // Time of *overall* collection
+	Collection  string        // Name of the collection
+	Sequence    uint64        // Sequence number within the collection
+	Timestamp   time.Time     // Scheduled time of *overall* collection
 	Start       time.Time     // Start time of *this* collection
 	Duration    time.Duration // Time collection took
+	Lateness    time.Duration // Delay between Timestamp and Start
+	Missed      int           // Ticks missed prior to this collection
 	Frequency   time.Duration // Collection frequency
 	System      string        // System that was measured
 	Measurement string        // Raw measurement
 }
 
+// PCSection is a single file of the measurement of a virtual system.
+type PCSection struct {
+	Name string // Filename, e.g. /proc/1/stat
+	Data []byte // File contents
+}
+
+// EncodeSections encodes sections into a measurement. Every section is
+// prefixed with a "@@ name length" line followed by the raw file contents.
+func EncodeSections(sections []PCSection) string {
+	var b strings.Builder
+	for _, v := range sections {
+		fmt.Fprintf(&b, "@@ %v %v\n", v.Name, len(v.Data))
+		b.Write(v.Data)
+	}
+	return b.String()
+}
+
+// DecodeSections decodes a measurement that was encoded with EncodeSections.
+func DecodeSections(measurement string) ([]PCSection, error) {
+	var sections []PCSection
+	for len(measurement) > 0 {
+		i := strings.IndexByte(measurement, '\n')
+		if i < 0 {
+			return nil, fmt.Errorf("section header not terminated")
+		}
+		header := strings.Fields(measurement[:i])
+		if len(header) != 3 || header[0] != "@@" {
+			return nil, fmt.Errorf("invalid section header: %q",
+				measurement[:i])
+		}
+		length, err := strconv.Atoi(header[2])
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid section length: %q",
+				header[2])
+		}
+		measurement = measurement[i+1:]
+		if length > len(measurement) {
+			return nil, fmt.Errorf("section %v truncated", header[1])
+		}
+		sections = append(sections, PCSection{
+			Name: header[1],
+			Data: []byte(measurement[:length]),
+		})
+		measurement = measurement[length:]
+	}
+	return sections, nil
+}
+
+// EncodeRun returns run r of collection as a PCRunSystem measurement.
+func EncodeRun(collection string, r PCRun) (*PCCollection, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := r.Start
+	if !r.Stop.IsZero() {
+		timestamp = r.Stop
+	}
+	return &PCCollection{
+		Collection:  collection,
+		Timestamp:   timestamp,
+		Start:       timestamp,
+		Frequency:   r.StartCollection.Frequency,
+		System:      PCRunSystem,
+		Measurement: string(b),
+	}, nil
+}
+
+// DecodeRun decodes a PCRunSystem measurement that was encoded with
+// EncodeRun.
+func DecodeRun(m *PCCollection) (*PCRun, error) {
+	if m.System != PCRunSystem {
+		return nil, fmt.Errorf("not a run: %v", m.System)
+	}
+	var r PCRun
+	err := json.Unmarshal([]byte(m.Measurement), &r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run: %v", err)
+	}
+	return &r, nil
+}
+
+// UnescapeMount returns the mount point s of /proc/self/mountinfo without
+// escapes. The kernel escapes space, tab, newline and backslash as \NNN
+// octal sequences.
+func UnescapeMount(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
 // Encode encodes an interface with gob. This should only be called with types
 // in this file.
 func Encode(x interface{}) ([]byte, error) {
@@ -136,6 +360,9 @@ func init() {
 	gob.Register(PCCollectDirectories{})
 	gob.Register(PCCollectDirectoriesReply{})
 	gob.Register(PCStartCollection{})
+	gob.Register(PCStopCollection{})
+	gob.Register(PCRegisterSink{})
+	gob.Register(PCAckSequence{})
 	gob.Register(PCStatusCollectionReply{})
 	gob.Register(PCPrepareReplay{})
 	gob.Register(PCPrepareReplayReply{})
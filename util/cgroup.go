@@ -0,0 +1,54 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// CgroupSystem returns true if s is a cgroup system, i.e. the cgroup v2
+// mount point or a path below it.
+func CgroupSystem(s string) bool {
+	path := filepath.Clean(s)
+	return path == types.PCCgroupRoot ||
+		strings.HasPrefix(path, types.PCCgroupRoot+"/")
+}
+
+// MeasureCgroup returns the cgroup files of the cgroup directory path,
+// encoded with types.EncodeSections. Files that do not exist are omitted.
+func MeasureCgroup(path string) ([]byte, error) {
+	path = filepath.Clean(path)
+	if !CgroupSystem(path) {
+		return nil, fmt.Errorf("not a cgroup: %v", path)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("not a cgroup directory: %v", path)
+	}
+
+	sections := make([]types.PCSection, 0, len(types.PCCgroupFiles))
+	for _, v := range types.PCCgroupFiles {
+		filename := filepath.Join(path, v)
+		if !fileExists(filename) {
+			continue
+		}
+		b, err := Measure(filename)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, types.PCSection{
+			Name: filename,
+			Data: b,
+		})
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("not a cgroup v2 directory: %v", path)
+	}
+	return []byte(types.EncodeSections(sections)), nil
+}
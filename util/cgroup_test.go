@@ -0,0 +1,28 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestCgroupSystem(t *testing.T) {
+	tests := []struct {
+		system string
+		cgroup bool
+	}{
+		{"/sys/fs/cgroup", true},
+		{"/sys/fs/cgroup/", true},
+		{"/sys/fs/cgroup/system.slice/sshd.service", true},
+		{"/sys/fs/cgroupfoo", false},
+		{"/sys/fs/cgroup/../../../etc", false},
+		{"/proc/stat", false},
+	}
+	for _, tt := range tests {
+		if CgroupSystem(tt.system) != tt.cgroup {
+			t.Fatalf("%v: expected %v", tt.system, tt.cgroup)
+		}
+	}
+
+	if _, err := MeasureCgroup("/proc"); err == nil {
+		t.Fatal("expected error")
+	}
+}
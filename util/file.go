@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/businessperformancetuning/perfcollector/types"
 )
 
 // FilesExists reports whether the named file or directory exists.
@@ -27,9 +29,15 @@ func fileExists(name string) bool {
 //}
 
 func ValidSystem(s string) bool {
-	// XXX we really need to reject some stuff from proc and sys. There
-	// definitely is data that can leak.
 	path := filepath.Clean(s)
+	if VirtualSystem(path) {
+		return true
+	}
+	if CgroupSystem(path) {
+		// Cgroups come and go with the services and containers
+		// they belong to.
+		return true
+	}
 	if !(strings.HasPrefix(path, "/proc/") ||
 		strings.HasPrefix(path, "/sys/")) {
 		return false
@@ -42,5 +50,24 @@ func Measure(s string) ([]byte, error) {
 	if !ValidSystem(path) {
 		return nil, fmt.Errorf("invalid system: %v", path)
 	}
+	if err := CheckPolicy(path); err != nil {
+		return nil, err
+	}
+	switch path {
+	case types.PCPidstatSystem:
+		// Without a selector all processes are measured.
+		return MeasurePidstat(types.PCProcessSelector{})
+	case types.PCStatfsSystem:
+		return MeasureStatfs()
+	case types.PCCPUFreqSystem:
+		return MeasureCPUFreq()
+	case types.PCNodeSystem:
+		return MeasureNode()
+	}
+	if CgroupSystem(path) {
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			return MeasureCgroup(path)
+		}
+	}
 	return ioutil.ReadFile(path)
 }
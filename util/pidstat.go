@@ -0,0 +1,192 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// VirtualSystem returns true if s is a system that the collector assembles
+// from several files.
+func VirtualSystem(s string) bool {
+	switch s {
+	case types.PCPidstatSystem, types.PCStatfsSystem,
+		types.PCCPUFreqSystem, types.PCNodeSystem:
+		return true
+	}
+	return false
+}
+
+// processSelector is a types.PCProcessSelector with the users resolved to
+// user ids.
+type processSelector struct {
+	names   []string
+	uids    map[string]struct{}
+	cgroups []string
+}
+
+func newProcessSelector(ps types.PCProcessSelector) (*processSelector, error) {
+	s := &processSelector{
+		names:   ps.Names,
+		cgroups: ps.Cgroups,
+	}
+	for _, patterns := range [][]string{ps.Names, ps.Cgroups} {
+		for _, v := range patterns {
+			_, err := filepath.Match(v, "")
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %v: %v",
+					v, err)
+			}
+		}
+	}
+	if len(ps.Users) != 0 {
+		s.uids = make(map[string]struct{}, len(ps.Users))
+	}
+	for _, v := range ps.Users {
+		if _, err := strconv.ParseUint(v, 10, 32); err == nil {
+			s.uids[v] = struct{}{}
+			continue
+		}
+		u, err := user.Lookup(v)
+		if err != nil {
+			return nil, err
+		}
+		s.uids[u.Uid] = struct{}{}
+	}
+	return s, nil
+}
+
+// ValidProcessSelector returns an error if ps contains invalid patterns or
+// unknown users.
+func ValidProcessSelector(ps types.PCProcessSelector) error {
+	_, err := newProcessSelector(ps)
+	return err
+}
+
+// comm returns the command name from the contents of /proc/<pid>/stat. The
+// name is enclosed in parentheses and may itself contain parentheses.
+func comm(stat []byte) string {
+	start := bytes.IndexByte(stat, '(')
+	end := bytes.LastIndexByte(stat, ')')
+	if start < 0 || end < start {
+		return ""
+	}
+	return string(stat[start+1 : end])
+}
+
+// uid returns the real user id from the contents of /proc/<pid>/status.
+func uid(status []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(status))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) > 1 && fields[0] == "Uid:" {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// cgroupMatch returns true if any of the cgroups in the contents of
+// /proc/<pid>/cgroup, or any of its parents, matches one of the patterns.
+func cgroupMatch(patterns []string, cgroup []byte) bool {
+	s := bufio.NewScanner(bytes.NewReader(cgroup))
+	for s.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		a := strings.SplitN(s.Text(), ":", 3)
+		if len(a) != 3 {
+			continue
+		}
+		for p := a[2]; ; p = filepath.Dir(p) {
+			for _, pattern := range patterns {
+				if ok, _ := filepath.Match(pattern, p); ok {
+					return true
+				}
+			}
+			if p == "/" || p == "." {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// measureProcess returns the sections of a single process or nil if the
+// process does not match the selector or exited.
+func (s *processSelector) measureProcess(pid string) []types.PCSection {
+	dir := filepath.Join("/proc", pid)
+	stat, err := Measure(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil
+	}
+	if len(s.names) != 0 {
+		name := comm(stat)
+		found := false
+		for _, pattern := range s.names {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	status, err := Measure(filepath.Join(dir, "status"))
+	if err != nil {
+		return nil
+	}
+	if s.uids != nil {
+		if _, ok := s.uids[uid(status)]; !ok {
+			return nil
+		}
+	}
+	if len(s.cgroups) != 0 {
+		cgroup, err := Measure(filepath.Join(dir, "cgroup"))
+		if err != nil || !cgroupMatch(s.cgroups, cgroup) {
+			return nil
+		}
+	}
+	sections := []types.PCSection{
+		{Name: filepath.Join(dir, "stat"), Data: stat},
+		{Name: filepath.Join(dir, "status"), Data: status},
+	}
+
+	// io is only readable for processes of the same user unless the
+	// collector runs as root.
+	if io, err := Measure(filepath.Join(dir, "io")); err == nil {
+		sections = append(sections, types.PCSection{
+			Name: filepath.Join(dir, "io"),
+			Data: io,
+		})
+	}
+	return sections
+}
+
+// MeasurePidstat returns the stat, status and io files of all processes that
+// match the selector, encoded with types.EncodeSections. Processes that exit
+// while being measured are omitted.
+func MeasurePidstat(ps types.PCProcessSelector) ([]byte, error) {
+	s, err := newProcessSelector(ps)
+	if err != nil {
+		return nil, err
+	}
+	des, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	var sections []types.PCSection
+	for _, de := range des {
+		if _, err := strconv.ParseUint(de.Name(), 10, 64); err != nil {
+			continue
+		}
+		sections = append(sections, s.measureProcess(de.Name())...)
+	}
+	return []byte(types.EncodeSections(sections)), nil
+}
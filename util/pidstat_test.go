@@ -0,0 +1,59 @@
+package util
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+func TestMeasurePidstat(t *testing.T) {
+	stat, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		t.Skip("no /proc")
+	}
+	self := "/proc/" + strconv.Itoa(os.Getpid()) + "/"
+
+	// Select this process by name and user.
+	b, err := MeasurePidstat(types.PCProcessSelector{
+		Names: []string{comm(stat)},
+		Users: []string{strconv.Itoa(os.Getuid())},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections, err := types.DecodeSections(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]bool)
+	for _, v := range sections {
+		if strings.HasPrefix(v.Name, self) {
+			found[strings.TrimPrefix(v.Name, self)] = true
+		}
+	}
+	if !found["stat"] || !found["status"] {
+		t.Fatalf("process not measured: %v", found)
+	}
+
+	// Nothing matches.
+	b, err = MeasurePidstat(types.PCProcessSelector{
+		Names: []string{"no such process name"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 0 {
+		t.Fatalf("unexpected measurement: %q", b)
+	}
+
+	// Invalid selectors.
+	err = ValidProcessSelector(types.PCProcessSelector{
+		Names: []string{"["},
+	})
+	if err == nil {
+		t.Fatal("expected invalid pattern")
+	}
+}
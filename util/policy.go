@@ -0,0 +1,173 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrPolicy is returned when a path is rejected by the policy.
+var ErrPolicy = errors.New("rejected by policy")
+
+// DefaultDeny contains the patterns that are always denied. These paths leak
+// secrets such as environment variables, command lines and memory contents.
+var DefaultDeny = []string{
+	"/proc/*/auxv",
+	"/proc/*/cmdline",
+	"/proc/*/environ",
+	"/proc/*/maps",
+	"/proc/*/mem",
+	"/proc/*/pagemap",
+	"/proc/*/smaps",
+	"/proc/*/smaps_rollup",
+	"/proc/*/task/*/cmdline",
+	"/proc/*/task/*/environ",
+	"/proc/*/task/*/mem",
+	"/proc/kcore",
+	"/proc/kallsyms",
+	"/proc/keys",
+	"/sys/firmware",
+	"/sys/kernel/debug",
+	"/sys/kernel/security",
+}
+
+// Policy restricts the /proc and /sys paths that may be read. Patterns use
+// filepath.Match syntax and match a path or any of its parent directories,
+// e.g. /sys/class/net allows everything below it. A path is allowed when it
+// matches an allow pattern, or there are no allow patterns, and it does not
+// match a deny pattern. Symbolic links are resolved and the resolved path must
+// be below /proc or /sys and may not match a deny pattern either. Allow
+// patterns only apply to the requested path; many /proc paths, e.g.
+// /proc/net/dev and /proc/self/mountinfo, resolve to /proc/<pid>/... paths.
+type Policy struct {
+	allow []string
+	deny  []string
+}
+
+// NewPolicy returns a policy with the provided allow and deny patterns. The
+// DefaultDeny patterns are always part of the policy.
+func NewPolicy(allow, deny []string) (*Policy, error) {
+	p := &Policy{
+		allow: make([]string, 0, len(allow)),
+		deny:  make([]string, 0, len(DefaultDeny)+len(deny)),
+	}
+	for _, v := range allow {
+		if err := validPattern(v); err != nil {
+			return nil, err
+		}
+		p.allow = append(p.allow, filepath.Clean(v))
+	}
+	for _, v := range append(append([]string{}, DefaultDeny...), deny...) {
+		if err := validPattern(v); err != nil {
+			return nil, err
+		}
+		p.deny = append(p.deny, filepath.Clean(v))
+	}
+	return p, nil
+}
+
+// validPattern returns an error if pattern is not a valid absolute pattern
+// below /proc or /sys.
+func validPattern(pattern string) error {
+	if !(strings.HasPrefix(pattern, "/proc/") ||
+		strings.HasPrefix(pattern, "/sys/")) {
+		return fmt.Errorf("invalid pattern %v: must start with /proc/ "+
+			"or /sys/", pattern)
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %v: %v", pattern, err)
+	}
+	return nil
+}
+
+// match returns the first pattern that matches path or any of its parent
+// directories.
+func match(patterns []string, path string) (string, bool) {
+	for p := path; p != "/" && p != "."; p = filepath.Dir(p) {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, p); ok {
+				return pattern, true
+			}
+		}
+	}
+	return "", false
+}
+
+// denied returns an error if a cleaned path is not below /proc or /sys or if
+// it matches a deny pattern.
+func (p *Policy) denied(path string) error {
+	if !(strings.HasPrefix(path, "/proc/") ||
+		strings.HasPrefix(path, "/sys/")) {
+		return fmt.Errorf("%v: %w: not in /proc or /sys", path,
+			ErrPolicy)
+	}
+	if pattern, ok := match(p.deny, path); ok {
+		return fmt.Errorf("%v: %w: denied by %v", path, ErrPolicy,
+			pattern)
+	}
+	return nil
+}
+
+// check applies the policy to a single, cleaned, path.
+func (p *Policy) check(path string) error {
+	if err := p.denied(path); err != nil {
+		return err
+	}
+	if len(p.allow) == 0 {
+		return nil
+	}
+	if _, ok := match(p.allow, path); !ok {
+		return fmt.Errorf("%v: %w: not allowed", path, ErrPolicy)
+	}
+	return nil
+}
+
+// Check returns an error that wraps ErrPolicy if path may not be read.
+func (p *Policy) Check(path string) error {
+	path = filepath.Clean(path)
+	if err := p.check(path); err != nil {
+		return err
+	}
+
+	// Virtual systems are not files, the files they are assembled from
+	// are checked when they are read.
+	if VirtualSystem(path) {
+		return nil
+	}
+
+	// Symbolic links, e.g. /proc/self/root, must not escape /proc and /sys
+	// or reach denied paths. The allow patterns were matched against the
+	// requested path above.
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("%v: %w: %v", path, ErrPolicy, err)
+	}
+	if resolved == path {
+		return nil
+	}
+	return p.denied(resolved)
+}
+
+// policy is the policy that is enforced by Measure.
+var policy atomic.Pointer[Policy]
+
+func init() {
+	p, err := NewPolicy(nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	policy.Store(p)
+}
+
+// SetPolicy sets the policy that is enforced by Measure and CheckPolicy.
+func SetPolicy(p *Policy) {
+	policy.Store(p)
+}
+
+// CheckPolicy returns an error that wraps ErrPolicy if path may not be read
+// according to the current policy.
+func CheckPolicy(path string) error {
+	return policy.Load().Check(path)
+}
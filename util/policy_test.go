@@ -0,0 +1,121 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	p, err := NewPolicy([]string{"/proc/stat", "/proc/[0-9]*", "/sys/class/net"},
+		[]string{"/proc/*/status"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"/proc/stat", true},
+		{"/proc/meminfo", false},          // Not allowed
+		{"/proc/1/stat", true},            // Glob
+		{"/proc/1/status", false},         // Denied
+		{"/proc/1/environ", false},        // Default deny
+		{"/proc/1/task/1/environ", false}, // Default deny
+		{"/sys/class/net/eth0/speed", true},
+		{"/sys/class/block", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		err := p.check(tt.path)
+		if tt.allowed && err != nil {
+			t.Fatalf("%v: unexpected error: %v", tt.path, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrPolicy) {
+			t.Fatalf("%v: expected ErrPolicy, got %v", tt.path, err)
+		}
+	}
+
+	// Default policy allows everything but the default deny list.
+	p, err = NewPolicy(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.check("/proc/meminfo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.check("/proc/kcore"); !errors.Is(err, ErrPolicy) {
+		t.Fatalf("expected ErrPolicy, got %v", err)
+	}
+}
+
+func TestPolicyInvalid(t *testing.T) {
+	for _, v := range []string{"/etc/*", "proc/stat", "/proc/[", ""} {
+		if _, err := NewPolicy([]string{v}, nil); err == nil {
+			t.Fatalf("expected error for allow %q", v)
+		}
+		if _, err := NewPolicy(nil, []string{v}); err == nil {
+			t.Fatalf("expected error for deny %q", v)
+		}
+	}
+}
+
+func TestPolicySymlink(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("no /proc")
+	}
+	p, err := NewPolicy(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Check("/proc/self/stat"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Symbolic links may not escape /proc.
+	err = p.Check("/proc/self/root/etc/passwd")
+	if !errors.Is(err, ErrPolicy) {
+		t.Fatalf("expected ErrPolicy, got %v", err)
+	}
+	err = p.Check("/proc/self/../../etc/passwd")
+	if !errors.Is(err, ErrPolicy) {
+		t.Fatalf("expected ErrPolicy, got %v", err)
+	}
+}
+
+func TestPolicyAllowResolved(t *testing.T) {
+	if _, err := os.Stat("/proc/self/mountinfo"); err != nil {
+		t.Skip("no /proc")
+	}
+
+	// /proc/net and /proc/self resolve to /proc/<pid>, the allow patterns
+	// apply to the requested path.
+	p, err := NewPolicy([]string{"/proc/net/dev", "/proc/self/mountinfo"},
+		nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/proc/net/dev", "/proc/self/mountinfo"} {
+		if err := p.Check(path); err != nil {
+			t.Fatalf("%v: unexpected error: %v", path, err)
+		}
+	}
+	for _, path := range []string{"/proc/net/snmp", "/proc/self/stat"} {
+		if err := p.Check(path); !errors.Is(err, ErrPolicy) {
+			t.Fatalf("%v: expected ErrPolicy, got %v", path, err)
+		}
+	}
+
+	// Deny patterns still apply to the resolved path.
+	p, err = NewPolicy(nil, []string{"/proc/[0-9]*/net"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Check("/proc/net/dev"); !errors.Is(err, ErrPolicy) {
+		t.Fatalf("expected ErrPolicy, got %v", err)
+	}
+	if err := p.Check("/proc/self/mountinfo"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// mount is a filesystem of /proc/self/mountinfo. The mount point is escaped
+// like in mountinfo so that it does not contain white space.
+type mount struct {
+	device     string // Mount source, e.g. /dev/sda1
+	mountPoint string // Escaped mount point
+	fsType     string // Filesystem type
+}
+
+// statfsResult is the capacity of a filesystem in blocks of blockSize bytes.
+type statfsResult struct {
+	blockSize   uint64
+	blocks      uint64
+	blocksFree  uint64
+	blocksAvail uint64
+	files       uint64
+	filesFree   uint64
+}
+
+// parseMountinfo returns the block device filesystems of /proc/self/mountinfo,
+// i.e. the filesystems that have a mount source that starts with a slash like
+// sar -F. Every mount point is returned once. When filesystems are stacked on
+// the same mount point only the top-most, i.e. the last in mountinfo, is
+// visible and returned, provided it is a block device filesystem.
+func parseMountinfo(b []byte) []mount {
+	var mounts []mount
+	seen := make(map[string]int) // Index into mounts, keyed by mount point
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw
+		fields := strings.Fields(s.Text())
+		sep := -1
+		for k, v := range fields {
+			if v == "-" {
+				sep = k
+				break
+			}
+		}
+		if sep < 5 || sep+2 >= len(fields) {
+			continue
+		}
+		m := mount{
+			device:     fields[sep+2],
+			mountPoint: fields[4],
+			fsType:     fields[sep+1],
+		}
+		if k, ok := seen[m.mountPoint]; ok {
+			mounts[k] = m
+			continue
+		}
+		seen[m.mountPoint] = len(mounts)
+		mounts = append(mounts, m)
+	}
+
+	r := mounts[:0]
+	for _, m := range mounts {
+		if strings.HasPrefix(m.device, "/") {
+			r = append(r, m)
+		}
+	}
+	return r
+}
+
+// MeasureStatfs returns the capacity of all block device filesystems, see
+// types.PCStatfsSystem. Every filesystem is a line with the device, the
+// escaped mount point, the filesystem type, the block size, the total, free
+// and available blocks and the total and free inodes. Filesystems that can
+// not be measured, e.g. because they were unmounted, are omitted.
+func MeasureStatfs() ([]byte, error) {
+	mi, err := Measure("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	for _, m := range parseMountinfo(mi) {
+		st, err := statfs(types.UnescapeMount(m.mountPoint))
+		if err != nil || st.blocks == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%v %v %v %v %v %v %v %v %v\n", m.device,
+			m.mountPoint, m.fsType, st.blockSize, st.blocks,
+			st.blocksFree, st.blocksAvail, st.files, st.filesFree)
+	}
+	return b.Bytes(), nil
+}
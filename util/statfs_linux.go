@@ -0,0 +1,27 @@
+//go:build linux
+// +build linux
+
+package util
+
+import "syscall"
+
+// statfs returns the capacity of the filesystem that path is on.
+func statfs(path string) (*statfsResult, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return nil, err
+	}
+	// The block counts are in fragments when the fragment size is set.
+	blockSize := uint64(st.Frsize)
+	if blockSize == 0 {
+		blockSize = uint64(st.Bsize)
+	}
+	return &statfsResult{
+		blockSize:   blockSize,
+		blocks:      st.Blocks,
+		blocksFree:  st.Bfree,
+		blocksAvail: st.Bavail,
+		files:       st.Files,
+		filesFree:   st.Ffree,
+	}, nil
+}
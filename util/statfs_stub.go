@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package util
+
+import "errors"
+
+// statfs returns an error, the statfs system is only supported on Linux.
+func statfs(path string) (*statfsResult, error) {
+	return nil, errors.New("statfs is only supported on Linux")
+}
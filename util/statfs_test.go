@@ -0,0 +1,54 @@
+package util
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const sampleMountinfo = `22 28 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:13 - proc proc rw
+28 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw,errors=remount-ro
+31 28 8:2 / /mnt/my\040data rw,relatime shared:2 - xfs /dev/sda2 rw
+32 28 8:2 /sub /srv rw,relatime shared:2 - xfs /dev/sda2 rw
+33 28 0:45 / /nfs rw,relatime shared:3 - nfs4 server:/export rw
+34 28 8:3 / /data rw,relatime shared:4 - ext4 /dev/sdb1 rw
+35 34 8:4 / /data rw,relatime shared:5 - ext4 /dev/sdc1 rw
+36 28 8:5 / /tmp rw,relatime shared:6 - ext4 /dev/sdd1 rw
+37 36 0:46 / /tmp rw,relatime shared:7 - tmpfs tmpfs rw
+`
+
+func TestParseMountinfo(t *testing.T) {
+	mounts := parseMountinfo([]byte(sampleMountinfo))
+	expected := []mount{
+		{"/dev/sda1", "/", "ext4"},
+		{"/dev/sda2", `/mnt/my\040data`, "xfs"},
+		{"/dev/sda2", "/srv", "xfs"},
+		{"/dev/sdc1", "/data", "ext4"}, // Overmounted /dev/sdb1
+	}
+	if len(mounts) != len(expected) {
+		t.Fatalf("unexpected mounts: %+v", mounts)
+	}
+	for k, v := range expected {
+		if mounts[k] != v {
+			t.Fatalf("unexpected mounts: %+v", mounts)
+		}
+	}
+}
+
+func TestMeasureStatfs(t *testing.T) {
+	if _, err := os.Stat("/proc/self/mountinfo"); err != nil {
+		t.Skip("no /proc")
+	}
+	b, err := MeasureStatfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if v == "" {
+			continue
+		}
+		if fields := strings.Fields(v); len(fields) != 9 {
+			t.Fatalf("unexpected line: %v", v)
+		}
+	}
+}
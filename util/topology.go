@@ -0,0 +1,44 @@
+package util
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+// measureGlob returns the files that match pattern, encoded with
+// types.EncodeSections. Files that disappear while they are read, e.g.
+// because a CPU went offline, are omitted.
+func measureGlob(pattern string) ([]byte, error) {
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sections := make([]types.PCSection, 0, len(filenames))
+	for _, v := range filenames {
+		b, err := Measure(v)
+		if err != nil {
+			continue
+		}
+		sections = append(sections, types.PCSection{
+			Name: v,
+			Data: b,
+		})
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("not supported: %v", pattern)
+	}
+	return []byte(types.EncodeSections(sections)), nil
+}
+
+// MeasureCPUFreq returns the current frequency of every CPU, see
+// types.PCCPUFreqSystem.
+func MeasureCPUFreq() ([]byte, error) {
+	return measureGlob(types.PCCPUFreqGlob)
+}
+
+// MeasureNode returns the meminfo of every NUMA node, see types.PCNodeSystem.
+func MeasureNode() ([]byte, error) {
+	return measureGlob(types.PCNodeGlob)
+}
@@ -0,0 +1,33 @@
+package util
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/businessperformancetuning/perfcollector/types"
+)
+
+func TestMeasureNode(t *testing.T) {
+	if m, _ := filepath.Glob(types.PCNodeGlob); len(m) == 0 {
+		t.Skip("no NUMA nodes")
+	}
+	b, err := MeasureNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections, err := types.DecodeSections(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range sections {
+		if !strings.HasSuffix(v.Name, "/meminfo") ||
+			!strings.HasPrefix(string(v.Data), "Node ") {
+			t.Fatalf("unexpected section: %v", v.Name)
+		}
+	}
+
+	if _, err := measureGlob("/proc/nonexistent*"); err == nil {
+		t.Fatal("expected error")
+	}
+}